@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/secret"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Command line flags for config set-key command
+var (
+	setKeyPassphrase bool
+	setKeyDelete     bool
+)
+
+// addAPIKeyFlags adds the --apikey and --apikey-file flags of the commands that upload
+func addAPIKeyFlags(cmd *cobra.Command, key, keyFile *string) {
+	cmd.Flags().StringVar(key, "apikey", "", "API key for authentication (default: $"+config.EnvVar("api_key")+", else the stored key)")
+	cmd.Flags().StringVar(keyFile, "apikey-file", "", "File holding the API key, so it stays out of the process list and shell history")
+	cmd.MarkFlagsMutuallyExclusive("apikey", "apikey-file")
+}
+
+// newConfigSetKeyCmd creates a new config set-key command
+func newConfigSetKeyCmd() *cobra.Command {
+	setKeyCmd := &cobra.Command{
+		Use:   "set-key",
+		Short: "Store the API key of the profile in use",
+		Long: `Store the API key of the profile in use, encrypted, so upload, watch and
+schedule use it when no key is given. The key is read from a hidden prompt,
+or from standard input when it is not a terminal.
+
+The stored keys are kept in secrets.json in the config directory, encrypted
+with AES-256-GCM. With --passphrase, the encryption key is derived from a
+passphrase that must be entered, or set in TRTC_PASSPHRASE, whenever a key is
+used; otherwise it is kept in secrets.key, readable only by you.
+
+Without --passphrase the keys are only obfuscated, not protected: anyone who
+can read the config directory, or a backup of it, can decrypt them with
+secrets.key. Use --passphrase when that matters.
+
+A plaintext api_key of the profile in use is removed from the config file once
+the key is stored. The top-level api_key belongs to the default profile and is
+only removed when storing the default profile's key.`,
+		Example: `  # Store the key of the active profile
+  trtc-go config set-key
+
+  # Store the key of the test profile, protected by a passphrase
+  trtc-go --profile=test config set-key --passphrase
+
+  # Forget the stored key of the active profile
+  trtc-go config set-key --delete`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigSetKey()
+		},
+	}
+
+	// Add flags
+	setKeyCmd.Flags().BoolVar(&setKeyPassphrase, "passphrase", false, "Protect the stored keys with a passphrase (only when no keys are stored yet)")
+	setKeyCmd.Flags().BoolVar(&setKeyDelete, "delete", false, "Remove the stored key of the profile in use")
+	setKeyCmd.MarkFlagsMutuallyExclusive("passphrase", "delete")
+
+	return setKeyCmd
+}
+
+// runConfigSetKey runs the config set-key command
+func runConfigSetKey() error {
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		return err
+	}
+	profile := Config.CurrentProfile()
+	name := secret.APIKey(profile)
+
+	if setKeyDelete {
+		if err := store.Delete(name); err != nil {
+			return err
+		}
+		Logger.Info("Removed the stored API key of profile %s", profile)
+		fmt.Printf("Stored API key of profile %s removed.\n", profile)
+		return nil
+	}
+
+	if setKeyPassphrase {
+		if _, err := os.Stat(store.Path()); err == nil {
+			return fmt.Errorf("keys are already stored in %s; a passphrase can only be chosen for the first one", store.Path())
+		}
+		if os.Getenv(secret.PassphraseEnv) == "" {
+			passphrase, err := readSecret("New passphrase: ")
+			if err != nil {
+				return err
+			}
+			confirm, err := readSecret("Repeat passphrase: ")
+			if err != nil {
+				return err
+			}
+			if passphrase == "" || passphrase != confirm {
+				return fmt.Errorf("the passphrases are empty or do not match")
+			}
+			store.SetPassphrase(passphrase)
+		}
+	}
+
+	key, err := readSecret(fmt.Sprintf("API key for profile %s: ", profile))
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("no API key was entered")
+	}
+	if err := withPassphrase(store, func() error {
+		return store.Set(name, key)
+	}); err != nil {
+		return fmt.Errorf("failed to store API key: %w", err)
+	}
+	Logger.Info("Stored the API key of profile %s", profile)
+	fmt.Printf("API key of profile %s stored in %s.\n", profile, store.Path())
+	if protected, err := store.Protected(); err == nil && !protected {
+		Logger.Warning("The stored API keys are only obfuscated: anyone who can read %s can decrypt them; protect them by storing the first key with --passphrase", filepath.Dir(store.Path()))
+	}
+
+	// Remove the plaintext copy the stored key replaces. A profile without a
+	// key of its own inherits the top-level key, which belongs to the default
+	// profile, so it is left alone.
+	own := config.SourceFile
+	if profile != config.DefaultProfile {
+		own = config.SourceProfile + " " + profile
+	}
+	path, _ := config.File()
+	switch source := Config.Source("api_key"); {
+	case Config.APIKey == "":
+	case source == own:
+		Config.APIKey = ""
+		if err := config.SaveConfig(Config); err != nil {
+			return fmt.Errorf("failed to remove the plaintext API key: %w", err)
+		}
+		fmt.Printf("Plaintext API key removed from %s.\n", path)
+	case source == config.SourceFile:
+		fmt.Printf("The plaintext api_key of the default profile is still in %s; run \"trtc-go --profile=%s config set-key\" to encrypt it.\n", path, config.DefaultProfile)
+	}
+	return nil
+}
+
+// resolveAPIKey returns the API key to upload with: the --apikey flag, else
+// the contents of the --apikey-file file, else the TRTC_API_KEY environment
+// variable, else the stored key of the profile cfg uses. The key is set in cfg.
+func resolveAPIKey(cfg *config.Config, key, keyFile string) (string, error) {
+	if key != "" {
+		logger.RegisterSecret(key)
+		cfg.APIKey = key
+		cfg.SetSource("api_key", config.SourceFlag+" --apikey")
+		return key, nil
+	}
+
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read API key file: %w", err)
+		}
+		key = strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("API key file %s is empty", keyFile)
+		}
+		logger.RegisterSecret(key)
+		cfg.APIKey = key
+		cfg.SetSource("api_key", config.SourceFlag+" --apikey-file")
+		return key, nil
+	}
+
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		return "", err
+	}
+	var source string
+	err = withPassphrase(store, func() error {
+		var err error
+		key, source, err = secret.LookupAPIKey(cfg, store)
+		return err
+	})
+	if errors.Is(err, secret.ErrNotFound) {
+		return "", fmt.Errorf("an API key is required: use --apikey, --apikey-file, %s or trtc-go config set-key", config.EnvVar("api_key"))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the stored API key: %w", err)
+	}
+	if source == config.SourceFile || strings.HasPrefix(source, config.SourceProfile) {
+		path, _ := config.File()
+		Logger.Warning("The API key is stored in plaintext in %s; run \"trtc-go config set-key\" to encrypt it", path)
+	}
+	logger.RegisterSecret(key)
+	cfg.APIKey = key
+	cfg.SetSource("api_key", source)
+	return key, nil
+}
+
+// apiKeyStatus describes the API key of the profile in use for config get,
+// without revealing it
+func apiKeyStatus() (string, string) {
+	if source := Config.Source("api_key"); strings.HasPrefix(source, config.SourceEnv) && Config.APIKey != "" {
+		return "********", source
+	}
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		return "", err.Error()
+	}
+	stored, err := store.Has(secret.APIKey(Config.CurrentProfile()))
+	if err != nil {
+		return "", err.Error()
+	}
+	if !stored && Config.APIKey == "" {
+		stored, err = store.Has(secret.APIKey(config.DefaultProfile))
+		if err != nil {
+			return "", err.Error()
+		}
+	}
+	if stored {
+		return "********", secret.SourceStore
+	}
+	if Config.APIKey != "" {
+		return "******** (plaintext)", Config.Source("api_key")
+	}
+	return "", Config.Source("api_key")
+}
+
+// withPassphrase runs fn, asking for the passphrase of the stored keys and
+// running it again if fn needs it
+func withPassphrase(store *secret.FileStore, fn func() error) error {
+	err := fn()
+	if !errors.Is(err, secret.ErrPassphraseRequired) {
+		return err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return fmt.Errorf("%w; set %s", err, secret.PassphraseEnv)
+	}
+	passphrase, err := readSecret("Passphrase for the stored API keys: ")
+	if err != nil {
+		return err
+	}
+	store.SetPassphrase(passphrase)
+	return fn()
+}
+
+// readSecret reads a line without echoing it when standard input is a
+// terminal, showing prompt on standard error. The value is kept out of the log.
+func readSecret(prompt string) (string, error) {
+	var value string
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, prompt)
+		data, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		value = strings.TrimSpace(string(data))
+	} else {
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+		value = strings.TrimSpace(line)
+	}
+	logger.RegisterSecret(value)
+	return value, nil
+}
+
+// stdin buffers standard input so several secrets can be read from a pipe
+var stdin = bufio.NewReader(os.Stdin)
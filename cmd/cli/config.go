@@ -2,8 +2,14 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/chatt-state/trtc-go/internal/api"
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/pkg/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -11,7 +17,26 @@ import (
 var (
 	endpoint        string
 	logFile         string
+	setLogLevel     string
+	setLogFormat    string
+	setConsoleLevel string
+	setSyslogLevel  string
+	setSyslogAddr   string
+	setLogMaxSize   int
+	setLogMaxAge    time.Duration
+	setLogBackups   int
+	setLogCompress  bool
 	ignoreCertError bool
+	caCertFile      string
+	clientCertFile  string
+	clientKeyFile   string
+	minTLSVersion   string
+	setRetries      int
+	setRetryDelay   time.Duration
+	setRetryMax     time.Duration
+	validateFiles   bool
+	validateStrict  bool
+	showSource      bool
 )
 
 // newConfigCmd creates a new config command
@@ -25,6 +50,8 @@ func newConfigCmd() *cobra.Command {
 	// Add subcommands
 	configCmd.AddCommand(newConfigGetCmd())
 	configCmd.AddCommand(newConfigSetCmd())
+	configCmd.AddCommand(newConfigSetKeyCmd())
+	configCmd.AddCommand(newConfigProfileCmd())
 
 	return configCmd
 }
@@ -34,12 +61,33 @@ func newConfigGetCmd() *cobra.Command {
 	getCmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get configuration values",
-		Long:  `Get the current configuration values.`,
+		Long: `Get the current configuration values.
+
+Values are resolved in this order, each overriding the ones after it:
+command line flags, TRTC_* environment variables (such as TRTC_API_ENDPOINT
+or TRTC_RETRY_MAX_ATTEMPTS), the profile in use, the config file, and the
+built-in defaults. --show-source lists every setting with where its value
+came from. With --output json or yaml every setting is listed with its
+source.`,
+		Example: `  # Show where each setting comes from
+  TRTC_API_ENDPOINT="https://test.example.com/api/Upload" trtc-go config get --show-source
+
+  # Read the settings from a script
+  trtc-go config get --output=json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if structuredOutput() {
+				return runConfigGetOutput()
+			}
+			if showSource {
+				return runConfigGetSources()
+			}
 			return runConfigGet()
 		},
 	}
 
+	// Add flags
+	getCmd.Flags().BoolVar(&showSource, "show-source", false, "List every setting with where its value came from")
+
 	return getCmd
 }
 
@@ -48,7 +96,8 @@ func newConfigSetCmd() *cobra.Command {
 	setCmd := &cobra.Command{
 		Use:   "set",
 		Short: "Set configuration values",
-		Long:  `Set configuration values such as API endpoint, log file, and certificate validation.`,
+		Long: `Set configuration values such as API endpoint, log file, and certificate validation.
+Endpoint, log and TLS settings are saved to the profile in use.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runConfigSet(cmd)
 		},
@@ -57,7 +106,25 @@ func newConfigSetCmd() *cobra.Command {
 	// Add flags
 	setCmd.Flags().StringVar(&endpoint, "endpoint", "", "API endpoint URL")
 	setCmd.Flags().StringVar(&logFile, "logfile", "", "Log file path")
+	setCmd.Flags().StringVar(&setLogLevel, "loglevel", "", "Log level (debug, info, warn, error)")
+	setCmd.Flags().StringVar(&setLogFormat, "logformat", "", "Log format (text, json)")
+	setCmd.Flags().StringVar(&setConsoleLevel, "console-level", "", "Lowest level logged to the console (debug, info, warn, error, off)")
+	setCmd.Flags().StringVar(&setSyslogLevel, "syslog-level", "", "Lowest level sent to syslog (debug, info, warn, error, off)")
+	setCmd.Flags().StringVar(&setSyslogAddr, "syslog-address", "", "Path of the syslog socket (empty for the system default)")
+	setCmd.Flags().IntVar(&setLogMaxSize, "log-max-size", 0, "Size in megabytes at which the log file is archived (0 disables)")
+	setCmd.Flags().DurationVar(&setLogMaxAge, "log-max-age", 0, "Age at which the log file is archived, such as 168h (0 disables)")
+	setCmd.Flags().IntVar(&setLogBackups, "log-backups", 0, "Number of log archives kept (0 keeps all)")
+	setCmd.Flags().BoolVar(&setLogCompress, "log-compress", true, "Compress log archives with gzip")
 	setCmd.Flags().BoolVar(&ignoreCertError, "ignore-cert-error", false, "Ignore certificate errors")
+	setCmd.Flags().StringVar(&caCertFile, "ca-cert", "", "Path to a PEM bundle of additional trusted CA certificates")
+	setCmd.Flags().StringVar(&clientCertFile, "client-cert", "", "Path to a PEM client certificate for mutual TLS")
+	setCmd.Flags().StringVar(&clientKeyFile, "client-key", "", "Path to the PEM private key for the client certificate")
+	setCmd.Flags().StringVar(&minTLSVersion, "min-tls-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+	setCmd.Flags().IntVar(&setRetries, "retry-attempts", 0, "Total upload attempts before giving up")
+	setCmd.Flags().DurationVar(&setRetryDelay, "retry-delay", 0, "Delay before the first retry, doubled on each retry")
+	setCmd.Flags().DurationVar(&setRetryMax, "retry-max-delay", 0, "Maximum delay between retries")
+	setCmd.Flags().BoolVar(&validateFiles, "validate-files", true, "Validate files against the TRTC file layouts before upload")
+	setCmd.Flags().BoolVar(&validateStrict, "validate-strict", false, "Block uploads on layout problems rather than only warning; the layouts are not confirmed by a published TRTC specification")
 
 	return setCmd
 }
@@ -65,12 +132,140 @@ func newConfigSetCmd() *cobra.Command {
 // runConfigGet runs the config get command
 func runConfigGet() error {
 	fmt.Println("Current Configuration:")
+	fmt.Printf("Profile: %s\n", Config.CurrentProfile())
+	key, source := apiKeyStatus()
+	if key == "" {
+		key = "not set"
+	} else {
+		key = fmt.Sprintf("%s (%s)", key, source)
+	}
+	fmt.Printf("API Key: %s\n", key)
 	fmt.Printf("API Endpoint: %s\n", Config.APIEndpoint)
-	fmt.Printf("Log File: %s\n", Config.LogFile)
+	logFilePath := Config.LogFile
+	if path, err := Config.LogPath(); err == nil && path != logFilePath {
+		logFilePath = fmt.Sprintf("%s (%s)", logFilePath, path)
+	}
+	fmt.Printf("Log File: %s\n", logFilePath)
+	fmt.Printf("Log Level: %s\n", Config.LogLevel)
+	fmt.Printf("Log Format: %s\n", Config.LogFormat)
+	fmt.Printf("Log Rotation: %s\n", describeLogRotation())
+	fmt.Printf("Console Log Level: %s\n", Config.LogConsoleLevel)
+	syslogAddress := Config.LogSyslogAddress
+	if syslogAddress == "" {
+		syslogAddress = "default socket"
+	}
+	fmt.Printf("Syslog Log Level: %s (%s)\n", Config.LogSyslogLevel, syslogAddress)
 	fmt.Printf("Ignore Certificate Errors: %t\n", Config.IgnoreCertError)
+	fmt.Printf("CA Certificate File: %s\n", Config.CACertFile)
+	fmt.Printf("Client Certificate File: %s\n", Config.ClientCertFile)
+	fmt.Printf("Client Key File: %s\n", Config.ClientKeyFile)
+	fmt.Printf("Minimum TLS Version: %s\n", Config.MinTLSVersion)
+	fmt.Printf("Retry Attempts: %d\n", Config.RetryMaxAttempts)
+	fmt.Printf("Retry Delay: %s (max %s, jitter %.0f%%)\n", Config.RetryBaseDelay, Config.RetryMaxDelay, Config.RetryJitter*100)
+	fmt.Printf("Retry Status Codes: %v\n", Config.RetryStatusCodes)
+	fmt.Printf("Retry Network Errors: %t (after send: %t)\n", Config.RetryNetworkErrors, Config.RetryAfterSend)
+	fmt.Printf("Validate Files: %t\n", Config.ValidateFiles)
+	fmt.Printf("Strict Validation: %t\n", Config.ValidateStrict)
 	return nil
 }
 
+// describeLogRotation describes when the log file is archived and how many
+// archives are kept
+func describeLogRotation() string {
+	var triggers []string
+	if Config.LogMaxSize > 0 {
+		triggers = append(triggers, fmt.Sprintf("at %d MB", Config.LogMaxSize))
+	}
+	if Config.LogMaxAge > 0 {
+		triggers = append(triggers, fmt.Sprintf("after %s", Config.LogMaxAge))
+	}
+	if len(triggers) == 0 {
+		return "off"
+	}
+	kept := "all archives kept"
+	if Config.LogMaxBackups > 0 {
+		kept = fmt.Sprintf("%d archive(s) kept", Config.LogMaxBackups)
+	}
+	if Config.LogCompress {
+		kept += ", compressed"
+	}
+	return strings.Join(triggers, " or ") + ", " + kept
+}
+
+// runConfigGetSources runs the config get command with --show-source
+func runConfigGetSources() error {
+	path, err := config.File()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Config File: %s\n", path)
+	fmt.Printf("Profile: %s\n", Config.CurrentProfile())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, key := range config.Keys() {
+		value, source := Config.Value(key), Config.Source(key)
+		if key == "api_key" {
+			value, source = apiKeyStatus()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, source)
+	}
+	return w.Flush()
+}
+
+// configResult is what config get writes with --output json or yaml
+type configResult struct {
+	ConfigFile string `json:"configFile"`
+	Profile    string `json:"profile"`
+	// LogPath is where the log file is written, log_file resolved against the config directory
+	LogPath  string          `json:"logPath"`
+	Settings []configSetting `json:"settings"`
+}
+
+// configSetting is a setting as config set and the config file name it.
+// The API key is never shown, only whether it is set.
+type configSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// runConfigGetOutput runs the config get command with --output json or yaml
+func runConfigGetOutput() error {
+	result, err := newConfigResult()
+	if err != nil {
+		return err
+	}
+	return writeOutput(result)
+}
+
+// newConfigResult describes the configuration in use for --output json or yaml
+func newConfigResult() (configResult, error) {
+	path, err := config.File()
+	if err != nil {
+		return configResult{}, err
+	}
+	logPath, err := Config.LogPath()
+	if err != nil {
+		return configResult{}, err
+	}
+
+	result := configResult{
+		ConfigFile: path,
+		Profile:    Config.CurrentProfile(),
+		LogPath:    logPath,
+		Settings:   []configSetting{},
+	}
+	for _, key := range config.Keys() {
+		value, source := Config.Value(key), Config.Source(key)
+		if key == "api_key" {
+			value, source = apiKeyStatus()
+		}
+		result.Settings = append(result.Settings, configSetting{Key: key, Value: value, Source: source})
+	}
+	return result, nil
+}
+
 // runConfigSet runs the config set command
 func runConfigSet(cmd *cobra.Command) error {
 	// Check if any flags were set
@@ -90,6 +285,86 @@ func runConfigSet(cmd *cobra.Command) error {
 		Logger.Info("Log file set to %s", logFile)
 	}
 
+	// Update log level if provided
+	if setLogLevel != "" {
+		level, err := logger.ParseLevel(setLogLevel)
+		if err != nil {
+			return err
+		}
+		Config.LogLevel = logger.LevelName(level)
+		flagsSet = true
+		Logger.Info("Log level set to %s", Config.LogLevel)
+	}
+
+	// Update log format if provided
+	if setLogFormat != "" {
+		format, err := logger.ParseFormat(setLogFormat)
+		if err != nil {
+			return err
+		}
+		Config.LogFormat = format
+		flagsSet = true
+		Logger.Info("Log format set to %s", format)
+	}
+
+	// Update console and syslog levels if provided
+	for _, s := range []struct {
+		value, name string
+		dst         *string
+	}{
+		{setConsoleLevel, "Console log level", &Config.LogConsoleLevel},
+		{setSyslogLevel, "Syslog log level", &Config.LogSyslogLevel},
+	} {
+		if s.value == "" {
+			continue
+		}
+		level, err := logger.ParseLevel(s.value)
+		if err != nil {
+			return err
+		}
+		*s.dst = logger.LevelName(level)
+		flagsSet = true
+		Logger.Info("%s set to %s", s.name, *s.dst)
+	}
+
+	// Update syslog address if provided (an empty value restores the default socket)
+	if cmd.Flags().Changed("syslog-address") {
+		Config.LogSyslogAddress = setSyslogAddr
+		flagsSet = true
+		Logger.Info("Syslog address set to %s", setSyslogAddr)
+	}
+
+	// Update log rotation if provided
+	if cmd.Flags().Changed("log-max-size") {
+		if setLogMaxSize < 0 {
+			return fmt.Errorf("log max size cannot be negative")
+		}
+		Config.LogMaxSize = setLogMaxSize
+		flagsSet = true
+		Logger.Info("Log max size set to %d MB", setLogMaxSize)
+	}
+	if cmd.Flags().Changed("log-max-age") {
+		if setLogMaxAge < 0 {
+			return fmt.Errorf("log max age cannot be negative")
+		}
+		Config.LogMaxAge = setLogMaxAge
+		flagsSet = true
+		Logger.Info("Log max age set to %s", setLogMaxAge)
+	}
+	if cmd.Flags().Changed("log-backups") {
+		if setLogBackups < 0 {
+			return fmt.Errorf("log backups cannot be negative")
+		}
+		Config.LogMaxBackups = setLogBackups
+		flagsSet = true
+		Logger.Info("Log backups set to %d", setLogBackups)
+	}
+	if cmd.Flags().Changed("log-compress") {
+		Config.LogCompress = setLogCompress
+		flagsSet = true
+		Logger.Info("Log compression set to %t", setLogCompress)
+	}
+
 	// Update ignore cert error if provided
 	if cmd.Flags().Changed("ignore-cert-error") {
 		Config.IgnoreCertError = ignoreCertError
@@ -97,6 +372,75 @@ func runConfigSet(cmd *cobra.Command) error {
 		Logger.Info("Ignore certificate errors set to %t", ignoreCertError)
 	}
 
+	// Update CA certificate file if provided (an empty value clears it)
+	if cmd.Flags().Changed("ca-cert") {
+		Config.CACertFile = caCertFile
+		flagsSet = true
+		Logger.Info("CA certificate file set to %s", caCertFile)
+	}
+
+	// Update client certificate file if provided (an empty value clears it)
+	if cmd.Flags().Changed("client-cert") {
+		Config.ClientCertFile = clientCertFile
+		flagsSet = true
+		Logger.Info("Client certificate file set to %s", clientCertFile)
+	}
+
+	// Update client key file if provided (an empty value clears it)
+	if cmd.Flags().Changed("client-key") {
+		Config.ClientKeyFile = clientKeyFile
+		flagsSet = true
+		Logger.Info("Client key file set to %s", clientKeyFile)
+	}
+
+	// Update minimum TLS version if provided
+	if minTLSVersion != "" {
+		if _, err := api.ParseTLSVersion(minTLSVersion); err != nil {
+			return err
+		}
+		Config.MinTLSVersion = minTLSVersion
+		flagsSet = true
+		Logger.Info("Minimum TLS version set to %s", minTLSVersion)
+	}
+
+	// Update retry attempts if provided
+	if cmd.Flags().Changed("retry-attempts") {
+		if setRetries < 1 {
+			return fmt.Errorf("retry attempts must be at least 1")
+		}
+		Config.RetryMaxAttempts = setRetries
+		flagsSet = true
+		Logger.Info("Retry attempts set to %d", setRetries)
+	}
+
+	// Update retry delay if provided
+	if cmd.Flags().Changed("retry-delay") {
+		Config.RetryBaseDelay = setRetryDelay
+		flagsSet = true
+		Logger.Info("Retry delay set to %s", setRetryDelay)
+	}
+
+	// Update maximum retry delay if provided
+	if cmd.Flags().Changed("retry-max-delay") {
+		Config.RetryMaxDelay = setRetryMax
+		flagsSet = true
+		Logger.Info("Maximum retry delay set to %s", setRetryMax)
+	}
+
+	// Update file validation if provided
+	if cmd.Flags().Changed("validate-files") {
+		Config.ValidateFiles = validateFiles
+		flagsSet = true
+		Logger.Info("Validate files set to %t", validateFiles)
+	}
+
+	// Update strict validation if provided
+	if cmd.Flags().Changed("validate-strict") {
+		Config.ValidateStrict = validateStrict
+		flagsSet = true
+		Logger.Info("Strict validation set to %t", validateStrict)
+	}
+
 	// If no flags were set, print current configuration
 	if !flagsSet {
 		fmt.Println("No configuration values were provided. Current configuration:")
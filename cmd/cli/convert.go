@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/excel"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for convert command
+var (
+	convertOutput string
+	listSheets    bool
+)
+
+// newConvertCmd creates a new convert command
+func newConvertCmd() *cobra.Command {
+	convertCmd := &cobra.Command{
+		Use:   "convert <workbook>",
+		Short: "Convert an Excel worksheet to CSV",
+		Long: `Convert a worksheet of an Excel (.xlsx) workbook to the CSV that would be uploaded.
+Dates are written as YYYY-MM-DD, long numbers are written in full instead of in
+scientific notation, and IDs formatted with leading zeros keep them.`,
+		Example: `  # Convert the first sheet to courses.csv next to the workbook
+  trtc-go convert courses.xlsx
+
+  # Convert a sheet by name to a chosen file
+  trtc-go convert export.xlsx --sheet="Student Courses" --output=studentcourses.csv
+
+  # List the sheets in a workbook
+  trtc-go convert export.xlsx --list-sheets`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConvert(args[0])
+		},
+	}
+
+	// Add flags
+	convertCmd.Flags().StringVar(&sheet, "sheet", "", "Worksheet to convert, by name or number (default: the first sheet)")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "CSV file to write (default: the workbook name with a .csv extension)")
+	convertCmd.Flags().BoolVar(&listSheets, "list-sheets", false, "List the sheets in the workbook instead of converting")
+
+	return convertCmd
+}
+
+// runConvert runs the convert command
+func runConvert(workbook string) error {
+	if listSheets {
+		sheets, err := excel.Sheets(workbook)
+		if err != nil {
+			return err
+		}
+		for i, name := range sheets {
+			fmt.Printf("%d\t%s\n", i+1, name)
+		}
+		return nil
+	}
+
+	output := convertOutput
+	if output == "" {
+		output = strings.TrimSuffix(workbook, filepath.Ext(workbook)) + ".csv"
+	}
+
+	result, err := excel.ConvertFile(workbook, output, excel.Options{Sheet: sheet})
+	if err != nil {
+		return err
+	}
+
+	Logger.Info("Converted sheet %s of %s to %s", result.Sheet, workbook, output)
+	fmt.Printf("Converted sheet %s to %s (%d rows)\n", result.Sheet, output, result.Rows)
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/excel"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for diff command
+var (
+	diffType    string
+	diffOutput  string
+	diffSummary bool
+)
+
+// newDiffCmd creates a new diff command
+func newDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff <old> <new>",
+		Short: "Show the rows added, removed and changed between two files",
+		Long: `Compare two files of the same type and show the rows that were added, removed
+or changed. Rows are matched by the natural key of the file type, such as the
+student ID for students or the subject and course number for courses, and columns
+are matched by name. When either file lacks a key column, rows are matched by all
+of their values instead. Excel workbooks are converted to CSV first.
+
+The file type is taken from --type, or from the new file's name when it is named
+after a type (for example students.csv).`,
+		Example: `  # Show what changed in the students extract since last term
+  trtc-go diff students-fall.csv students-spring.csv --type=students
+
+  # Write the added and changed rows to a file that can be uploaded
+  trtc-go diff old/studentcourses.csv studentcourses.csv --output=studentcourses-delta.csv`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiff(args[0], args[1])
+		},
+	}
+
+	// Add flags
+	diffCmd.Flags().StringVar(&diffType, "type", "", "File type (courses, equivalencies, students, studentcourses)")
+	diffCmd.Flags().StringVar(&sheet, "sheet", "", "Worksheet to read from Excel workbooks, by name or number")
+	diffCmd.Flags().StringVarP(&diffOutput, "output", "o", "", "Write the header and the added and changed rows of the new file to this CSV file")
+	diffCmd.Flags().BoolVar(&diffSummary, "summary", false, "Only print the number of added, removed and changed rows")
+
+	return diffCmd
+}
+
+// runDiff runs the diff command
+func runDiff(oldPath, newPath string) error {
+	ft, err := diffFileType(newPath)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "trtc-go-diff-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	oldCSV, err := diffInput(oldPath, filepath.Join(tempDir, "old.csv"))
+	if err != nil {
+		return err
+	}
+	newCSV, err := diffInput(newPath, filepath.Join(tempDir, "new.csv"))
+	if err != nil {
+		return err
+	}
+
+	result, err := delta.Diff(ft, oldCSV, newCSV)
+	if err != nil {
+		return err
+	}
+
+	if !diffSummary {
+		for _, row := range result.Removed {
+			fmt.Printf("- line %d: %s\n", row.Line, diffKey(row))
+		}
+		for _, row := range result.Added {
+			fmt.Printf("+ line %d: %s\n", row.Line, diffKey(row))
+		}
+		for _, change := range result.Changed {
+			fmt.Printf("~ line %d: %s\n", change.New.Line, diffKey(change.New))
+			for _, field := range change.Fields {
+				fmt.Printf("    %s: %q -> %q\n", field.Column, field.Old, field.New)
+			}
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d changed, %d unchanged\n", len(result.Added), len(result.Removed), len(result.Changed), result.Unchanged)
+
+	if diffOutput != "" {
+		if err := result.WriteDeltaFile(diffOutput); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %d rows to %s\n", result.Rows(), diffOutput)
+	}
+	return nil
+}
+
+// diffFileType returns the file type from --type or the name of the file
+func diffFileType(path string) (models.FileType, error) {
+	if diffType != "" {
+		return models.ParseFileType(diffType)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	ft, err := models.ParseFileType(name)
+	if err != nil {
+		return 0, fmt.Errorf("cannot tell the file type from %s; use --type", filepath.Base(path))
+	}
+	return ft, nil
+}
+
+// diffInput returns a CSV path for a file, converting Excel workbooks to dst
+func diffInput(path, dst string) (string, error) {
+	if !excel.IsWorkbook(path) {
+		return path, nil
+	}
+	if _, err := excel.ConvertFile(path, dst, excel.Options{Sheet: sheet}); err != nil {
+		return "", fmt.Errorf("failed to convert %s: %w", path, err)
+	}
+	return dst, nil
+}
+
+// diffKey describes a row by its key
+func diffKey(row delta.Row) string {
+	if row.Key == "" {
+		return "(no key) " + strings.Join(row.Values, ",")
+	}
+	return row.Key
+}
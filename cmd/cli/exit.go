@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/validation"
+)
+
+// Exit codes, documented in the README. Scripts rely on them, so existing
+// codes must never change meaning.
+const (
+	exitOK = 0
+	// exitFailure is any failure without a code of its own, such as a usage or configuration error
+	exitFailure = 1
+	// exitValidation means the files do not match the TRTC file layouts
+	exitValidation = 2
+	// exitNetwork means the server could not be reached or did not answer in time
+	exitNetwork = 3
+	// exitAuth means the server rejected the API key
+	exitAuth = 4
+	// exitRejected means the server answered but did not accept the upload
+	exitRejected = 5
+	// exitCancelled means the command was interrupted
+	exitCancelled = 130
+)
+
+// exitStatuses name the exit codes in the status field of structured output
+var exitStatuses = map[int]string{
+	exitOK:         "success",
+	exitFailure:    "error",
+	exitValidation: "invalid",
+	exitNetwork:    "network_error",
+	exitAuth:       "unauthorized",
+	exitRejected:   "rejected",
+	exitCancelled:  "cancelled",
+}
+
+// exitError gives an error the exit code the program ends with
+type exitError struct {
+	code int
+	err  error
+}
+
+// Error returns the message of the underlying error
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with the exit code the program ends with
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// rejectionCode returns the exit code of an upload the server answered but did
+// not accept. A status that is retried, such as 503, still means the server was
+// unavailable once the retries ran out, so it is a network failure like a
+// refused connection.
+func rejectionCode(statusCode int) int {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return exitAuth
+	case slices.Contains(Config.RetryStatusCodes, statusCode):
+		return exitNetwork
+	}
+	return exitRejected
+}
+
+// exitCode returns the exit code for an error returned by a command
+func exitCode(err error) int {
+	var codeErr *exitError
+	var validationErr *validation.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &codeErr):
+		return codeErr.code
+	case errors.As(err, &validationErr):
+		return exitValidation
+	case errors.Is(err, api.ErrCanceled) && errors.Is(err, context.DeadlineExceeded), errors.Is(err, api.ErrNetwork):
+		return exitNetwork
+	case errors.Is(err, api.ErrCanceled), errors.Is(err, context.Canceled):
+		return exitCancelled
+	default:
+		return exitFailure
+	}
+}
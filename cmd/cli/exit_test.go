@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/validation"
+)
+
+func TestExitCode(t *testing.T) {
+	// Retryable status codes come from the configuration
+	Config = config.DefaultConfig()
+
+	rejected := func(code int) error {
+		return responseError(&models.UploadResponse{Code: code, Message: http.StatusText(code)})
+	}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, exitOK},
+		{"other failure", errors.New("no files to upload"), exitFailure},
+		{"validation", fmt.Errorf("upload failed: %w", &validation.Error{Report: &validation.Report{}}), exitValidation},
+		{"network", fmt.Errorf("failed to send request: %w", api.ErrNetwork), exitNetwork},
+		{"timeout", fmt.Errorf("%w: %w", api.ErrCanceled, context.DeadlineExceeded), exitNetwork},
+		{"cancelled", fmt.Errorf("%w: %w", api.ErrCanceled, context.Canceled), exitCancelled},
+		{"context cancelled", context.Canceled, exitCancelled},
+		{"401", rejected(http.StatusUnauthorized), exitAuth},
+		{"403", rejected(http.StatusForbidden), exitAuth},
+		{"400", rejected(http.StatusBadRequest), exitRejected},
+		{"422", rejected(http.StatusUnprocessableEntity), exitRejected},
+		{"500", rejected(http.StatusInternalServerError), exitRejected},
+		{"503", rejected(http.StatusServiceUnavailable), exitNetwork},
+	}
+	for _, tc := range testCases {
+		if got := exitCode(tc.err); got != tc.expected {
+			t.Errorf("%s: exitCode = %d, expected %d", tc.name, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/history"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for history command
+var (
+	historySince  string
+	historyUntil  string
+	historyTypes  []string
+	historyStatus string
+	historyLimit  int
+)
+
+// newHistoryCmd creates a new history command
+func newHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List past uploads",
+		Long: `List the uploads recorded in the history journal, most recent last.
+Every upload run is recorded under its run ID with the files sent, their
+checksums and row counts, how long each step took, and the server's response.
+Runs that sent nothing because every file was unchanged are recorded as
+skipped. Use "history show" to see the details of a run.`,
+		Example: `  # List the last 20 uploads
+  trtc-go history
+
+  # List failed student uploads since the start of March
+  trtc-go history --since=2024-03-01 --type=students --status=failed`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory()
+		},
+	}
+
+	// Add flags
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Only show uploads on or after this date (YYYY-MM-DD or RFC 3339)")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Only show uploads on or before this date (YYYY-MM-DD or RFC 3339)")
+	historyCmd.Flags().StringSliceVar(&historyTypes, "type", nil, "Only show uploads that include these file types (courses, equivalencies, students, studentcourses)")
+	historyCmd.Flags().StringVar(&historyStatus, "status", "", "Only show uploads with this status (success, failed, cancelled, skipped)")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of uploads to show; 0 shows all")
+
+	// Add subcommands
+	historyCmd.AddCommand(newHistoryShowCmd())
+
+	return historyCmd
+}
+
+// newHistoryShowCmd creates a new history show command
+func newHistoryShowCmd() *cobra.Command {
+	showCmd := &cobra.Command{
+		Use:   "show <run-id>",
+		Short: "Show the details of an upload run",
+		Long: `Show the summary of an upload run: its outcome, the server's response, the
+time taken by each step, and the checksum, size and row count of each file.
+The run ID is printed by "upload" and carried as run_id by every log record of
+the run. It may be shortened to any unique prefix.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistoryShow(args[0])
+		},
+	}
+
+	return showCmd
+}
+
+// runHistory runs the history command
+func runHistory() error {
+	filter, err := historyFilter()
+	if err != nil {
+		return err
+	}
+
+	journal, err := history.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	entries, err := journal.Entries(filter)
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		if entries == nil {
+			entries = []history.Entry{}
+		}
+		return writeOutput(historyResult{Entries: entries})
+	}
+	if len(entries) == 0 {
+		fmt.Println("No uploads found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSTATUS\tCODE\tFILES\tREFERENCE")
+	for _, entry := range entries {
+		code := "-"
+		if entry.Code != 0 {
+			code = fmt.Sprint(entry.Code)
+		}
+		reference := entry.ReferenceID
+		if reference == "" {
+			reference = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Status, code, fileTypes(entry.Files), reference)
+	}
+	return w.Flush()
+}
+
+// runHistoryShow runs the history show command
+func runHistoryShow(id string) error {
+	journal, err := history.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	entry, err := journal.Find(id)
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return writeOutput(entry)
+	}
+
+	fmt.Printf("ID:        %s\n", entry.ID)
+	fmt.Printf("Time:      %s\n", entry.Time.Local().Format(time.RFC3339))
+	fmt.Printf("Endpoint:  %s\n", entry.Endpoint)
+	if entry.Profile != "" {
+		fmt.Printf("Profile:   %s\n", entry.Profile)
+	}
+	fmt.Printf("Status:    %s\n", entry.Status)
+	if entry.Code != 0 {
+		fmt.Printf("Code:      %d\n", entry.Code)
+	}
+	if entry.Message != "" {
+		fmt.Printf("Message:   %s\n", entry.Message)
+	}
+	if entry.ReferenceID != "" {
+		fmt.Printf("Reference: %s\n", entry.ReferenceID)
+	}
+	if entry.Error != "" {
+		fmt.Printf("Error:     %s\n", entry.Error)
+	}
+	if timings := entry.Timings; timings != nil {
+		fmt.Printf("Duration:  %s (convert %s, validate %s, upload %s)\n", timings.Total.Round(time.Millisecond),
+			timings.Convert.Round(time.Millisecond), timings.Validate.Round(time.Millisecond), timings.Upload.Round(time.Millisecond))
+	} else {
+		fmt.Printf("Duration:  %s\n", entry.Duration.Round(time.Millisecond))
+	}
+	fmt.Printf("Size:      %d bytes in %d file(s)\n", entry.Bytes(), len(entry.Files))
+	fmt.Println("Files:")
+	for _, file := range entry.Files {
+		fmt.Printf("  %s: %s\n", file.Type.String(), file.Path)
+		if file.SHA256 != "" {
+			fmt.Printf("    sha256: %s\n", file.SHA256)
+		}
+		fmt.Printf("    size: %d bytes, rows: %d\n", file.Size, file.Rows)
+		if file.DeltaRows > 0 {
+			fmt.Printf("    delta: %d added or changed rows sent\n", file.DeltaRows)
+		}
+	}
+	return nil
+}
+
+// historyResult is what history writes with --output json or yaml; each
+// entry is written as it is stored in the journal
+type historyResult struct {
+	Entries []history.Entry `json:"entries"`
+}
+
+// historyFilter builds the journal filter from the command line flags
+func historyFilter() (history.Filter, error) {
+	filter := history.Filter{Limit: historyLimit}
+
+	if historySince != "" {
+		since, _, err := parseHistoryTime(historySince)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+		filter.Since = since
+	}
+	if historyUntil != "" {
+		until, dateOnly, err := parseHistoryTime(historyUntil)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+		// A date includes the whole day
+		if dateOnly {
+			until = until.AddDate(0, 0, 1)
+		} else {
+			until = until.Add(time.Nanosecond)
+		}
+		filter.Until = until
+	}
+	for _, name := range historyTypes {
+		ft, err := models.ParseFileType(name)
+		if err != nil {
+			return filter, err
+		}
+		filter.Types = append(filter.Types, ft)
+	}
+	if historyStatus != "" {
+		status, err := history.ParseStatus(historyStatus)
+		if err != nil {
+			return filter, err
+		}
+		filter.Status = status
+	}
+
+	return filter, nil
+}
+
+// parseHistoryTime parses a local date or an RFC 3339 time, reporting whether only a date was given
+func parseHistoryTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%s is not a date (YYYY-MM-DD) or RFC 3339 time", value)
+	}
+	return t, false, nil
+}
+
+// fileTypes lists the types of the files in an upload
+func fileTypes(files []history.File) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Type.String()
+	}
+	return strings.Join(names, ",")
+}
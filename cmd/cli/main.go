@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/secret"
 	"github.com/chatt-state/trtc-go/pkg/logger"
 	"github.com/spf13/cobra"
 )
@@ -21,9 +24,22 @@ var (
 	Logger *logger.Logger
 
 	// Command line flags
-	logLevel int
+	logLevel    string
+	logFormat   string
+	quiet       bool
+	verbosity   int
+	profileName string
+	configPath  string
+	output      string
+
+	// outputFormat is the format results are written in, set from --output
+	outputFormat = outputText
 )
 
+// consoleLevelAnnotation is the annotation of a command that sets the console
+// log level used when log_console_level is not set
+const consoleLevelAnnotation = "console_log_level"
+
 func main() {
 	// Create root command
 	rootCmd := &cobra.Command{
@@ -31,24 +47,91 @@ func main() {
 		Short:   "TRTC-Go is a tool for uploading files to the TRTC API",
 		Version: Version,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// Load configuration
 			var err error
+			if outputFormat, err = parseOutputFormat(output); err != nil {
+				return err
+			}
+			// The arguments parsed, so later errors are not about usage
+			cmd.SilenceUsage = true
+
+			// Load configuration from --config, else $TRTC_CONFIG, else the default file
+			if configPath == "" {
+				configPath = os.Getenv(config.EnvPrefix + "_CONFIG")
+			}
+			config.SetFile(configPath)
 			Config, err = config.LoadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load configuration: %w", err)
 			}
 
+			// Apply the profile given on the command line, else in $TRTC_PROFILE,
+			// else the active one. A missing active profile must not stop the
+			// profile commands that fix it.
+			profile := profileName
+			if profile == "" {
+				profile = os.Getenv(config.EnvPrefix + "_PROFILE")
+			}
+			chosen := profile != ""
+			if !chosen {
+				profile = Config.ActiveProfile
+			}
+			if err := Config.UseProfile(profile); err != nil && (chosen || !isProfileCmd(cmd)) {
+				return fmt.Errorf("%w; see trtc-go config profile list", err)
+			}
+
+			// Environment variables override the file and the profile
+			if err := Config.ApplyEnv(); err != nil {
+				return err
+			}
+
+			// The logging flags override the settings for this run
+			if logLevel != "" {
+				Config.LogLevel = logLevel
+				Config.SetSource("log_level", config.SourceFlag+" --log-level")
+			}
+			if logFormat != "" {
+				Config.LogFormat = logFormat
+				Config.SetSource("log_format", config.SourceFlag+" --log-format")
+			}
+			// Long-running commands report their progress on the console by default
+			if level, ok := cmd.Annotations[consoleLevelAnnotation]; ok && Config.Source("log_console_level") == config.SourceDefault {
+				Config.LogConsoleLevel = level
+			}
+			switch {
+			case quiet:
+				Config.LogConsoleLevel = logger.LevelName(logger.LevelOff)
+				Config.SetSource("log_console_level", config.SourceFlag+" --quiet")
+			case verbosity == 1:
+				Config.LogConsoleLevel = logger.LevelName(logger.LevelInfo)
+				Config.SetSource("log_console_level", config.SourceFlag+" -v")
+			case verbosity > 1:
+				Config.LogConsoleLevel = logger.LevelName(logger.LevelDebug)
+				Config.SetSource("log_console_level", config.SourceFlag+" -vv")
+			}
+			logOpts, err := logOptions(Config)
+			if err != nil {
+				return err
+			}
+
+			// Keep known secrets out of the log and error messages
+			logger.RegisterSecret(Config.APIKey)
+			logger.RegisterSecret(os.Getenv(secret.PassphraseEnv))
+
 			// Create logger
-			logFilePath := Config.LogFile
-			if !filepath.IsAbs(logFilePath) {
-				// If log file path is not absolute, make it relative to the current directory
-				logFilePath, err = filepath.Abs(logFilePath)
-				if err != nil {
-					return fmt.Errorf("failed to get absolute path for log file: %w", err)
-				}
+			logFilePath, err := Config.LogPath()
+			if err != nil {
+				return fmt.Errorf("failed to locate log file: %w", err)
 			}
 
-			Logger, err = logger.New(logFilePath, logLevel)
+			// An unreachable syslog socket must not stop the commands that fix it
+			Logger, err = logger.NewWithOptions(logFilePath, logOpts)
+			if err != nil && logOpts.Syslog != nil {
+				syslogErr := err
+				logOpts.Syslog = nil
+				if Logger, err = logger.NewWithOptions(logFilePath, logOpts); err == nil {
+					Logger.Warning("Logging without syslog: %v", syslogErr)
+				}
+			}
 			if err != nil {
 				return fmt.Errorf("failed to create logger: %w", err)
 			}
@@ -63,16 +146,70 @@ func main() {
 		},
 	}
 
+	// Usage printed by cobra has secrets redacted; errors are printed below
+	rootCmd.SetErr(logger.RedactWriter(os.Stderr))
+	rootCmd.SilenceErrors = true
+
 	// Add persistent flags
-	rootCmd.PersistentFlags().IntVar(&logLevel, "log-level", logger.LevelInfo, "Log level (0=DEBUG, 1=INFO, 2=WARNING, 3=ERROR)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file to use instead of the default one (default: $TRTC_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Configuration profile to use for this run (default: $TRTC_PROFILE, else the active profile)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "", "Log file level: debug, info, warn, error or off (default: the log_level setting, info)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Log format: text or json (default: the log_format setting, text)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Write no log records to the console")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Write info log records to the console, or debug records with -vv (default: warnings and errors)")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().StringVar(&output, "output", outputText, "Result format of upload, validate, config get and history: text, json or yaml")
 
 	// Add commands
 	rootCmd.AddCommand(newUploadCmd())
 	rootCmd.AddCommand(newConfigCmd())
+	rootCmd.AddCommand(newValidateCmd())
+	rootCmd.AddCommand(newConvertCmd())
+	rootCmd.AddCommand(newHistoryCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newWatchCmd())
+	rootCmd.AddCommand(newScheduleCmd())
+	rootCmd.AddCommand(newMockServerCmd())
+
+	// Cancel in-flight work on Ctrl-C or termination
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Execute
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		stop()
+		fmt.Fprintln(os.Stderr, "Error: "+logger.Redact(err.Error()))
+		os.Exit(exitCode(err))
+	}
+}
+
+// logOptions returns the logger options set in the configuration: the log
+// file, the console on standard error and, if enabled, the syslog socket
+func logOptions(c *config.Config) (logger.Options, error) {
+	options := logger.Options{
+		Format: c.LogFormat,
+		Rotation: logger.Rotation{
+			MaxSize:    int64(c.LogMaxSize) << 20,
+			MaxAge:     c.LogMaxAge,
+			MaxBackups: c.LogMaxBackups,
+			Compress:   c.LogCompress,
+		},
+	}
+	var err error
+	if options.Level, err = logger.ParseLevel(c.LogLevel); err != nil {
+		return options, err
+	}
+	consoleLevel, err := logger.ParseLevel(c.LogConsoleLevel)
+	if err != nil {
+		return options, fmt.Errorf("log_console_level: %w", err)
+	}
+	options.Console = &logger.Console{Level: consoleLevel}
+	syslogLevel, err := logger.ParseLevel(c.LogSyslogLevel)
+	if err != nil {
+		return options, fmt.Errorf("log_syslog_level: %w", err)
+	}
+	if syslogLevel != logger.LevelOff {
+		options.Syslog = &logger.Syslog{Level: syslogLevel, Address: c.LogSyslogAddress, Tag: "trtc-go"}
 	}
+	return options, nil
 }
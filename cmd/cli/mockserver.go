@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/mockserver"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for mock-server command
+var (
+	mockAddr       string
+	mockAPIKeys    []string
+	mockDir        string
+	mockLatency    time.Duration
+	mockStatus     int
+	mockFailFirst  int
+	mockErrorRate  float64
+	mockFailStatus int
+)
+
+// newMockServerCmd creates a new mock-server command
+func newMockServerCmd() *cobra.Command {
+	mockCmd := &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run a local mock of the TRTC upload endpoint",
+		Long: `Run a local server that implements the TRTC upload endpoint for offline testing.
+It validates the API key, accepts the courses, equivalencies, students and
+studentcourses form fields, stores received files on disk, and can inject
+latency and errors.`,
+		Example: `  # Start a mock server and point the client at it
+  trtc-go mock-server --addr=localhost:8080 --apikey=test-key --dir=./received
+  trtc-go config set --endpoint="http://localhost:8080/api/Upload"
+
+  # Fail the first two uploads with 503 to exercise retries
+  trtc-go mock-server --fail-first=2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMockServer(cmd.Context())
+		},
+	}
+
+	// Add flags
+	mockCmd.Flags().StringVar(&mockAddr, "addr", "localhost:8080", "Address to listen on")
+	mockCmd.Flags().StringSliceVar(&mockAPIKeys, "apikey", nil, "Accepted API key (repeatable); any non-empty key when omitted")
+	mockCmd.Flags().StringVar(&mockDir, "dir", "", "Directory to store received files in")
+	mockCmd.Flags().DurationVar(&mockLatency, "latency", 0, "Delay before every response")
+	mockCmd.Flags().IntVar(&mockStatus, "status", 0, "Return this status code for every upload")
+	mockCmd.Flags().IntVar(&mockFailFirst, "fail-first", 0, "Fail the first N uploads")
+	mockCmd.Flags().Float64Var(&mockErrorRate, "error-rate", 0, "Probability (0-1) that an upload fails")
+	mockCmd.Flags().IntVar(&mockFailStatus, "fail-status", http.StatusServiceUnavailable, "Status code for injected failures")
+
+	return mockCmd
+}
+
+// runMockServer runs the mock-server command until the context is cancelled
+func runMockServer(ctx context.Context) error {
+	handler := mockserver.New(mockserver.Options{
+		APIKeys:    mockAPIKeys,
+		StorageDir: mockDir,
+		Latency:    mockLatency,
+		StatusCode: mockStatus,
+		FailFirst:  mockFailFirst,
+		ErrorRate:  mockErrorRate,
+		FailStatus: mockFailStatus,
+		Logger:     Logger,
+	})
+
+	server := &http.Server{
+		Addr:              mockAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	Logger.Info("Mock TRTC server listening on http://%s", mockAddr)
+	fmt.Printf("Mock TRTC server listening on http://%s (press Ctrl-C to stop)\n", mockAddr)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("mock server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to stop mock server: %w", err)
+	}
+
+	Logger.Info("Mock TRTC server stopped after %d request(s)", handler.Requests())
+	return nil
+}
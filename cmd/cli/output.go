@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the global --output flag
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// parseOutputFormat checks that s names an output format, returning
+// outputText for an empty string
+func parseOutputFormat(s string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(s)); format {
+	case "", outputText:
+		return outputText, nil
+	case outputJSON, outputYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("invalid output format %q: use %s, %s or %s", s, outputText, outputJSON, outputYAML)
+	}
+}
+
+// structuredOutput reports whether results are written as JSON or YAML
+// rather than as text
+func structuredOutput() bool {
+	return outputFormat != outputText
+}
+
+// writeOutput writes v to standard output in the --output format
+func writeOutput(v any) error {
+	data, err := encodeOutput(v)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// encodeOutput encodes v in the --output format. YAML is converted from the
+// JSON encoding, so both formats have the same field names in the same order.
+func encodeOutput(v any) ([]byte, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	if outputFormat == outputJSON {
+		return append(data, '\n'), nil
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	blockStyle(&node)
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode output: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle clears the flow and quoting styles that JSON input gives a YAML
+// node, so it is written as ordinary block YAML
+func blockStyle(node *yaml.Node) {
+	// Empty collections stay as [] and {}
+	if (node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode) && len(node.Content) == 0 {
+		return
+	}
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// update rewrites the golden files with the current output, for intended changes:
+// go test ./cmd/cli -run Output -update
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares output with a golden file in testdata. Scripts parse
+// the structured output, so a renamed or missing field must fail a test.
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Output does not match %s:\n%s\nexpected:\n%s", path, output, expected)
+	}
+}
+
+// setOutputFormat sets the --output format for a test
+func setOutputFormat(t *testing.T, format string) {
+	original := outputFormat
+	outputFormat = format
+	t.Cleanup(func() { outputFormat = original })
+}
+
+func TestUploadResultOutput(t *testing.T) {
+	Config = config.DefaultConfig()
+	setOutputFormat(t, outputJSON)
+
+	response := &models.UploadResponse{
+		Success:         false,
+		Message:         "Upload rejected",
+		Code:            http.StatusUnprocessableEntity,
+		ReferenceID:     "batch-42",
+		RecordsAccepted: 10,
+		RecordsRejected: 2,
+		Files: []models.FileResult{
+			{Type: models.FileTypeCourses, FileName: "courses.csv", Success: true, RecordsAccepted: 10},
+			{Type: models.FileTypeStudents, FileName: "students.csv", RecordsRejected: 2, Errors: []string{"row 3: missing student ID"}},
+		},
+	}
+	result := newUploadResult("20261017-120000-abcd", response, nil, false, responseError(response))
+
+	output, err := encodeOutput(result)
+	if err != nil {
+		t.Fatalf("Failed to encode output: %v", err)
+	}
+	checkGolden(t, "upload.json", output)
+}
+
+func TestConfigResultOutput(t *testing.T) {
+	// Keep the config file, log and secrets in a temporary directory, written
+	// as CONFIG_DIR so the output is the same everywhere
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	dir, err := config.Dir()
+	if err != nil {
+		t.Fatalf("Failed to get config dir: %v", err)
+	}
+	config.SetFile(filepath.Join(dir, "config.yaml"))
+	t.Cleanup(func() { config.SetFile("") })
+	Config = config.DefaultConfig()
+	setOutputFormat(t, outputJSON)
+
+	result, err := newConfigResult()
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+	result.ConfigFile = filepath.ToSlash(strings.Replace(result.ConfigFile, dir, "CONFIG_DIR", 1))
+	result.LogPath = filepath.ToSlash(strings.Replace(result.LogPath, dir, "CONFIG_DIR", 1))
+	output, err := encodeOutput(result)
+	if err != nil {
+		t.Fatalf("Failed to encode output: %v", err)
+	}
+	checkGolden(t, "config.json", output)
+}
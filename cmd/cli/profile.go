@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/secret"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for config profile add command
+var (
+	profileAPIKey          string
+	profileEndpoint        string
+	profileLogFile         string
+	profileLogLevel        string
+	profileLogFormat       string
+	profileIgnoreCertError bool
+	profileCACertFile      string
+	profileClientCertFile  string
+	profileClientKeyFile   string
+	profileMinTLSVersion   string
+)
+
+// newConfigProfileCmd creates a new config profile command
+func newConfigProfileCmd() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Long: `Manage named configuration profiles, each holding the endpoint, API key, TLS
+and log settings of one institution or environment. Settings a profile does
+not set come from the top-level settings, which form the "default" profile.
+The active profile is used unless --profile names another for a single run,
+and "config set" changes the settings of the profile in use.`,
+		Example: `  # Add a profile for the test endpoint and make it active
+  trtc-go config profile add test --endpoint="https://test.example.com/api/Upload"
+  trtc-go config profile use test
+
+  # Upload once with another profile
+  trtc-go --profile=southwest upload -apikey="your-api-key" -courses="courses.csv"`,
+	}
+
+	// Add subcommands
+	profileCmd.AddCommand(newConfigProfileListCmd())
+	profileCmd.AddCommand(newConfigProfileAddCmd())
+	profileCmd.AddCommand(newConfigProfileRemoveCmd())
+	profileCmd.AddCommand(newConfigProfileUseCmd())
+
+	return profileCmd
+}
+
+// newConfigProfileListCmd creates a new config profile list command
+func newConfigProfileListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List configuration profiles",
+		Long:  `List the configuration profiles with their endpoints. The active profile is marked with *.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigProfileList()
+		},
+	}
+
+	return listCmd
+}
+
+// newConfigProfileAddCmd creates a new config profile add command
+func newConfigProfileAddCmd() *cobra.Command {
+	addCmd := &cobra.Command{
+		Use:   "add <name>",
+		Short: "Add a configuration profile",
+		Long: `Add a configuration profile. Names may contain lowercase letters, digits,
+dashes and underscores. Settings not given here come from the top-level settings.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigProfileAdd(cmd, args[0])
+		},
+	}
+
+	// Add flags
+	addCmd.Flags().StringVar(&profileEndpoint, "endpoint", "", "API endpoint URL")
+	addCmd.Flags().StringVar(&profileAPIKey, "apikey", "", "API key for authentication, stored encrypted (see config set-key)")
+	addCmd.Flags().StringVar(&profileLogFile, "logfile", "", "Log file path")
+	addCmd.Flags().StringVar(&profileLogLevel, "loglevel", "", "Log level (debug, info, warn, error)")
+	addCmd.Flags().StringVar(&profileLogFormat, "logformat", "", "Log format (text, json)")
+	addCmd.Flags().BoolVar(&profileIgnoreCertError, "ignore-cert-error", false, "Ignore certificate errors")
+	addCmd.Flags().StringVar(&profileCACertFile, "ca-cert", "", "Path to a PEM bundle of additional trusted CA certificates")
+	addCmd.Flags().StringVar(&profileClientCertFile, "client-cert", "", "Path to a PEM client certificate for mutual TLS")
+	addCmd.Flags().StringVar(&profileClientKeyFile, "client-key", "", "Path to the PEM private key for the client certificate")
+	addCmd.Flags().StringVar(&profileMinTLSVersion, "min-tls-version", "", "Minimum TLS version (1.0, 1.1, 1.2, 1.3)")
+
+	return addCmd
+}
+
+// newConfigProfileRemoveCmd creates a new config profile remove command
+func newConfigProfileRemoveCmd() *cobra.Command {
+	removeCmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a configuration profile",
+		Long:  `Remove a configuration profile and its stored API key. If it was active, the default profile becomes active.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigProfileRemove(args[0])
+		},
+	}
+
+	return removeCmd
+}
+
+// newConfigProfileUseCmd creates a new config profile use command
+func newConfigProfileUseCmd() *cobra.Command {
+	useCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a configuration profile active",
+		Long:  `Make a configuration profile active, so it is used unless --profile names another. Use "default" for the top-level settings.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigProfileUse(args[0])
+		},
+	}
+
+	return useCmd
+}
+
+// runConfigProfileList runs the config profile list command
+func runConfigProfileList() error {
+	active := Config.ActiveProfile
+	if active == "" {
+		active = config.DefaultProfile
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tNAME\tENDPOINT")
+	for _, name := range Config.ProfileNames() {
+		profile, err := Config.WithProfile(name)
+		if err != nil {
+			return err
+		}
+		marker := ""
+		if name == active {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", marker, name, profile.APIEndpoint)
+	}
+	return w.Flush()
+}
+
+// runConfigProfileAdd runs the config profile add command
+func runConfigProfileAdd(cmd *cobra.Command, name string) error {
+	profile := config.Profile{
+		APIEndpoint:    profileEndpoint,
+		LogFile:        profileLogFile,
+		CACertFile:     profileCACertFile,
+		ClientCertFile: profileClientCertFile,
+		ClientKeyFile:  profileClientKeyFile,
+		MinTLSVersion:  profileMinTLSVersion,
+	}
+	if cmd.Flags().Changed("ignore-cert-error") {
+		profile.IgnoreCertError = &profileIgnoreCertError
+	}
+	if profileLogLevel != "" {
+		level, err := logger.ParseLevel(profileLogLevel)
+		if err != nil {
+			return err
+		}
+		profile.LogLevel = logger.LevelName(level)
+	}
+	if profileLogFormat != "" {
+		format, err := logger.ParseFormat(profileLogFormat)
+		if err != nil {
+			return err
+		}
+		profile.LogFormat = format
+	}
+	if profileMinTLSVersion != "" {
+		if _, err := api.ParseTLSVersion(profileMinTLSVersion); err != nil {
+			return err
+		}
+	}
+
+	if err := Config.AddProfile(name, profile); err != nil {
+		return err
+	}
+
+	// The API key goes to the secret store rather than the config file
+	if profileAPIKey != "" {
+		store, err := secret.OpenFileStore()
+		if err != nil {
+			return err
+		}
+		if err := withPassphrase(store, func() error {
+			return store.Set(secret.APIKey(name), profileAPIKey)
+		}); err != nil {
+			return fmt.Errorf("failed to store API key: %w", err)
+		}
+	}
+
+	if err := config.SaveConfig(Config); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	Logger.Info("Added profile %s", name)
+	fmt.Printf("Profile %s added. Use \"trtc-go config profile use %s\" to make it active.\n", name, name)
+	return nil
+}
+
+// runConfigProfileRemove runs the config profile remove command
+func runConfigProfileRemove(name string) error {
+	if err := Config.RemoveProfile(name); err != nil {
+		return err
+	}
+	if err := config.SaveConfig(Config); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(secret.APIKey(name)); err != nil {
+		return fmt.Errorf("failed to remove the stored API key: %w", err)
+	}
+
+	Logger.Info("Removed profile %s", name)
+	fmt.Printf("Profile %s removed.\n", name)
+	return nil
+}
+
+// runConfigProfileUse runs the config profile use command
+func runConfigProfileUse(name string) error {
+	if _, err := Config.WithProfile(name); err != nil {
+		return err
+	}
+	Config.ActiveProfile = name
+	if name == config.DefaultProfile {
+		Config.ActiveProfile = ""
+	}
+	if err := config.SaveConfig(Config); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+
+	Logger.Info("Active profile set to %s", name)
+	fmt.Printf("Profile %s is now active.\n", name)
+	return nil
+}
+
+// isProfileCmd reports whether cmd is one of the config profile commands
+func isProfileCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "profile" && c.Parent() != nil && c.Parent().Name() == "config" {
+			return true
+		}
+	}
+	return false
+}
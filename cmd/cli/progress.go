@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// progressBarWidth is the number of characters in the rendered bar
+const progressBarWidth = 30
+
+// progressBar renders upload progress on a single terminal line
+type progressBar struct {
+	out      io.Writer
+	interval time.Duration
+	last     time.Time
+	drawn    bool
+}
+
+// newProgressBar creates a progress bar writing to out
+func newProgressBar(out io.Writer) *progressBar {
+	return &progressBar{
+		out:      out,
+		interval: 100 * time.Millisecond,
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Update redraws the bar, at most once per interval except at the end of each file
+func (p *progressBar) Update(progress models.Progress) {
+	fileDone := progress.FileSent == progress.FileTotal
+	if !fileDone && time.Since(p.last) < p.interval {
+		return
+	}
+	p.last = time.Now()
+
+	filled := progressBarWidth
+	percent := 100
+	if progress.Total > 0 {
+		filled = int(progress.Sent * progressBarWidth / progress.Total)
+		percent = int(progress.Sent * 100 / progress.Total)
+	}
+
+	fmt.Fprintf(p.out, "\r[%s%s] %3d%% %s / %s  %s (%s)\033[K",
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", progressBarWidth-filled),
+		percent,
+		formatBytes(progress.Sent),
+		formatBytes(progress.Total),
+		filepath.Base(progress.File.FilePath),
+		progress.File.Type.String(),
+	)
+	p.drawn = true
+}
+
+// Finish ends the progress line so later output starts on a new line
+func (p *progressBar) Finish() {
+	if p.drawn {
+		fmt.Fprintln(p.out)
+		p.drawn = false
+	}
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/history"
+	"github.com/chatt-state/trtc-go/internal/manifest"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/chatt-state/trtc-go/internal/validation"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags overriding the retry settings, shared by the commands that upload
+var (
+	retryAttempts int
+	retryDelay    time.Duration
+	retryMaxDelay time.Duration
+)
+
+// runSettings are the settings of an upload run that are not part of the
+// configuration, given by flags or a manifest
+type runSettings struct {
+	timeout time.Duration
+	sheet   string
+	force   bool
+	delta   bool
+}
+
+// addRetryFlags adds the flags overriding the retry settings for a run
+func addRetryFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&retryAttempts, "retry-attempts", 0, "Total upload attempts before giving up (overrides config)")
+	cmd.Flags().DurationVar(&retryDelay, "retry-delay", 0, "Delay before the first retry, doubled on each retry (overrides config)")
+	cmd.Flags().DurationVar(&retryMaxDelay, "retry-max-delay", 0, "Maximum delay between retries (overrides config)")
+}
+
+// applyRetryFlags applies the retry flags given on the command line to cfg
+func applyRetryFlags(cmd *cobra.Command, cfg *config.Config) {
+	if cmd.Flags().Changed("retry-attempts") {
+		cfg.RetryMaxAttempts = retryAttempts
+	}
+	if cmd.Flags().Changed("retry-delay") {
+		cfg.RetryBaseDelay = retryDelay
+	}
+	if cmd.Flags().Changed("retry-max-delay") {
+		cfg.RetryMaxDelay = retryMaxDelay
+	}
+}
+
+// applyManifest applies the settings of a manifest read from path to cfg and
+// settings, returning the configuration of the manifest's profile when it
+// names one. Flags given on the command line take precedence over the
+// manifest; cmd is nil for runs without flags of their own, such as scheduled
+// runs.
+func applyManifest(cmd *cobra.Command, cfg *config.Config, settings *runSettings, job *manifest.Manifest, path string) (*config.Config, error) {
+	flagChanged := func(name string) bool {
+		return cmd != nil && cmd.Flags().Changed(name)
+	}
+
+	if job.Name != "" {
+		Logger.Info("Running upload job %s from %s", job.Name, path)
+	}
+	if job.Profile != "" && job.Profile != cfg.CurrentProfile() && !flagChanged("profile") {
+		profiled, err := cfg.WithProfile(job.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", path, err)
+		}
+		// Environment variables override the profile, as they do for --profile
+		if err := profiled.ApplyEnv(); err != nil {
+			return nil, err
+		}
+		Logger.Info("Using profile %s from the manifest", job.Profile)
+		cfg = profiled
+	}
+	if job.Endpoint != "" {
+		cfg.APIEndpoint = job.Endpoint
+	}
+	if job.Validate != nil && !flagChanged("skip-validation") {
+		cfg.ValidateFiles = *job.Validate
+	}
+	if job.Strict != nil {
+		cfg.ValidateStrict = *job.Strict
+	}
+	if !flagChanged("sheet") && job.Sheet != "" {
+		settings.sheet = job.Sheet
+	}
+	if !flagChanged("timeout") {
+		if timeout, _ := job.TimeoutDuration(); timeout > 0 {
+			settings.timeout = timeout
+		}
+	}
+	if !flagChanged("force") {
+		settings.force = job.Force
+	}
+	if !flagChanged("delta") {
+		settings.delta = job.Delta
+	}
+	return cfg, nil
+}
+
+// newRunUploader creates an uploader for a run, recording it in the upload
+// history and keeping snapshots of the uploaded files for delta uploads
+func newRunUploader(cfg *config.Config, settings runSettings) (*uploader.Uploader, error) {
+	u, err := uploader.New(cfg, Logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create uploader: %w", err)
+	}
+	u.SetTimeout(settings.timeout)
+	u.SetSheet(settings.sheet)
+	u.SetForce(settings.force)
+	u.SetDelta(settings.delta)
+
+	journal, err := history.Open()
+	if err != nil {
+		Logger.Warning("Upload history is unavailable: %v", err)
+	} else {
+		u.SetJournal(journal)
+	}
+	snapshots, err := delta.OpenStore()
+	if err != nil {
+		Logger.Warning("Upload snapshots are unavailable: %v", err)
+	} else {
+		u.SetSnapshots(snapshots)
+	}
+	return u, nil
+}
+
+// startRun gives an upload run an ID, carried by the returned context into
+// the log, the history and the output
+func startRun(ctx context.Context, cfg *config.Config) (context.Context, string) {
+	runID := history.NewID(time.Now())
+	ctx = logger.WithRunID(ctx, runID)
+	Logger.WithContext(ctx).Info("Uploading files to %s", cfg.APIEndpoint)
+	return ctx, runID
+}
+
+// uploadUnattended runs an upload for watch and schedule, which report its
+// outcome in the log rather than on standard output. It returns
+// uploader.ErrUnchanged when every file is unchanged since its last upload.
+func uploadUnattended(ctx context.Context, u *uploader.Uploader, cfg *config.Config, key string, files []models.UploadFile) error {
+	ctx, _ = startRun(ctx, cfg)
+	log := Logger.WithContext(ctx)
+
+	response, err := u.UploadFilesWithContext(ctx, key, files)
+	if errors.Is(err, uploader.ErrUnchanged) {
+		log.Info("Nothing to upload: all files are unchanged since the last successful upload")
+		return err
+	}
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		return fmt.Errorf("%w\n%s", err, validationDetails(validationErr.Report))
+	}
+	if err != nil {
+		return err
+	}
+	if !response.Success {
+		return responseError(response)
+	}
+	if response.ReferenceID != "" {
+		log.Info("Upload reference ID: %s", response.ReferenceID)
+	}
+	return nil
+}
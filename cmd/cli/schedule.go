@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/manifest"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/schedule"
+	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for schedule command
+var (
+	scheduleAPIKey     string
+	scheduleAPIKeyFile string
+)
+
+// scheduleKeys holds the API key of each profile the scheduled runs have used,
+// so a passphrase for the stored keys is asked for only once
+var scheduleKeys = map[string]string{}
+
+// newScheduleCmd creates a new schedule command
+func newScheduleCmd() *cobra.Command {
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Run scheduled uploads",
+		Long: `Run the uploads listed under schedules in the config file at their scheduled
+times, until interrupted. Each schedule has a name, a cron expression in the
+local time zone, and either a manifest or a set of files:
+
+  schedules:
+    - name: nightly
+      cron: "30 2 * * *"
+      manifest: /srv/trtc/nightly.yaml
+      catch_up: true
+    - name: courses
+      cron: "@weekly"
+      files:
+        courses: /srv/trtc/courses.csv
+
+Only one scheduled upload runs at a time; a run that finds another in
+progress, in this or another process, is skipped. With catch_up, a schedule
+that missed a run while the scheduler was stopped runs once when it starts.
+The outcome of every run is logged.`,
+		Example: `  # Run the configured schedules
+  trtc-go schedule -apikey="your-api-key"
+
+  # Show when each schedule runs next
+  trtc-go schedule list`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{consoleLevelAnnotation: "info"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSchedule(cmd)
+		},
+	}
+
+	// Add flags
+	addAPIKeyFlags(scheduleCmd, &scheduleAPIKey, &scheduleAPIKeyFile)
+	addRetryFlags(scheduleCmd)
+
+	// Add subcommands
+	scheduleCmd.AddCommand(newScheduleListCmd())
+
+	return scheduleCmd
+}
+
+// newScheduleListCmd creates a new schedule list command
+func newScheduleListCmd() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the configured schedules",
+		Long:  `List the configured schedules with when each last ran and runs next.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScheduleList()
+		},
+	}
+
+	return listCmd
+}
+
+// runSchedule runs the schedule command
+func runSchedule(cmd *cobra.Command) error {
+	key, err := resolveAPIKey(Config, scheduleAPIKey, scheduleAPIKeyFile)
+	if err != nil {
+		return err
+	}
+	scheduleKeys[Config.CurrentProfile()] = key
+	applyRetryFlags(cmd, Config)
+
+	jobs, err := schedule.Jobs(Config.Schedules)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		path, err := config.File()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("no schedules are configured; add them under schedules in %s", path)
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+
+	run := func(ctx context.Context, s config.Schedule) error {
+		return runScheduledUpload(ctx, cmd, s)
+	}
+	return schedule.New(jobs, run, dir, Logger).Run(cmd.Context())
+}
+
+// runScheduleList runs the schedule list command
+func runScheduleList() error {
+	jobs, err := schedule.Jobs(Config.Schedules)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No schedules are configured.")
+		return nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return err
+	}
+	state, err := schedule.LoadState(dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCRON\tLAST RUN\tNEXT RUN\tUPLOADS")
+	for _, job := range jobs {
+		last := "-"
+		if t, ok := state[job.Name]; ok {
+			last = t.Local().Format("2006-01-02 15:04")
+		}
+		target := job.Manifest
+		if target == "" {
+			target = fmt.Sprintf("%d file(s)", len(job.Files))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", job.Name, job.Cron, last, job.Cron.Next(now).Format("2006-01-02 15:04"), target)
+	}
+	return w.Flush()
+}
+
+// runScheduledUpload uploads the files of a schedule. A manifest is read
+// again on every run so edits take effect without restarting the scheduler.
+func runScheduledUpload(ctx context.Context, cmd *cobra.Command, s config.Schedule) error {
+	copied := *Config
+	cfg := &copied
+	settings := runSettings{timeout: api.DefaultTimeout}
+
+	var job *manifest.Manifest
+	var files []models.UploadFile
+	if s.Manifest != "" {
+		var err error
+		job, err = manifest.Load(s.Manifest)
+		if err != nil {
+			return err
+		}
+		files, err = job.UploadFiles()
+		if err != nil {
+			return err
+		}
+		cfg, err = applyManifest(nil, cfg, &settings, job, s.Manifest)
+		if err != nil {
+			return err
+		}
+	} else {
+		for name, path := range s.Files {
+			ft, err := models.ParseFileType(name)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return fmt.Errorf("%s file does not exist: %s", ft.String(), path)
+			}
+			files = append(files, models.UploadFile{Type: ft, FilePath: path})
+		}
+		sort.Slice(files, func(a, b int) bool {
+			return files[a].Type < files[b].Type
+		})
+	}
+
+	// The retry flags and the key flags also apply to a manifest's profile
+	applyRetryFlags(cmd, cfg)
+	key, ok := scheduleKeys[cfg.CurrentProfile()]
+	if !ok {
+		var err error
+		key, err = resolveAPIKey(cfg, scheduleAPIKey, scheduleAPIKeyFile)
+		if err != nil {
+			return err
+		}
+		scheduleKeys[cfg.CurrentProfile()] = key
+	}
+
+	u, err := newRunUploader(cfg, settings)
+	if err != nil {
+		return err
+	}
+	err = uploadUnattended(ctx, u, cfg, key, files)
+	if errors.Is(err, uploader.ErrUnchanged) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Run the manifest's post-upload actions
+	if job != nil {
+		if err := job.RunAfter(files, time.Now()); err != nil {
+			return fmt.Errorf("upload succeeded but the after actions failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/manifest"
+	"github.com/chatt-state/trtc-go/internal/models"
 	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/chatt-state/trtc-go/internal/validation"
+	"github.com/chatt-state/trtc-go/pkg/logger"
 	"github.com/spf13/cobra"
 )
 
 // Command line flags for upload command
 var (
 	apiKey             string
+	apiKeyFile         string
 	coursesPath        string
 	equivalenciesPath  string
 	studentsPath       string
 	studentCoursesPath string
+	uploadTimeout      time.Duration
+	skipValidation     bool
+	sheet              string
+	force              bool
+	deltaUpload        bool
+	manifestPath       string
 )
 
 // newUploadCmd creates a new upload command
@@ -23,74 +38,277 @@ func newUploadCmd() *cobra.Command {
 		Use:   "upload",
 		Short: "Upload files to the TRTC API",
 		Long: `Upload files to the Tennessee Reverse Transfer Consortium (TRTC) API.
-You can upload courses, equivalencies, students, and student courses files.
-At least one file must be specified.`,
+You can upload courses, equivalencies, students, and student courses files
+as CSV or Excel workbooks; workbooks are converted to CSV before upload.
+Files identical to the last successful upload of the same type to the same
+endpoint are skipped; use --force to send them anyway. With --delta, only the
+rows added or changed since the last successful upload of each type are sent.
+At least one file must be specified, either with the file flags or in a
+manifest given with --manifest.`,
 		Example: `  # Upload a courses file
   trtc-go upload -apikey="your-api-key" -courses="path/to/courses.csv"
 
   # Upload multiple file types
-  trtc-go upload -apikey="your-api-key" -courses="path/to/courses.csv" -equivalencies="path/to/equivalencies.csv"`,
+  trtc-go upload -apikey="your-api-key" -courses="path/to/courses.csv" -equivalencies="path/to/equivalencies.csv"
+
+  # Upload the second sheet of an Excel workbook
+  trtc-go upload -apikey="your-api-key" -students="path/to/students.xlsx" --sheet=2
+
+  # Run the upload job described in a manifest
+  trtc-go upload -apikey="your-api-key" --manifest=nightly.yaml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runUpload()
+			return runUpload(cmd)
 		},
 	}
 
 	// Add flags
-	uploadCmd.Flags().StringVar(&apiKey, "apikey", "", "API key for authentication (required)")
+	addAPIKeyFlags(uploadCmd, &apiKey, &apiKeyFile)
 	uploadCmd.Flags().StringVar(&coursesPath, "courses", "", "Path to courses file")
 	uploadCmd.Flags().StringVar(&equivalenciesPath, "equivalencies", "", "Path to equivalencies file")
 	uploadCmd.Flags().StringVar(&studentsPath, "students", "", "Path to students file")
 	uploadCmd.Flags().StringVar(&studentCoursesPath, "studentcourses", "", "Path to student courses file")
-
-	// Mark required flags
-	if err := uploadCmd.MarkFlagRequired("apikey"); err != nil {
-		fmt.Fprintf(os.Stderr, "Error marking apikey flag as required: %v\n", err)
-	}
+	uploadCmd.Flags().DurationVar(&uploadTimeout, "timeout", api.DefaultTimeout, "Maximum time to wait for the upload to complete")
+	addRetryFlags(uploadCmd)
+	uploadCmd.Flags().StringVar(&sheet, "sheet", "", "Worksheet to upload from Excel workbooks, by name or number (default: the sheet named after the file type, else the first)")
+	uploadCmd.Flags().BoolVar(&force, "force", false, "Upload files even when they are unchanged since the last successful upload")
+	uploadCmd.Flags().BoolVar(&deltaUpload, "delta", false, "Send only the rows added or changed since the last successful upload of each file type")
+	uploadCmd.Flags().StringVar(&manifestPath, "manifest", "", "YAML or JSON manifest describing the files and options of an upload job")
+	uploadCmd.Flags().BoolVar(&skipValidation, "skip-validation", false, "Upload without checking files against the TRTC file layouts")
 
 	return uploadCmd
 }
 
 // runUpload runs the upload command
-func runUpload() error {
-	// Check if at least one file is specified
-	if coursesPath == "" && equivalenciesPath == "" && studentsPath == "" && studentCoursesPath == "" {
-		return fmt.Errorf("at least one file must be specified")
-	}
+func runUpload(cmd *cobra.Command) error {
+	ctx := cmd.Context()
 
-	// Check if files exist
-	filesToCheck := map[string]string{
-		"courses":        coursesPath,
-		"equivalencies":  equivalenciesPath,
-		"students":       studentsPath,
-		"studentcourses": studentCoursesPath,
-	}
+	settings := runSettings{timeout: uploadTimeout, sheet: sheet, force: force, delta: deltaUpload}
+	fileFlagsSet := coursesPath != "" || equivalenciesPath != "" || studentsPath != "" || studentCoursesPath != ""
+
+	var job *manifest.Manifest
+	var files []models.UploadFile
+	if manifestPath != "" {
+		if fileFlagsSet {
+			return fmt.Errorf("--manifest cannot be combined with the file flags")
+		}
 
-	for name, path := range filesToCheck {
-		if path != "" {
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				return fmt.Errorf("%s file does not exist: %s", name, path)
+		var err error
+		job, err = manifest.Load(manifestPath)
+		if err != nil {
+			return err
+		}
+		files, err = job.UploadFiles()
+		if err != nil {
+			return err
+		}
+		Config, err = applyManifest(cmd, Config, &settings, job, manifestPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Check if at least one file is specified
+		if !fileFlagsSet {
+			return fmt.Errorf("at least one file must be specified")
+		}
+
+		// Check if files exist
+		filesToCheck := map[string]string{
+			"courses":        coursesPath,
+			"equivalencies":  equivalenciesPath,
+			"students":       studentsPath,
+			"studentcourses": studentCoursesPath,
+		}
+
+		for name, path := range filesToCheck {
+			if path != "" {
+				if _, err := os.Stat(path); os.IsNotExist(err) {
+					return fmt.Errorf("%s file does not exist: %s", name, path)
+				}
 			}
 		}
+
+		var err error
+		files, err = uploader.FilesFromPaths(coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Resolve the key of the manifest's profile, if it named one
+	key, err := resolveAPIKey(Config, apiKey, apiKeyFile)
+	if err != nil {
+		return err
 	}
 
-	// Create uploader
-	u := uploader.New(Config, Logger)
+	// Apply retry overrides for this run
+	applyRetryFlags(cmd, Config)
+	if skipValidation {
+		Config.ValidateFiles = false
+	}
 
-	// Upload files
-	Logger.Info("Uploading files to %s", Config.APIEndpoint)
-	response, err := u.UploadFilesFromPaths(apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+	u, err := newRunUploader(Config, settings)
 	if err != nil {
-		return fmt.Errorf("failed to upload files: %w", err)
+		return err
+	}
+
+	// Show a progress bar when running interactively, unless the result is for a script
+	var bar *progressBar
+	if isTerminal(os.Stdout) && !structuredOutput() {
+		bar = newProgressBar(os.Stdout)
+		u.SetProgressFunc(bar.Update)
 	}
 
-	// Print response
-	if response.Success {
-		fmt.Println("Upload successful!")
-		fmt.Println(response.Message)
+	// Upload files under a run ID that the log, the history and the output share
+	ctx, runID := startRun(ctx, Config)
+	response, err := u.UploadFilesWithContext(ctx, key, files)
+	if bar != nil {
+		bar.Finish()
+	}
+	skipped := errors.Is(err, uploader.ErrUnchanged)
+	var report *validation.Report
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		report = validationErr.Report
+	}
+	err = uploadError(response, err, settings.timeout)
+
+	// Run the manifest's post-upload actions
+	if err == nil && !skipped && job != nil {
+		if afterErr := job.RunAfter(files, time.Now()); afterErr != nil {
+			err = fmt.Errorf("upload succeeded but the after actions failed: %w", afterErr)
+		}
+	}
+
+	if structuredOutput() {
+		if outErr := writeOutput(newUploadResult(runID, response, report, skipped, err)); outErr != nil && err == nil {
+			return outErr
+		}
+		return err
+	}
+	fmt.Printf("Run ID: %s\n", runID)
+	switch {
+	case skipped:
+		fmt.Println("Nothing to upload: all files are unchanged since the last successful upload. Use --force to upload them anyway.")
+	case report != nil:
+		printValidationReport(report)
+	case response != nil:
+		if response.Success {
+			fmt.Println("Upload successful!")
+		} else {
+			fmt.Printf("Upload failed with status code %d\n", response.Code)
+		}
+		printUploadResponse(response)
+	}
+	return err
+}
+
+// uploadError returns the error the upload command fails with, with its exit
+// code, or nil when the server accepted the upload or nothing needed sending
+func uploadError(response *models.UploadResponse, err error, timeout time.Duration) error {
+	var validationErr *validation.Error
+	switch {
+	case errors.Is(err, api.ErrCanceled) && errors.Is(err, context.DeadlineExceeded):
+		return withExitCode(exitNetwork, fmt.Errorf("upload timed out after %s", timeout))
+	case errors.Is(err, api.ErrCanceled):
+		return withExitCode(exitCancelled, fmt.Errorf("upload cancelled"))
+	case errors.Is(err, uploader.ErrUnchanged):
 		return nil
-	} else {
-		fmt.Printf("Upload failed with status code %d\n", response.Code)
-		fmt.Println(response.Message)
-		return fmt.Errorf("upload failed")
+	case errors.As(err, &validationErr):
+		return fmt.Errorf("%w; fix the files or use --skip-validation to upload anyway", err)
+	case err != nil:
+		return fmt.Errorf("failed to upload files: %w", err)
+	case !response.Success:
+		return withExitCode(rejectionCode(response.Code), fmt.Errorf("upload failed"))
+	}
+	return nil
+}
+
+// printUploadResponse prints the details the server reported about an upload.
+// The server's text is redacted, since it may echo the submitted API key.
+func printUploadResponse(response *models.UploadResponse) {
+	if response.Message != "" {
+		fmt.Println(logger.Redact(response.Message))
+	}
+	if response.ReferenceID != "" {
+		fmt.Printf("Reference ID: %s\n", response.ReferenceID)
+	}
+	if response.RecordsAccepted != 0 || response.RecordsRejected != 0 {
+		fmt.Printf("Records accepted: %d, rejected: %d\n", response.RecordsAccepted, response.RecordsRejected)
+	}
+	for _, file := range response.Files {
+		status := "ok"
+		if !file.Success {
+			status = "failed"
+		}
+		fmt.Printf("  %s (%s): %s, %d accepted, %d rejected\n", file.FileName, file.Type.String(), status, file.RecordsAccepted, file.RecordsRejected)
+		for _, e := range file.Errors {
+			fmt.Printf("    - %s\n", logger.Redact(e))
+		}
+	}
+	for _, e := range response.Errors {
+		fmt.Printf("Error: %s\n", logger.Redact(e))
+	}
+}
+
+// redactAll returns a redacted copy of messages, never nil
+func redactAll(messages []string) []string {
+	redacted := make([]string, len(messages))
+	for i, message := range messages {
+		redacted[i] = logger.Redact(message)
+	}
+	return redacted
+}
+
+// responseError describes an upload the server did not accept
+func responseError(response *models.UploadResponse) error {
+	message := fmt.Sprintf("upload failed with status code %d", response.Code)
+	if response.Message != "" {
+		message += ": " + response.Message
+	}
+	for _, e := range response.Errors {
+		message += "\n" + e
+	}
+	return withExitCode(rejectionCode(response.Code), errors.New(message))
+}
+
+// uploadResult is what upload writes with --output json or yaml
+type uploadResult struct {
+	RunID string `json:"runId"`
+	// Status is "skipped" when nothing needed sending, otherwise the name of the exit code
+	Status     string                 `json:"status"`
+	ExitCode   int                    `json:"exitCode"`
+	Error      string                 `json:"error,omitempty"`
+	Response   *models.UploadResponse `json:"response"`
+	Validation *validation.Report     `json:"validation,omitempty"`
+}
+
+// newUploadResult describes the outcome of an upload for --output json or yaml
+func newUploadResult(runID string, response *models.UploadResponse, report *validation.Report, skipped bool, err error) uploadResult {
+	result := uploadResult{
+		RunID:      runID,
+		ExitCode:   exitCode(err),
+		Response:   response,
+		Validation: normalizeReport(report),
+	}
+	result.Status = exitStatuses[result.ExitCode]
+	if skipped {
+		result.Status = "skipped"
+	}
+	if err != nil {
+		result.Error = logger.Redact(err.Error())
+	}
+	if response != nil {
+		// Lists are written as [] rather than null when empty, and the
+		// server's text is redacted as it is when printed
+		normalized := *response
+		normalized.Message = logger.Redact(response.Message)
+		normalized.Files = make([]models.FileResult, len(response.Files))
+		for i, file := range response.Files {
+			file.Errors = redactAll(file.Errors)
+			normalized.Files[i] = file
+		}
+		normalized.Errors = redactAll(response.Errors)
+		result.Response = &normalized
 	}
+	return result
 }
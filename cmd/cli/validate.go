@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/chatt-state/trtc-go/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for validate command
+var (
+	maxIssues      int
+	strictValidate bool
+)
+
+// newValidateCmd creates a new validate command
+func newValidateCmd() *cobra.Command {
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check files against the TRTC file layouts without uploading",
+		Long: `Check courses, equivalencies, students and student courses files against the
+columns TRTC expects. Headers, required values, data types, lengths and allowed
+values are checked, and every problem is reported with its line and column.
+When the files are valid on their own they are checked against each other for
+duplicate keys, student courses and equivalencies that reference students or
+courses missing from the accompanying files, and mismatched credit hours.
+Excel workbooks are converted to CSV first. At least one file must be specified.
+
+The layouts are not confirmed against a published TRTC file specification, so
+problems they find are warnings unless --strict or the validate_strict setting
+is given. Files that cannot be read as CSV, such as rows with the wrong number
+of fields, are always errors.`,
+		Example: `  # Validate a students file
+  trtc-go validate --students="path/to/students.csv"
+
+  # Validate every file before a full upload
+  trtc-go validate --courses=courses.csv --equivalencies=equivalencies.csv --students=students.csv --studentcourses=studentcourses.csv`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runValidate()
+		},
+	}
+
+	// Add flags
+	validateCmd.Flags().StringVar(&coursesPath, "courses", "", "Path to courses file")
+	validateCmd.Flags().StringVar(&equivalenciesPath, "equivalencies", "", "Path to equivalencies file")
+	validateCmd.Flags().StringVar(&studentsPath, "students", "", "Path to students file")
+	validateCmd.Flags().StringVar(&studentCoursesPath, "studentcourses", "", "Path to student courses file")
+	validateCmd.Flags().StringVar(&sheet, "sheet", "", "Worksheet to read from Excel workbooks, by name or number")
+	validateCmd.Flags().IntVar(&maxIssues, "max-issues", validation.DefaultMaxIssues, "Maximum number of issues to report per file")
+	validateCmd.Flags().BoolVar(&strictValidate, "strict", false, "Report layout problems as errors (default from validate_strict)")
+
+	return validateCmd
+}
+
+// runValidate runs the validate command
+func runValidate() error {
+	files, err := uploader.FilesFromPaths(coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("at least one file must be specified")
+	}
+
+	u, err := uploader.New(Config, Logger)
+	if err != nil {
+		return fmt.Errorf("failed to create uploader: %w", err)
+	}
+	u.SetSheet(sheet)
+
+	report, err := u.Validate(files, validation.Options{MaxIssues: maxIssues, Strict: strictValidate || Config.ValidateStrict})
+	if report == nil {
+		return err
+	}
+	if structuredOutput() {
+		result := validateResult{
+			ExitCode: exitCode(err),
+			Errors:   report.Errors(),
+			Files:    normalizeReport(report).Files,
+		}
+		result.Status = exitStatuses[result.ExitCode]
+		if outErr := writeOutput(result); outErr != nil && err == nil {
+			return outErr
+		}
+		return err
+	}
+	printValidationReport(report)
+
+	if err != nil {
+		return err
+	}
+	fmt.Println("All files are valid.")
+	return nil
+}
+
+// printValidationReport prints the issues found in each file
+func printValidationReport(report *validation.Report) {
+	for _, file := range report.Files {
+		fmt.Printf("%s (%s): %d row(s), %d error(s)\n", file.Path, file.Type.String(), file.Rows, file.Errors())
+		for _, issue := range file.Issues {
+			fmt.Printf("  %s\n", issue)
+		}
+		if file.Truncated > 0 {
+			fmt.Printf("  ... %d more error(s) not shown\n", file.Truncated)
+		}
+	}
+}
+
+// validateResult is what validate writes with --output json or yaml
+type validateResult struct {
+	// Status is "success" when every file is valid and "invalid" otherwise
+	Status   string `json:"status"`
+	ExitCode int    `json:"exitCode"`
+	// Errors is the number of errors across all files, including those not listed
+	Errors int                      `json:"errors"`
+	Files  []*validation.FileReport `json:"files"`
+}
+
+// normalizeReport makes the empty lists of a report encode as [] rather than null
+func normalizeReport(report *validation.Report) *validation.Report {
+	if report == nil {
+		return nil
+	}
+	if report.Files == nil {
+		report.Files = []*validation.FileReport{}
+	}
+	for _, file := range report.Files {
+		if file.Issues == nil {
+			file.Issues = []validation.Issue{}
+		}
+	}
+	return report
+}
+
+// validationDetails lists the problems in a validation report, one per line
+func validationDetails(report *validation.Report) string {
+	var lines []string
+	for _, file := range report.Files {
+		for _, issue := range file.Issues {
+			lines = append(lines, fmt.Sprintf("%s: %s", file.Path, issue))
+		}
+		if file.Truncated > 0 {
+			lines = append(lines, fmt.Sprintf("%s: ... %d more error(s) not shown", file.Path, file.Truncated))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
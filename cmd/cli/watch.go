@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/api"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/chatt-state/trtc-go/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// Command line flags for watch command
+var (
+	watchAPIKey         string
+	watchAPIKeyFile     string
+	watchSettle         time.Duration
+	watchPatterns       map[string]string
+	watchProcessedDir   string
+	watchFailedDir      string
+	watchTimeout        time.Duration
+	watchSheet          string
+	watchForce          bool
+	watchDelta          bool
+	watchSkipValidation bool
+)
+
+// newWatchCmd creates a new watch command
+func newWatchCmd() *cobra.Command {
+	watchCmd := &cobra.Command{
+		Use:   "watch <dir>",
+		Short: "Upload files as they are added to a folder",
+		Long: `Watch a folder and upload files as they are added to it, until interrupted.
+Files are recognized by name: a name starting with courses, equivalencies,
+students or student courses (ignoring case, spaces, dashes and underscores)
+is a file of that type. Use --pattern to match other names. Once no file in
+the folder has changed for the settle time, the files are validated and
+uploaded together, then moved to the processed folder, or to the failed
+folder with a .error.txt file explaining why. Files already in the folder
+are uploaded when watching starts.`,
+		Example: `  # Upload exports as they are dropped in a folder
+  trtc-go watch -apikey="your-api-key" /srv/trtc/outbox
+
+  # Recognize the student information system's own file names
+  trtc-go watch -apikey="your-api-key" --pattern="students=STU_EXTRACT_*.csv" /srv/trtc/outbox`,
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{consoleLevelAnnotation: "info"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWatch(cmd, args[0])
+		},
+	}
+
+	// Add flags
+	addAPIKeyFlags(watchCmd, &watchAPIKey, &watchAPIKeyFile)
+	watchCmd.Flags().DurationVar(&watchSettle, "settle", watch.DefaultSettle, "How long the folder must be unchanged before its files are uploaded")
+	watchCmd.Flags().StringToStringVar(&watchPatterns, "pattern", nil, "File name pattern for a file type, such as students=STU_*.csv; may be repeated")
+	watchCmd.Flags().StringVar(&watchProcessedDir, "processed-dir", watch.ProcessedDir, "Folder for uploaded files, relative to the watched folder")
+	watchCmd.Flags().StringVar(&watchFailedDir, "failed-dir", watch.FailedDir, "Folder for files that failed to upload, relative to the watched folder")
+	watchCmd.Flags().DurationVar(&watchTimeout, "timeout", api.DefaultTimeout, "Maximum time to wait for each upload to complete")
+	watchCmd.Flags().StringVar(&watchSheet, "sheet", "", "Worksheet to upload from Excel workbooks, by name or number")
+	watchCmd.Flags().BoolVar(&watchForce, "force", false, "Upload files even when they are unchanged since the last successful upload")
+	watchCmd.Flags().BoolVar(&watchDelta, "delta", false, "Send only the rows added or changed since the last successful upload of each file type")
+	addRetryFlags(watchCmd)
+	watchCmd.Flags().BoolVar(&watchSkipValidation, "skip-validation", false, "Upload without checking files against the TRTC file layouts")
+
+	return watchCmd
+}
+
+// runWatch runs the watch command
+func runWatch(cmd *cobra.Command, dir string) error {
+	key, err := resolveAPIKey(Config, watchAPIKey, watchAPIKeyFile)
+	if err != nil {
+		return err
+	}
+
+	patterns := map[models.FileType]string{}
+	for name, pattern := range watchPatterns {
+		ft, err := models.ParseFileType(name)
+		if err != nil {
+			return fmt.Errorf("invalid --pattern: %w", err)
+		}
+		patterns[ft] = pattern
+	}
+
+	applyRetryFlags(cmd, Config)
+	if watchSkipValidation {
+		Config.ValidateFiles = false
+	}
+
+	u, err := newRunUploader(Config, runSettings{timeout: watchTimeout, sheet: watchSheet, force: watchForce, delta: watchDelta})
+	if err != nil {
+		return err
+	}
+	upload := func(ctx context.Context, files []models.UploadFile) error {
+		err := uploadUnattended(ctx, u, Config, key, files)
+		switch {
+		case errors.Is(err, uploader.ErrUnchanged):
+			return watch.ErrSkipped
+		case exitCode(err) == exitNetwork:
+			// The server was unreachable or unavailable, not the files at fault
+			return watch.Retry(err)
+		}
+		return err
+	}
+
+	w, err := watch.New(watch.Options{
+		Dir:          dir,
+		Settle:       watchSettle,
+		Patterns:     patterns,
+		ProcessedDir: watchProcessedDir,
+		FailedDir:    watchFailedDir,
+	}, upload, Logger)
+	if err != nil {
+		return err
+	}
+	return w.Run(cmd.Context())
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chatt-state/trtc-go/internal/secret"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+// hasStoredAPIKey reports whether an API key is stored for the profile in use
+func hasStoredAPIKey() bool {
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		return false
+	}
+	stored, err := store.Has(secret.APIKey(Config.CurrentProfile()))
+	if err != nil {
+		Logger.Warning("Stored API keys are unavailable: %v", err)
+	}
+	return stored
+}
+
+// resolveAPIKey calls onKey with the API key to upload with: the one entered,
+// else the stored key of the profile in use, asking for the passphrase of the
+// stored keys when needed. With remember, an entered key is stored for the
+// profile; without it, the profile's stored key is forgotten.
+func resolveAPIKey(w fyne.Window, entered string, remember bool, onKey func(key string)) {
+	store, err := secret.OpenFileStore()
+	if err != nil {
+		showError(err, w)
+		return
+	}
+	profile := Config.CurrentProfile()
+	name := secret.APIKey(profile)
+
+	var resolve func()
+	resolve = func() {
+		switch {
+		case remember && entered != "":
+			if err := store.Set(name, entered); errors.Is(err, secret.ErrPassphraseRequired) {
+				askPassphrase(w, store, resolve)
+				return
+			} else if err != nil {
+				showError(fmt.Errorf("failed to remember API key: %w", err), w)
+				return
+			}
+			Logger.Info("Stored the API key of profile %s", profile)
+		case !remember:
+			if stored, _ := store.Has(name); stored {
+				if err := store.Delete(name); err != nil {
+					showError(fmt.Errorf("failed to forget API key: %w", err), w)
+					return
+				}
+				Logger.Info("Removed the stored API key of profile %s", profile)
+			}
+		}
+		if entered != "" {
+			logger.RegisterSecret(entered)
+			onKey(entered)
+			return
+		}
+
+		key, _, err := secret.LookupAPIKey(Config, store)
+		switch {
+		case errors.Is(err, secret.ErrPassphraseRequired):
+			askPassphrase(w, store, resolve)
+		case errors.Is(err, secret.ErrNotFound):
+			showError(fmt.Errorf("API key is required"), w)
+		case err != nil:
+			showError(fmt.Errorf("failed to read the stored API key: %w", err), w)
+		default:
+			logger.RegisterSecret(key)
+			onKey(key)
+		}
+	}
+	resolve()
+}
+
+// askPassphrase asks for the passphrase of the stored API keys and calls
+// onEntered once it is set
+func askPassphrase(w fyne.Window, store *secret.FileStore, onEntered func()) {
+	passphraseEntry := widget.NewPasswordEntry()
+	dialog.ShowForm("Stored API Keys", "Unlock", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Passphrase", passphraseEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		logger.RegisterSecret(passphraseEntry.Text)
+		store.SetPassphrase(passphraseEntry.Text)
+		onEntered()
+	}, w)
+}
+
+// showError shows an error dialog with secrets redacted
+func showError(err error, w fyne.Window) {
+	dialog.ShowError(errors.New(logger.Redact(err.Error())), w)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chatt-state/trtc-go/internal/history"
+)
+
+// historyLimit is the number of recent uploads shown in the History tab
+const historyLimit = 200
+
+// createHistoryContent creates the History tab, which lists past uploads with
+// the most recent first. The returned function reloads the list.
+func createHistoryContent(journal *history.Journal) (fyne.CanvasObject, func()) {
+	var entries []history.Entry
+
+	details := widget.NewLabel("Select an upload to see its details")
+	details.Wrapping = fyne.TextWrapWord
+
+	list := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			entry := entries[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s  %-9s  %s",
+				entry.Time.Local().Format("2006-01-02 15:04"), entry.Status, historyFileTypes(entry.Files)))
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		details.SetText(historyDetails(entries[id]))
+	}
+
+	statusSelect := widget.NewSelect([]string{"All", string(history.StatusSuccess), string(history.StatusFailed), string(history.StatusCancelled), string(history.StatusSkipped)}, nil)
+	statusSelect.SetSelected("All")
+
+	refresh := func() {
+		if journal == nil {
+			details.SetText("Upload history is unavailable")
+			return
+		}
+
+		filter := history.Filter{Limit: historyLimit}
+		if statusSelect.Selected != "All" {
+			filter.Status = history.Status(statusSelect.Selected)
+		}
+		loaded, err := journal.Entries(filter)
+		if err != nil {
+			details.SetText("Error: " + err.Error())
+			return
+		}
+
+		// Show the most recent upload first
+		for i, j := 0, len(loaded)-1; i < j; i, j = i+1, j-1 {
+			loaded[i], loaded[j] = loaded[j], loaded[i]
+		}
+		entries = loaded
+		list.UnselectAll()
+		list.Refresh()
+		if len(entries) == 0 {
+			details.SetText("No uploads found")
+		} else {
+			details.SetText("Select an upload to see its details")
+		}
+	}
+	statusSelect.OnChanged = func(string) {
+		refresh()
+	}
+
+	refreshButton := widget.NewButtonWithIcon("Refresh", theme.ViewRefreshIcon(), refresh)
+	refreshButton.Importance = widget.HighImportance
+
+	refresh()
+
+	toolbar := container.NewHBox(widget.NewLabel("Status:"), statusSelect, refreshButton)
+	split := container.NewVSplit(list, container.NewVScroll(details))
+	split.SetOffset(0.5)
+
+	return container.NewBorder(container.NewPadded(toolbar), nil, nil, nil, split), refresh
+}
+
+// historyDetails describes an upload for the details panel
+func historyDetails(entry history.Entry) string {
+	lines := []string{
+		"ID: " + entry.ID,
+		"Time: " + entry.Time.Local().Format(time.RFC1123),
+		"Endpoint: " + entry.Endpoint,
+		"Status: " + string(entry.Status),
+	}
+	if entry.Profile != "" {
+		lines = append(lines, "Profile: "+entry.Profile)
+	}
+	if entry.Code != 0 {
+		lines = append(lines, fmt.Sprintf("Code: %d", entry.Code))
+	}
+	if entry.Message != "" {
+		lines = append(lines, "Message: "+entry.Message)
+	}
+	if entry.ReferenceID != "" {
+		lines = append(lines, "Reference: "+entry.ReferenceID)
+	}
+	if entry.Error != "" {
+		lines = append(lines, "Error: "+entry.Error)
+	}
+	if timings := entry.Timings; timings != nil {
+		lines = append(lines, fmt.Sprintf("Duration: %s (convert %s, validate %s, upload %s)", timings.Total.Round(time.Millisecond),
+			timings.Convert.Round(time.Millisecond), timings.Validate.Round(time.Millisecond), timings.Upload.Round(time.Millisecond)))
+	} else {
+		lines = append(lines, "Duration: "+entry.Duration.Round(time.Millisecond).String())
+	}
+	for _, file := range entry.Files {
+		lines = append(lines,
+			"",
+			fmt.Sprintf("%s: %s", file.Type.String(), file.Path),
+			fmt.Sprintf("  %d bytes, %d rows", file.Size, file.Rows),
+		)
+		if file.SHA256 != "" {
+			lines = append(lines, "  SHA-256 "+file.SHA256)
+		}
+		if file.DeltaRows > 0 {
+			lines = append(lines, fmt.Sprintf("  Delta: %d added or changed rows sent", file.DeltaRows))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// historyFileTypes lists the types of the files in an upload
+func historyFileTypes(files []history.File) string {
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Type.String()
+	}
+	return strings.Join(names, ", ")
+}
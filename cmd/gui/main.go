@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/ncruces/zenity"
 
+	"github.com/chatt-state/trtc-go/internal/api"
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/history"
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/secret"
+	"github.com/chatt-state/trtc-go/internal/uploader"
+	"github.com/chatt-state/trtc-go/internal/validation"
 	"github.com/chatt-state/trtc-go/pkg/logger"
 	"github.com/chatt-state/trtc-go/ui"
 )
 
+// progressInterval is how often upload progress is shown, as in the CLI progress bar
+const progressInterval = 100 * time.Millisecond
+
 var (
 	// Version is the version of the application
 	Version = "0.0.1"
@@ -27,6 +42,12 @@ var (
 
 	// Logger is the application logger
 	Logger *logger.Logger
+
+	// Journal records upload attempts; nil when the history is unavailable
+	Journal *history.Journal
+
+	// Snapshots keeps the last uploaded file of each type for delta uploads; nil when unavailable
+	Snapshots *delta.Store
 )
 
 func main() {
@@ -43,47 +64,97 @@ func main() {
 	w := a.NewWindow("TRTC File Uploader")
 	w.Resize(fyne.NewSize(600, 400))
 
-	// Load configuration
+	// Load configuration, from $TRTC_CONFIG if set
+	config.SetFile(os.Getenv(config.EnvPrefix + "_CONFIG"))
 	var err error
 	Config, err = config.LoadConfig()
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to load configuration: %w", err), w)
+		showError(fmt.Errorf("failed to load configuration: %w", err), w)
+		return
+	}
+
+	// Use the profile in $TRTC_PROFILE, else the active one, falling back to
+	// the top-level settings if it is gone
+	profile := os.Getenv(config.EnvPrefix + "_PROFILE")
+	if profile == "" {
+		profile = Config.ActiveProfile
+	}
+	profileErr := Config.UseProfile(profile)
+
+	// Environment variables override the file and the profile
+	if err := Config.ApplyEnv(); err != nil {
+		showError(err, w)
 		return
 	}
 
+	// Keep known secrets out of the log and error messages
+	logger.RegisterSecret(Config.APIKey)
+	logger.RegisterSecret(os.Getenv(secret.PassphraseEnv))
+
 	// Create logger
-	logFilePath := Config.LogFile
-	if !filepath.IsAbs(logFilePath) {
-		// If log file path is not absolute, make it relative to the current directory
-		logFilePath, err = filepath.Abs(logFilePath)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("failed to get absolute path for log file: %w", err), w)
-			return
-		}
+	logFilePath, err := Config.LogPath()
+	if err != nil {
+		showError(fmt.Errorf("failed to locate log file: %w", err), w)
+		return
 	}
 
-	Logger, err = logger.New(logFilePath, logger.LevelInfo)
+	// Invalid log settings, or an unreachable syslog socket, fall back to
+	// the defaults rather than stopping the GUI
+	logOpts, logErrs := logOptions(Config)
+	Logger, err = logger.NewWithOptions(logFilePath, logOpts)
+	if err != nil && logOpts.Syslog != nil {
+		logErrs = append(logErrs, err)
+		logOpts.Syslog = nil
+		Logger, err = logger.NewWithOptions(logFilePath, logOpts)
+	}
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to create logger: %w", err), w)
+		showError(fmt.Errorf("failed to create logger: %w", err), w)
 		return
 	}
 	defer Logger.Close()
+	for _, err := range logErrs {
+		Logger.Warning("Using the default log settings: %v", err)
+	}
+	if profileErr != nil {
+		Logger.Warning("Using the default profile: %v", profileErr)
+	}
+
+	// Open the upload history
+	Journal, err = history.Open()
+	if err != nil {
+		Logger.Warning("Upload history is unavailable: %v", err)
+	}
+	Snapshots, err = delta.OpenStore()
+	if err != nil {
+		Logger.Warning("Upload snapshots are unavailable: %v", err)
+	}
+
+	// Create the profile selector, which reloads when the settings are saved;
+	// switching profiles reloads the API key options of the upload tab
+	var refreshAPIKey func()
+	profileBar, refreshProfiles := createProfileBar(w, func() {
+		refreshAPIKey()
+	})
+
+	// Create the upload and history tabs; the history reloads after each upload
+	historyContent, refreshHistory := createHistoryContent(Journal)
+	uploadContent, refreshAPIKey := createMainContent(w, refreshHistory, refreshProfiles)
+	tabs := container.NewAppTabs(
+		container.NewTabItemWithIcon("Upload", theme.UploadIcon(), uploadContent),
+		container.NewTabItemWithIcon("History", theme.HistoryIcon(), historyContent),
+	)
 
 	// Create main content
-	content := container.NewVBox(
+	content := container.NewBorder(container.NewVBox(
 		widget.NewLabelWithStyle("TRTC File Uploader", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 		widget.NewLabel("Upload files to the Tennessee Reverse Transfer Consortium (TRTC) API"),
 		container.NewHBox(
 			widget.NewLabel("Version:"),
 			widget.NewLabel(Version),
 		),
-		container.NewHBox(
-			widget.NewLabel("API Endpoint:"),
-			widget.NewLabel(Config.APIEndpoint),
-		),
+		profileBar,
 		widget.NewSeparator(),
-		createMainContent(w),
-	)
+	), nil, nil, nil, tabs)
 
 	// Set window content
 	w.SetContent(content)
@@ -92,8 +163,10 @@ func main() {
 	w.ShowAndRun()
 }
 
-// createMainContent creates the main content of the application
-func createMainContent(w fyne.Window) fyne.CanvasObject {
+// createMainContent creates the upload tab of the application; onUploaded is called after
+// each upload attempt and onSettingsSaved after the settings are saved. The returned
+// function reloads the API key options after switching profiles.
+func createMainContent(w fyne.Window, onUploaded func(), onSettingsSaved func()) (fyne.CanvasObject, func()) {
 	// Create file selection widgets
 	coursesCheck := widget.NewCheck("Courses", nil)
 	coursesPath := widget.NewEntry()
@@ -127,67 +200,131 @@ func createMainContent(w fyne.Window) fyne.CanvasObject {
 	})
 	studentCoursesButton.Importance = widget.HighImportance
 
+	// Create a progress bar per file row, bound to a value the upload sets
+	progress := map[models.FileType]binding.Float{}
+	progressBars := map[models.FileType]*widget.ProgressBar{}
+	for _, ft := range models.FileTypes {
+		progress[ft] = binding.NewFloat()
+		progressBars[ft] = widget.NewProgressBarWithData(progress[ft])
+	}
+
 	// Create API key entry
 	apiKeyLabel := widget.NewLabel("API Key:")
 	apiKeyEntry := widget.NewPasswordEntry()
-	apiKeyEntry.SetPlaceHolder("Enter your API key")
+	apiKeyEntry.SetPlaceHolder("Enter your API key, or leave empty to use the stored key")
+
+	// Create remember key checkbox, checked while a key is stored for the profile
+	rememberCheck := widget.NewCheck("Remember key", nil)
+	rememberCheck.SetChecked(hasStoredAPIKey())
+	keyProfile := Config.CurrentProfile()
+	refreshAPIKey := func() {
+		if Config.CurrentProfile() == keyProfile {
+			return
+		}
+		keyProfile = Config.CurrentProfile()
+		apiKeyEntry.SetText("")
+		rememberCheck.SetChecked(hasStoredAPIKey())
+	}
 
-	// Create status label
-	statusLabel := widget.NewLabelWithStyle("Ready", fyne.TextAlignCenter, fyne.TextStyle{})
+	// Create Excel sheet entry
+	sheetLabel := widget.NewLabel("Excel Sheet:")
+	sheetEntry := widget.NewEntry()
+	sheetEntry.SetPlaceHolder("Sheet name or number (optional)")
+
+	// Create force checkbox
+	forceCheck := widget.NewCheck("Upload unchanged files", nil)
+
+	// Create delta checkbox
+	deltaCheck := widget.NewCheck("Send only rows changed since the last upload", nil)
+
+	// Create status label, bound to a value the upload sets
+	status := binding.NewString()
+	status.Set("Ready")
+	statusLabel := widget.NewLabelWithData(status)
+	statusLabel.Alignment = fyne.TextAlignCenter
 
 	// Create buttons
 	settingsButton := widget.NewButtonWithIcon("Settings", theme.SettingsIcon(), func() {
-		showSettingsDialog(w)
+		showSettingsDialog(w, onSettingsSaved)
 	})
 	settingsButton.Importance = widget.HighImportance
 
-	uploadButton := widget.NewButtonWithIcon("Upload Files", theme.UploadIcon(), func() {
-		// Validate input
-		if apiKeyEntry.Text == "" {
-			dialog.ShowError(fmt.Errorf("API key is required"), w)
-			return
+	// The cancel button aborts the upload currently in progress
+	var cancelUpload context.CancelFunc
+	cancelButton := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		if cancelUpload != nil {
+			cancelUpload()
 		}
+	})
+	cancelButton.Importance = widget.DangerImportance
+	cancelButton.Disable()
 
+	var uploadButton *widget.Button
+	uploadButton = widget.NewButtonWithIcon("Upload Files", theme.UploadIcon(), func() {
 		// Check if at least one file is selected
 		if !coursesCheck.Checked && !equivalenciesCheck.Checked && !studentsCheck.Checked && !studentCoursesCheck.Checked {
-			dialog.ShowError(fmt.Errorf("at least one file must be selected"), w)
+			showError(fmt.Errorf("at least one file must be selected"), w)
 			return
 		}
 
-		// Perform upload
-		go performUpload(
-			w,
-			statusLabel,
-			apiKeyEntry.Text,
-			coursesCheck.Checked, coursesPath.Text,
-			equivalenciesCheck.Checked, equivalenciesPath.Text,
-			studentsCheck.Checked, studentsPath.Text,
-			studentCoursesCheck.Checked, studentCoursesPath.Text,
-		)
+		// Use the key entered, else the stored one
+		resolveAPIKey(w, apiKeyEntry.Text, rememberCheck.Checked, func(apiKey string) {
+			// Perform upload
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelUpload = cancel
+			uploadButton.Disable()
+			cancelButton.Enable()
+			go func() {
+				defer func() {
+					cancel()
+					cancelButton.Disable()
+					uploadButton.Enable()
+					onUploaded()
+				}()
+				performUpload(
+					ctx,
+					w,
+					status,
+					progress,
+					apiKey,
+					sheetEntry.Text,
+					forceCheck.Checked,
+					deltaCheck.Checked,
+					coursesCheck.Checked, coursesPath.Text,
+					equivalenciesCheck.Checked, equivalenciesPath.Text,
+					studentsCheck.Checked, studentsPath.Text,
+					studentCoursesCheck.Checked, studentCoursesPath.Text,
+				)
+			}()
+		})
 	})
 	uploadButton.Importance = widget.HighImportance
 
 	// Create layout with more padding and spacing for a traditional desktop look
 	fileSelectionContainer := container.NewVBox(
-		container.NewGridWithColumns(3,
+		container.NewGridWithColumns(4,
 			coursesCheck,
 			coursesPath,
 			coursesButton,
+			progressBars[models.FileTypeCourses],
 		),
-		container.NewGridWithColumns(3,
+		container.NewGridWithColumns(4,
 			equivalenciesCheck,
 			equivalenciesPath,
 			equivalenciesButton,
+			progressBars[models.FileTypeEquivalencies],
 		),
-		container.NewGridWithColumns(3,
+		container.NewGridWithColumns(4,
 			studentsCheck,
 			studentsPath,
 			studentsButton,
+			progressBars[models.FileTypeStudents],
 		),
-		container.NewGridWithColumns(3,
+		container.NewGridWithColumns(4,
 			studentCoursesCheck,
 			studentCoursesPath,
 			studentCoursesButton,
+			progressBars[models.FileTypeStudentCourses],
 		),
 	)
 
@@ -195,13 +332,19 @@ func createMainContent(w fyne.Window) fyne.CanvasObject {
 		container.NewGridWithColumns(2,
 			apiKeyLabel,
 			apiKeyEntry,
+			sheetLabel,
+			sheetEntry,
 		),
+		rememberCheck,
+		forceCheck,
+		deltaCheck,
 	)
 
 	buttonContainer := container.NewHBox(
 		settingsButton,
 		widget.NewSeparator(),
 		uploadButton,
+		cancelButton,
 	)
 
 	// Add padding around each section
@@ -212,7 +355,7 @@ func createMainContent(w fyne.Window) fyne.CanvasObject {
 		widget.NewSeparator(),
 		container.NewPadded(buttonContainer),
 		statusLabel,
-	)
+	), refreshAPIKey
 }
 
 // selectFile shows a file dialog to select a file
@@ -221,7 +364,7 @@ func selectFile(w fyne.Window, entry *widget.Entry, check *widget.Check) {
 	path, err := zenity.SelectFile(
 		zenity.Title("Select File"),
 		zenity.FileFilters{
-			{Name: "Spreadsheet Files", Patterns: []string{"*.csv", "*.xlsx", "*.xls"}},
+			{Name: "Spreadsheet Files", Patterns: []string{"*.csv", "*.xlsx", "*.xlsm"}},
 			{Name: "All Files", Patterns: []string{"*"}},
 		},
 		zenity.Modal(),
@@ -241,7 +384,7 @@ func selectFile(w fyne.Window, entry *widget.Entry, check *widget.Check) {
 		// Fall back to Fyne's dialog only on actual errors
 		dialog.ShowFileOpen(func(uri fyne.URIReadCloser, err error) {
 			if err != nil {
-				dialog.ShowError(err, w)
+				showError(err, w)
 				return
 			}
 			if uri == nil {
@@ -260,37 +403,123 @@ func selectFile(w fyne.Window, entry *widget.Entry, check *widget.Check) {
 	}
 }
 
-// showSettingsDialog shows the settings dialog
-func showSettingsDialog(w fyne.Window) {
+// showSettingsDialog shows the settings dialog; onSaved is called after the settings are saved
+func showSettingsDialog(w fyne.Window, onSaved func()) {
 	// Create form items
 	endpointEntry := widget.NewEntry()
-	endpointEntry.SetText(Config.APIEndpoint)
-
 	logFileEntry := widget.NewEntry()
-	logFileEntry.SetText(Config.LogFile)
-
 	ignoreCertErrorCheck := widget.NewCheck("Ignore Certificate Errors", nil)
-	ignoreCertErrorCheck.SetChecked(Config.IgnoreCertError)
+
+	caCertEntry := widget.NewEntry()
+	caCertEntry.SetPlaceHolder("Optional PEM bundle for a custom CA")
+
+	clientCertEntry := widget.NewEntry()
+	clientCertEntry.SetPlaceHolder("Optional PEM client certificate")
+
+	clientKeyEntry := widget.NewEntry()
+	clientKeyEntry.SetPlaceHolder("Optional PEM client key")
+
+	validateFilesCheck := widget.NewCheck("Validate files before upload", nil)
+	validateFilesCheck.SetChecked(Config.ValidateFiles)
+
+	minTLSVersionSelect := widget.NewSelect([]string{"1.0", "1.1", "1.2", "1.3"}, nil)
+
+	logLevelSelect := widget.NewSelect([]string{"debug", "info", "warn", "error"}, nil)
+	logLevelSelect.SetSelected("info")
+	if level, err := logger.ParseLevel(Config.LogLevel); err == nil {
+		logLevelSelect.SetSelected(logger.LevelName(level))
+	}
+
+	logFormatSelect := widget.NewSelect([]string{logger.FormatText, logger.FormatJSON}, nil)
+	logFormatSelect.SetSelected(logger.FormatText)
+	if format, err := logger.ParseFormat(Config.LogFormat); err == nil {
+		logFormatSelect.SetSelected(format)
+	}
+
+	// loadProfile fills the profile settings from a configuration
+	loadProfile := func(c *config.Config) {
+		endpointEntry.SetText(c.APIEndpoint)
+		logFileEntry.SetText(c.LogFile)
+		ignoreCertErrorCheck.SetChecked(c.IgnoreCertError)
+		caCertEntry.SetText(c.CACertFile)
+		clientCertEntry.SetText(c.ClientCertFile)
+		clientKeyEntry.SetText(c.ClientKeyFile)
+		minTLSVersionSelect.SetSelected(c.MinTLSVersion)
+		if minTLSVersionSelect.Selected == "" {
+			minTLSVersionSelect.SetSelected("1.2")
+		}
+	}
+	loadProfile(Config)
+
+	// The profile selector picks the profile whose settings are edited
+	profileSelect := widget.NewSelect(Config.ProfileNames(), nil)
+	profileSelect.SetSelected(Config.CurrentProfile())
+	profileSelect.OnChanged = func(name string) {
+		c, err := Config.WithProfile(name)
+		if err != nil {
+			showError(err, w)
+			return
+		}
+		loadProfile(c)
+	}
+	newProfileButton := widget.NewButtonWithIcon("New", theme.ContentAddIcon(), func() {
+		showNewProfileDialog(w, func(name string) {
+			profileSelect.Options = Config.ProfileNames()
+			profileSelect.SetSelected(name)
+			onSaved()
+		})
+	})
 
 	// Create form
 	form := &widget.Form{
 		Items: []*widget.FormItem{
+			{Text: "Profile", Widget: container.NewBorder(nil, nil, nil, newProfileButton, profileSelect)},
 			{Text: "API Endpoint", Widget: endpointEntry},
 			{Text: "Log File", Widget: logFileEntry},
 			{Text: "", Widget: ignoreCertErrorCheck},
+			{Text: "CA Certificate", Widget: caCertEntry},
+			{Text: "Client Certificate", Widget: clientCertEntry},
+			{Text: "Client Key", Widget: clientKeyEntry},
+			{Text: "Minimum TLS Version", Widget: minTLSVersionSelect},
+			{Text: "", Widget: validateFilesCheck},
+			{Text: "Log Level", Widget: logLevelSelect},
+			{Text: "Log Format", Widget: logFormatSelect},
 		},
 		OnSubmit: func() {
+			// Save the settings to the selected profile and make it active
+			if err := Config.UseProfile(profileSelect.Selected); err != nil {
+				showError(err, w)
+				return
+			}
+			Config.ActiveProfile = profileSelect.Selected
+			if Config.ActiveProfile == config.DefaultProfile {
+				Config.ActiveProfile = ""
+			}
+
 			// Update configuration
 			Config.APIEndpoint = endpointEntry.Text
 			Config.LogFile = logFileEntry.Text
 			Config.IgnoreCertError = ignoreCertErrorCheck.Checked
+			Config.CACertFile = caCertEntry.Text
+			Config.ClientCertFile = clientCertEntry.Text
+			Config.ClientKeyFile = clientKeyEntry.Text
+			Config.MinTLSVersion = minTLSVersionSelect.Selected
+			Config.ValidateFiles = validateFilesCheck.Checked
+			Config.LogLevel = logLevelSelect.Selected
+			Config.LogFormat = logFormatSelect.Selected
 
 			// Save configuration
 			if err := config.SaveConfig(Config); err != nil {
-				dialog.ShowError(fmt.Errorf("failed to save configuration: %w", err), w)
+				showError(fmt.Errorf("failed to save configuration: %w", err), w)
 				return
 			}
 
+			// The level applies at once; the format when the GUI is next started
+			if level, err := logger.ParseLevel(Config.LogLevel); err == nil {
+				Logger.SetLevel(level)
+			}
+
+			onSaved()
 			ui.ShowSuccessDialog("Settings", "Settings saved successfully", w)
 		},
 		OnCancel: func() {
@@ -323,19 +552,60 @@ func showSettingsDialog(w fyne.Window) {
 
 // performUpload performs the upload operation
 func performUpload(
+	ctx context.Context,
 	w fyne.Window,
-	statusLabel *widget.Label,
+	status binding.String,
+	progress map[models.FileType]binding.Float,
 	apiKey string,
+	sheet string,
+	force bool,
+	deltaOnly bool,
 	coursesChecked bool, coursesPath string,
 	equivalenciesChecked bool, equivalenciesPath string,
 	studentsChecked bool, studentsPath string,
 	studentCoursesChecked bool, studentCoursesPath string,
 ) {
 	// Update status
-	statusLabel.SetText("Uploading...")
+	status.Set("Uploading...")
+	for _, value := range progress {
+		value.Set(0)
+	}
 
 	// Create uploader
-	u := ui.NewUploader(Config, Logger)
+	u, err := ui.NewUploader(Config, Logger)
+	if err != nil {
+		status.Set("Error: " + logger.Redact(err.Error()))
+		showError(err, w)
+		return
+	}
+	u.SetSheet(sheet)
+	u.SetForce(force)
+	u.SetDelta(deltaOnly)
+	if Journal != nil {
+		u.SetJournal(Journal)
+	}
+	if Snapshots != nil {
+		u.SetSnapshots(Snapshots)
+	}
+
+	// Report progress per file and overall through the bindings, which may be
+	// set from this goroutine, at most once per interval except at the end of
+	// each file so the window is not redrawn for every write
+	var last time.Time
+	u.SetProgressFunc(func(p models.Progress) {
+		fileDone := p.FileSent == p.FileTotal
+		if !fileDone && time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+
+		if value, ok := progress[p.File.Type]; ok && p.FileTotal > 0 {
+			value.Set(float64(p.FileSent) / float64(p.FileTotal))
+		}
+		if p.Total > 0 {
+			status.Set(fmt.Sprintf("Uploading... %d%%", p.Sent*100/p.Total))
+		}
+	})
 
 	// Prepare file paths
 	var coursesFile, equivalenciesFile, studentsFile, studentCoursesFile string
@@ -353,19 +623,98 @@ func performUpload(
 	}
 
 	// Upload files
-	response, err := u.UploadFiles(apiKey, coursesFile, equivalenciesFile, studentsFile, studentCoursesFile)
+	response, err := u.UploadFilesWithContext(ctx, apiKey, coursesFile, equivalenciesFile, studentsFile, studentCoursesFile)
+	if errors.Is(err, api.ErrCanceled) && errors.Is(err, context.Canceled) {
+		status.Set("Upload cancelled")
+		return
+	}
+	if errors.Is(err, uploader.ErrUnchanged) {
+		status.Set("Nothing to upload")
+		dialog.ShowInformation("Nothing to Upload", "All files are unchanged since the last successful upload.\nCheck \"Upload unchanged files\" to upload them anyway.", w)
+		return
+	}
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		status.Set("Validation failed")
+		showError(fmt.Errorf("%w\n%s", err, validationDetails(validationErr.Report)), w)
+		return
+	}
 	if err != nil {
-		statusLabel.SetText("Error: " + err.Error())
-		dialog.ShowError(err, w)
+		status.Set("Error: " + logger.Redact(err.Error()))
+		showError(err, w)
 		return
 	}
 
 	// Update status
 	if response.Success {
-		statusLabel.SetText("Upload successful!")
-		ui.ShowSuccessDialog("Success", "Files uploaded successfully", w)
+		status.Set("Upload successful!")
+		message := "Files uploaded successfully"
+		if response.ReferenceID != "" {
+			message += fmt.Sprintf(" (reference %s)", response.ReferenceID)
+		}
+		if response.RecordsAccepted != 0 || response.RecordsRejected != 0 {
+			message += fmt.Sprintf(": %d records accepted, %d rejected", response.RecordsAccepted, response.RecordsRejected)
+		}
+		ui.ShowSuccessDialog("Success", message, w)
 	} else {
-		statusLabel.SetText(fmt.Sprintf("Upload failed with status code %d", response.Code))
-		dialog.ShowError(fmt.Errorf("upload failed: %s", response.Message), w)
+		status.Set(fmt.Sprintf("Upload failed with status code %d", response.Code))
+		details := response.Message
+		if len(response.Errors) > 0 {
+			details += "\n" + strings.Join(response.Errors, "\n")
+		}
+		showError(fmt.Errorf("upload failed: %s", details), w)
+	}
+}
+
+// maxValidationDetails is the number of validation errors listed in the error dialog
+const maxValidationDetails = 10
+
+// validationDetails lists the first validation errors of a report, one per line
+func validationDetails(report *validation.Report) string {
+	var lines []string
+	for _, file := range report.Files {
+		for _, issue := range file.Issues {
+			if issue.Severity == validation.SeverityError && len(lines) < maxValidationDetails {
+				lines = append(lines, filepath.Base(file.Path)+": "+issue.String())
+			}
+		}
+	}
+	if more := report.Errors() - len(lines); more > 0 {
+		lines = append(lines, fmt.Sprintf("... and %d more", more))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// logOptions converts the log settings to logger options, using the default
+// of each setting that is invalid and returning why
+func logOptions(c *config.Config) (logger.Options, []error) {
+	var errs []error
+	parse := func(value string, fallback int) int {
+		level, err := logger.ParseLevel(value)
+		if err != nil {
+			errs = append(errs, err)
+			return fallback
+		}
+		return level
+	}
+	format, err := logger.ParseFormat(c.LogFormat)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	options := logger.Options{
+		Level:   parse(c.LogLevel, logger.LevelInfo),
+		Format:  format,
+		Console: &logger.Console{Level: parse(c.LogConsoleLevel, logger.LevelWarning)},
+		Rotation: logger.Rotation{
+			MaxSize:    int64(c.LogMaxSize) << 20,
+			MaxAge:     c.LogMaxAge,
+			MaxBackups: c.LogMaxBackups,
+			Compress:   c.LogCompress,
+		},
+	}
+	if level := parse(c.LogSyslogLevel, logger.LevelOff); level != logger.LevelOff {
+		options.Syslog = &logger.Syslog{Level: level, Address: c.LogSyslogAddress, Tag: "trtc-go"}
 	}
+	return options, errs
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+)
+
+// createProfileBar creates the main window's profile selector and endpoint
+// label. Choosing a profile makes it active and calls onSwitched. The returned
+// function reloads the bar after the profiles change.
+func createProfileBar(w fyne.Window, onSwitched func()) (fyne.CanvasObject, func()) {
+	endpointLabel := widget.NewLabel(Config.APIEndpoint)
+
+	var profileSelect *widget.Select
+	profileSelect = widget.NewSelect(Config.ProfileNames(), func(name string) {
+		if name == Config.CurrentProfile() {
+			return
+		}
+		if err := useProfile(name); err != nil {
+			showError(err, w)
+			profileSelect.SetSelected(Config.CurrentProfile())
+			return
+		}
+		Logger.Info("Switched to profile %s", name)
+		endpointLabel.SetText(Config.APIEndpoint)
+		onSwitched()
+	})
+	profileSelect.SetSelected(Config.CurrentProfile())
+
+	refresh := func() {
+		profileSelect.Options = Config.ProfileNames()
+		profileSelect.SetSelected(Config.CurrentProfile())
+		profileSelect.Refresh()
+		endpointLabel.SetText(Config.APIEndpoint)
+		onSwitched()
+	}
+
+	bar := container.NewHBox(
+		widget.NewLabel("Profile:"),
+		profileSelect,
+		widget.NewLabel("API Endpoint:"),
+		endpointLabel,
+	)
+	return bar, refresh
+}
+
+// useProfile switches to a profile and makes it active
+func useProfile(name string) error {
+	if err := Config.UseProfile(name); err != nil {
+		return err
+	}
+	Config.ActiveProfile = name
+	if name == config.DefaultProfile {
+		Config.ActiveProfile = ""
+	}
+	if err := config.SaveConfig(Config); err != nil {
+		return fmt.Errorf("failed to save configuration: %w", err)
+	}
+	return nil
+}
+
+// showNewProfileDialog asks for the name of a new profile and adds it with
+// no settings of its own; onAdded receives the name
+func showNewProfileDialog(w fyne.Window, onAdded func(name string)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("e.g. test or southwest")
+	nameEntry.Validator = config.CheckProfileName
+
+	dialog.ShowForm("New Profile", "Add", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := Config.AddProfile(nameEntry.Text, config.Profile{}); err != nil {
+			showError(err, w)
+			return
+		}
+		if err := config.SaveConfig(Config); err != nil {
+			showError(fmt.Errorf("failed to save configuration: %w", err), w)
+			return
+		}
+		Logger.Info("Added profile %s", nameEntry.Text)
+		onAdded(nameEntry.Text)
+	}, w)
+}
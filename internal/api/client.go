@@ -1,22 +1,31 @@
 package api
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/chatt-state/trtc-go/internal/models"
 	"github.com/chatt-state/trtc-go/pkg/logger"
 )
 
+// DefaultTimeout is the deadline applied to an upload when the request does not specify one
+const DefaultTimeout = 10 * time.Minute
+
+// ErrCanceled is returned when an upload is aborted because its context was cancelled or its deadline expired
+var ErrCanceled = errors.New("upload cancelled")
+
+// ErrNetwork is matched by the errors of uploads that failed because the server
+// could not be reached or the connection broke before a response was read
+var ErrNetwork = errors.New("network failure")
+
 // APIClient is an interface for the API client
 type APIClient interface {
 	UploadFiles(request models.UploadRequest) (*models.UploadResponse, error)
+	UploadFilesWithContext(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, error)
 }
 
 // Client represents an API client for the TRTC API
@@ -25,101 +34,196 @@ type Client struct {
 	httpClient      *http.Client
 	logger          *logger.Logger
 	ignoreCertError bool
+	retryPolicy     RetryPolicy
+	responseParser  ResponseParser
 }
 
 // NewClient creates a new API client
 func NewClient(endpoint string, ignoreCertError bool, logger *logger.Logger) *Client {
-	// Create a standard HTTP client with timeout
+	// Skipping verification needs no files, so building the TLS configuration cannot fail
+	client, _ := NewClientWithTLS(endpoint, TLSOptions{InsecureSkipVerify: ignoreCertError}, logger)
+	return client
+}
+
+// NewClientWithTLS creates a new API client with custom TLS settings
+func NewClientWithTLS(endpoint string, tlsOptions TLSOptions, logger *logger.Logger) (*Client, error) {
+	tlsConfig, err := tlsOptions.tlsConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	// Deadlines are applied per request through the context, see UploadFilesWithContext
 	httpClient := &http.Client{
-		Timeout: 10 * time.Minute, // 10 minutes timeout
+		Transport: transport,
+	}
+
+	if tlsOptions.InsecureSkipVerify {
+		logger.Warning("TLS certificate verification is disabled for %s", endpoint)
 	}
 
 	return &Client{
 		endpoint:        endpoint,
 		httpClient:      httpClient,
 		logger:          logger,
-		ignoreCertError: ignoreCertError,
-	}
+		ignoreCertError: tlsOptions.InsecureSkipVerify,
+		retryPolicy:     DefaultRetryPolicy(),
+		responseParser:  DefaultResponseParser{},
+	}, nil
+}
+
+// SetRetryPolicy sets the policy used to retry failed upload attempts
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+// SetResponseParser sets the parser used to interpret server responses
+func (c *Client) SetResponseParser(parser ResponseParser) {
+	c.responseParser = parser
 }
 
 // UploadFiles uploads files to the TRTC API
 func (c *Client) UploadFiles(request models.UploadRequest) (*models.UploadResponse, error) {
-	c.logger.Info("Uploading files to %s", c.endpoint)
-
-	// Create multipart form
-	var b bytes.Buffer
-	w := multipart.NewWriter(&b)
+	return c.UploadFilesWithContext(context.Background(), request)
+}
 
-	// Add API key
-	if err := w.WriteField("apikey", request.APIKey); err != nil {
-		return nil, fmt.Errorf("failed to write API key: %w", err)
+// UploadFilesWithContext uploads files to the TRTC API, aborting when ctx is cancelled.
+// The request's Timeout is applied as a deadline on top of ctx; DefaultTimeout is used when it is zero.
+func (c *Client) UploadFilesWithContext(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, error) {
+	timeout := request.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Keep the key out of everything logged from here on, including server messages that echo it
+	logger.RegisterSecret(request.APIKey)
 
-	// Add files
+	start := time.Now()
+	c.logger.Log(ctx, logger.LevelInfo, "Uploading files", logger.KeyEndpoint, c.endpoint, "files", len(request.Files))
 	for _, file := range request.Files {
-		c.logger.Info("Adding file: %s (type: %s)", file.FilePath, file.Type.String())
+		c.logger.Log(ctx, logger.LevelInfo, "Adding file", logger.KeyFileType, file.Type.String(), logger.KeyPath, file.FilePath)
+	}
 
-		// Open file
-		f, err := os.Open(file.FilePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open file %s: %w", file.FilePath, err)
+	attempts := c.retryPolicy.attempts()
+	for attempt := 1; ; attempt++ {
+		c.logger.Log(ctx, logger.LevelInfo, "Sending request", logger.KeyAttempt, attempt, "attempts", attempts, logger.KeyEndpoint, c.endpoint)
+		response, header, err := c.send(ctx, request)
+
+		// Decide whether this attempt is worth repeating
+		var retryErr error
+		switch {
+		case err != nil && c.retryPolicy.retryableError(err):
+			retryErr = err
+		case err == nil && c.retryPolicy.retryableStatus(response.Code):
+			retryErr = fmt.Errorf("server responded with status %d", response.Code)
 		}
-		defer f.Close()
-
-		// Create form file
-		fw, err := w.CreateFormFile(file.Type.String(), filepath.Base(file.FilePath))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create form file: %w", err)
+		if retryErr == nil || attempt >= attempts {
+			if err != nil {
+				return nil, err
+			}
+			if !response.Success {
+				c.logger.Log(ctx, logger.LevelError, "Upload failed", logger.KeyError, response.Message,
+					logger.KeyStatus, response.Code, logger.KeyAttempt, attempt, logger.KeyDuration, time.Since(start))
+			} else {
+				c.logger.Log(ctx, logger.LevelInfo, "Upload successful",
+					logger.KeyStatus, response.Code, logger.KeyAttempt, attempt, logger.KeyDuration, time.Since(start))
+			}
+			return response, nil
 		}
 
-		// Copy file content to form
-		if _, err = io.Copy(fw, f); err != nil {
-			return nil, fmt.Errorf("failed to copy file content: %w", err)
+		wait := c.retryPolicy.delay(attempt, header)
+		c.logger.Log(ctx, logger.LevelWarning, "Attempt failed, retrying",
+			logger.KeyAttempt, attempt, "attempts", attempts, logger.KeyError, retryErr, "retry_in", wait.Round(time.Millisecond))
+		if err := sleepContext(ctx, wait); err != nil {
+			c.logger.Log(ctx, logger.LevelError, "Upload aborted", logger.KeyError, ctx.Err())
+			return nil, err
 		}
 	}
+}
 
-	// Close multipart writer
-	if err := w.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close multipart writer: %w", err)
+// send performs a single upload attempt, rebuilding the multipart body from disk
+func (c *Client) send(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, http.Header, error) {
+	// Create a streaming multipart body
+	body, err := newMultipartBody(ctx, request)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create request
-	req, err := http.NewRequest("POST", c.endpoint, &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint, body.reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		body.reader.Close()
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.ContentLength = body.contentLength
 
 	// Set headers
-	req.Header.Set("Content-Type", w.FormDataContentType())
-	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Content-Type", body.contentType)
+	req.Header.Set("Accept", acceptHeader)
 
 	// Send request
-	c.logger.Info("Sending request to %s", c.endpoint)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			c.logger.Log(ctx, logger.LevelError, "Upload aborted", logger.KeyError, ctxErr)
+			return nil, nil, canceledError(ctxErr)
+		}
+		if errors.Is(err, ErrCanceled) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to send request: %w", networkError{err})
 	}
 	defer resp.Body.Close()
 
 	// Read response
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, nil, canceledError(ctxErr)
+		}
+		return nil, nil, fmt.Errorf("failed to read response: %w", networkError{err})
 	}
 
-	// Create response
-	response := &models.UploadResponse{
-		Success: resp.StatusCode == http.StatusOK,
-		Message: string(body),
-		Code:    resp.StatusCode,
-	}
+	c.logger.Log(ctx, logger.LevelInfo, "Response received", logger.KeyStatus, resp.StatusCode)
 
-	c.logger.Info("Response: %d %s", resp.StatusCode, resp.Status)
-	if !response.Success {
-		c.logger.Error("Upload failed: %s", response.Message)
-	} else {
-		c.logger.Info("Upload successful")
+	// Parse response, keeping the raw body if the server sent something unexpected
+	response, err := c.responseParser.Parse(resp.StatusCode, resp.Header, respBody)
+	if err != nil {
+		c.logger.Log(ctx, logger.LevelWarning, "Failed to parse response, using raw body", logger.KeyError, err)
+		response = &models.UploadResponse{
+			Success: resp.StatusCode == http.StatusOK,
+			Message: string(respBody),
+			Code:    resp.StatusCode,
+		}
 	}
+	if response.ReferenceID != "" {
+		c.logger.Log(ctx, logger.LevelInfo, "Server reference ID", "reference_id", response.ReferenceID)
+	}
+
+	return response, resp.Header, nil
+}
+
+// networkError marks a transport error as a network failure, so callers can
+// match both ErrNetwork and the underlying error, keeping its message
+type networkError struct {
+	err error
+}
+
+// Error returns the message of the underlying error
+func (e networkError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns ErrNetwork and the underlying error
+func (e networkError) Unwrap() []error {
+	return []error{ErrNetwork, e.err}
+}
 
-	return response, nil
+// canceledError wraps a context error so callers can match both ErrCanceled and the context error
+func canceledError(err error) error {
+	return fmt.Errorf("%w: %w", ErrCanceled, err)
 }
@@ -1,12 +1,16 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chatt-state/trtc-go/internal/models"
 	"github.com/chatt-state/trtc-go/pkg/logger"
@@ -175,6 +179,113 @@ func TestClient_UploadFilesError(t *testing.T) {
 	}
 }
 
+func TestClient_UploadFilesRedactsKey(t *testing.T) {
+	const apiKey = "k3y-that-must-stay-secret"
+
+	// The server echoes the key it rejected
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "invalid API key " + r.FormValue("apikey"),
+			"code":    401,
+		})
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "test.log")
+	log, err := logger.New(logFilePath, logger.LevelDebug)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	testFilePath := filepath.Join(tempDir, "test.csv")
+	if err := os.WriteFile(testFilePath, []byte("test,data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	client := NewClient(server.URL, false, log)
+	response, err := client.UploadFiles(models.UploadRequest{
+		APIKey: apiKey,
+		Files:  []models.UploadFile{{Type: models.FileTypeCourses, FilePath: testFilePath}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	log.Error("Upload failed: %s", response.Message)
+	log.Close()
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if strings.Contains(string(content), apiKey) {
+		t.Errorf("Expected the API key to be redacted from the log:\n%s", content)
+	}
+	if !strings.Contains(string(content), logger.Redacted) {
+		t.Errorf("Expected the echoed key to be replaced with %s:\n%s", logger.Redacted, content)
+	}
+}
+
+func TestClient_UploadFilesWithContextCancelled(t *testing.T) {
+	// Create a test server that blocks until the test finishes
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "api-client-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a logger
+	log, err := logger.New(filepath.Join(tempDir, "test.log"), logger.LevelInfo)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer log.Close()
+
+	// Create a temporary file for testing
+	testFilePath := filepath.Join(tempDir, "test.csv")
+	if err := os.WriteFile(testFilePath, []byte("test,data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	client := NewClient(server.URL, false, log)
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files:  []models.UploadFile{{Type: models.FileTypeCourses, FilePath: testFilePath}},
+	}
+
+	// Cancel the upload shortly after it starts
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = client.UploadFilesWithContext(ctx, request)
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Expected ErrCanceled, got %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected error to wrap context.Canceled, got %v", err)
+	}
+
+	// A per-request deadline should abort the upload as well
+	request.Timeout = 50 * time.Millisecond
+	_, err = client.UploadFilesWithContext(context.Background(), request)
+	if !errors.Is(err, ErrCanceled) {
+		t.Fatalf("Expected ErrCanceled, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to wrap context.DeadlineExceeded, got %v", err)
+	}
+}
+
 func TestMockClient_UploadFiles(t *testing.T) {
 	// Create a mock client
 	mockClient := &MockClient{
@@ -1,15 +1,33 @@
 package api
 
 import (
+	"context"
+
 	"github.com/chatt-state/trtc-go/internal/models"
 )
 
 // MockClient is a mock implementation of the API client for testing
 type MockClient struct {
-	UploadFilesFunc func(request models.UploadRequest) (*models.UploadResponse, error)
+	UploadFilesFunc            func(request models.UploadRequest) (*models.UploadResponse, error)
+	UploadFilesWithContextFunc func(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, error)
 }
 
 // UploadFiles calls the mock implementation
 func (m *MockClient) UploadFiles(request models.UploadRequest) (*models.UploadResponse, error) {
+	if m.UploadFilesFunc == nil {
+		return m.UploadFilesWithContext(context.Background(), request)
+	}
 	return m.UploadFilesFunc(request)
 }
+
+// UploadFilesWithContext calls the context-aware mock implementation,
+// falling back to UploadFilesFunc when it is not set
+func (m *MockClient) UploadFilesWithContext(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, error) {
+	if m.UploadFilesWithContextFunc == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, canceledError(err)
+		}
+		return m.UploadFilesFunc(request)
+	}
+	return m.UploadFilesWithContextFunc(ctx, request)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// multipartBody is a streaming multipart/form-data request body
+type multipartBody struct {
+	reader        io.ReadCloser
+	contentType   string
+	contentLength int64
+}
+
+// newMultipartBody builds a multipart/form-data body for the request that streams each
+// file from disk as it is read, so memory use does not grow with file size.
+// The total length is computed up front from the file sizes so the request can carry
+// a Content-Length header instead of using chunked transfer encoding.
+func newMultipartBody(ctx context.Context, request models.UploadRequest) (*multipartBody, error) {
+	// Size every file before anything is sent
+	sizes := make([]int64, len(request.Files))
+	for i, file := range request.Files {
+		info, err := os.Stat(file.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %w", file.FilePath, err)
+		}
+		sizes[i] = info.Size()
+	}
+
+	// Write the multipart framing to a counter to learn its size; both writers share a boundary
+	counter := &countingWriter{}
+	cw := multipart.NewWriter(counter)
+	if err := writeMultipart(ctx, cw, request, func(i int, w io.Writer) error {
+		counter.n += sizes[i]
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	if err := w.SetBoundary(cw.Boundary()); err != nil {
+		return nil, fmt.Errorf("failed to set multipart boundary: %w", err)
+	}
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	progress := &progressWriter{onProgress: request.OnProgress, progress: models.Progress{Total: total}}
+
+	go func() {
+		pw.CloseWithError(writeMultipart(ctx, w, request, func(i int, fw io.Writer) error {
+			progress.startFile(request.Files[i], sizes[i])
+			return copyFile(ctx, progress.wrap(fw), request.Files[i].FilePath, sizes[i])
+		}))
+	}()
+
+	return &multipartBody{
+		reader:        pr,
+		contentType:   w.FormDataContentType(),
+		contentLength: counter.n,
+	}, nil
+}
+
+// writeMultipart writes the API key and one form file per upload file, calling writeFile for each file's content
+func writeMultipart(ctx context.Context, w *multipart.Writer, request models.UploadRequest, writeFile func(i int, w io.Writer) error) error {
+	// Add API key
+	if err := w.WriteField("apikey", request.APIKey); err != nil {
+		return fmt.Errorf("failed to write API key: %w", err)
+	}
+
+	// Add files
+	for i, file := range request.Files {
+		if err := ctx.Err(); err != nil {
+			return canceledError(err)
+		}
+
+		// Create form file
+		fw, err := w.CreateFormFile(file.Type.String(), filepath.Base(file.FilePath))
+		if err != nil {
+			return fmt.Errorf("failed to create form file: %w", err)
+		}
+
+		if err := writeFile(i, fw); err != nil {
+			return err
+		}
+	}
+
+	// Close multipart writer
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	return nil
+}
+
+// copyFile streams a file into w, failing if its size no longer matches the announced length
+func copyFile(ctx context.Context, w io.Writer, path string, size int64) error {
+	// Open file
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	// Copy file content to form
+	n, err := io.Copy(w, &contextReader{ctx: ctx, r: io.LimitReader(f, size+1)})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return canceledError(ctxErr)
+		}
+		return fmt.Errorf("failed to copy file content: %w", err)
+	}
+	if n != size {
+		return fmt.Errorf("file %s changed size during upload", path)
+	}
+
+	return nil
+}
+
+// progressWriter reports file content written through it to a progress callback
+type progressWriter struct {
+	onProgress models.ProgressFunc
+	progress   models.Progress
+	w          io.Writer
+}
+
+// startFile resets the per-file counters and reports that the file has started
+func (p *progressWriter) startFile(file models.UploadFile, size int64) {
+	p.progress.File = file
+	p.progress.FileSent = 0
+	p.progress.FileTotal = size
+	if p.onProgress != nil {
+		p.onProgress(p.progress)
+	}
+}
+
+// wrap returns a writer that counts into the progress before writing to w
+func (p *progressWriter) wrap(w io.Writer) io.Writer {
+	if p.onProgress == nil {
+		return w
+	}
+	p.w = w
+	return p
+}
+
+// Write writes to the current form file and reports the bytes written
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	if n > 0 {
+		p.progress.FileSent += int64(n)
+		p.progress.Sent += int64(n)
+		p.onProgress(p.progress)
+	}
+	return n, err
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+// Write records the length of p
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// contextReader is an io.Reader that fails once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context is done
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+func TestNewMultipartBody(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "api-multipart-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create temporary files for testing
+	coursesPath := filepath.Join(tempDir, "courses.csv")
+	if err := os.WriteFile(coursesPath, []byte("course,data"), 0644); err != nil {
+		t.Fatalf("Failed to create courses file: %v", err)
+	}
+	studentsPath := filepath.Join(tempDir, "students.csv")
+	if err := os.WriteFile(studentsPath, []byte(strings.Repeat("student,data\n", 1000)), 0644); err != nil {
+		t.Fatalf("Failed to create students file: %v", err)
+	}
+
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files: []models.UploadFile{
+			{Type: models.FileTypeCourses, FilePath: coursesPath},
+			{Type: models.FileTypeStudents, FilePath: studentsPath},
+		},
+	}
+
+	body, err := newMultipartBody(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Failed to create multipart body: %v", err)
+	}
+	defer body.reader.Close()
+
+	data, err := io.ReadAll(body.reader)
+	if err != nil {
+		t.Fatalf("Failed to read multipart body: %v", err)
+	}
+
+	// The announced length must match what was streamed
+	if int64(len(data)) != body.contentLength {
+		t.Errorf("Expected content length %d, got %d bytes", body.contentLength, len(data))
+	}
+
+	// The body must parse back into the original fields and files
+	boundary := strings.TrimPrefix(body.contentType, "multipart/form-data; boundary=")
+	form, err := multipart.NewReader(bytes.NewReader(data), boundary).ReadForm(10 << 20)
+	if err != nil {
+		t.Fatalf("Failed to parse multipart body: %v", err)
+	}
+	defer form.RemoveAll()
+
+	if got := form.Value["apikey"]; len(got) != 1 || got[0] != "test-api-key" {
+		t.Errorf("Expected apikey field to be test-api-key, got %v", got)
+	}
+	for _, name := range []string{"courses", "students"} {
+		if len(form.File[name]) != 1 {
+			t.Errorf("Expected one %s file, got %d", name, len(form.File[name]))
+		}
+	}
+}
+
+func TestNewMultipartBodyProgress(t *testing.T) {
+	// Create temporary files for testing
+	tempDir := t.TempDir()
+	coursesPath := filepath.Join(tempDir, "courses.csv")
+	if err := os.WriteFile(coursesPath, []byte(strings.Repeat("c", 100000)), 0644); err != nil {
+		t.Fatalf("Failed to create courses file: %v", err)
+	}
+	studentsPath := filepath.Join(tempDir, "students.csv")
+	if err := os.WriteFile(studentsPath, []byte(strings.Repeat("s", 50000)), 0644); err != nil {
+		t.Fatalf("Failed to create students file: %v", err)
+	}
+
+	// Record the last update seen for each file
+	last := map[models.FileType]models.Progress{}
+	var final models.Progress
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files: []models.UploadFile{
+			{Type: models.FileTypeCourses, FilePath: coursesPath},
+			{Type: models.FileTypeStudents, FilePath: studentsPath},
+		},
+		OnProgress: func(p models.Progress) {
+			last[p.File.Type] = p
+			final = p
+		},
+	}
+
+	body, err := newMultipartBody(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Failed to create multipart body: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, body.reader); err != nil {
+		t.Fatalf("Failed to read multipart body: %v", err)
+	}
+
+	if p := last[models.FileTypeCourses]; p.FileSent != 100000 || p.FileTotal != 100000 {
+		t.Errorf("Expected courses progress 100000/100000, got %d/%d", p.FileSent, p.FileTotal)
+	}
+	if p := last[models.FileTypeStudents]; p.FileSent != 50000 || p.FileTotal != 50000 {
+		t.Errorf("Expected students progress 50000/50000, got %d/%d", p.FileSent, p.FileTotal)
+	}
+	if final.Sent != 150000 || final.Total != 150000 {
+		t.Errorf("Expected overall progress 150000/150000, got %d/%d", final.Sent, final.Total)
+	}
+}
+
+func TestNewMultipartBodyMissingFile(t *testing.T) {
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files:  []models.UploadFile{{Type: models.FileTypeCourses, FilePath: filepath.Join(os.TempDir(), "does-not-exist.csv")}},
+	}
+
+	if _, err := newMultipartBody(context.Background(), request); err == nil {
+		t.Fatalf("Expected an error for a missing file, got nil")
+	}
+}
+
+// benchmarkFile creates a file of the given size for the multipart benchmarks
+func benchmarkFile(b *testing.B, size int) string {
+	path := filepath.Join(b.TempDir(), "studentcourses.csv")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("0123456789,ABCDEFGHI\n"), size/21), 0644); err != nil {
+		b.Fatalf("Failed to create benchmark file: %v", err)
+	}
+	return path
+}
+
+// BenchmarkMultipartBuffered measures building the body in memory, as the client used to
+func BenchmarkMultipartBuffered(b *testing.B) {
+	path := benchmarkFile(b, 32<<20)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		if err := w.WriteField("apikey", "test-api-key"); err != nil {
+			b.Fatal(err)
+		}
+		fw, err := w.CreateFormFile("studentcourses", filepath.Base(path))
+		if err != nil {
+			b.Fatal(err)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(fw, f); err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+		if err := w.Close(); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, &buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkMultipartStreaming measures the streaming body, whose memory use stays flat
+func BenchmarkMultipartStreaming(b *testing.B) {
+	path := benchmarkFile(b, 32<<20)
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files:  []models.UploadFile{{Type: models.FileTypeStudentCourses, FilePath: path}},
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		body, err := newMultipartBody(context.Background(), request)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, body.reader); err != nil {
+			b.Fatal(err)
+		}
+		body.reader.Close()
+	}
+}
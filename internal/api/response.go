@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// acceptHeader asks the server for JSON while still accepting the HTML pages it used to return
+const acceptHeader = "application/json, text/html;q=0.9, */*;q=0.8"
+
+// ResponseParser turns a raw HTTP response into an UploadResponse.
+// A custom parser can be set with Client.SetResponseParser when the server's format changes.
+type ResponseParser interface {
+	Parse(statusCode int, header http.Header, body []byte) (*models.UploadResponse, error)
+}
+
+// ResponseParserFunc adapts an ordinary function to a ResponseParser
+type ResponseParserFunc func(statusCode int, header http.Header, body []byte) (*models.UploadResponse, error)
+
+// Parse calls f(statusCode, header, body)
+func (f ResponseParserFunc) Parse(statusCode int, header http.Header, body []byte) (*models.UploadResponse, error) {
+	return f(statusCode, header, body)
+}
+
+// DefaultResponseParser parses JSON responses, falls back to extracting the text of HTML pages,
+// and otherwise uses the body verbatim as the message
+type DefaultResponseParser struct{}
+
+// Parse parses the response according to its content type
+func (DefaultResponseParser) Parse(statusCode int, header http.Header, body []byte) (*models.UploadResponse, error) {
+	response := &models.UploadResponse{
+		Success: statusCode == http.StatusOK,
+		Code:    statusCode,
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	trimmed := bytes.TrimSpace(body)
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		(mediaType != "text/html" && len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')):
+		if err := parseJSONResponse(trimmed, response); err != nil {
+			return nil, err
+		}
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml" || bytes.HasPrefix(bytes.ToLower(trimmed), []byte("<!doctype html")):
+		response.Message = extractHTMLText(body)
+	default:
+		response.Message = string(trimmed)
+	}
+
+	return response, nil
+}
+
+// jsonFileResult is the wire format of a per-file outcome
+type jsonFileResult struct {
+	Type            string   `json:"type"`
+	FileName        string   `json:"fileName"`
+	Success         *bool    `json:"success"`
+	RecordsAccepted int      `json:"recordsAccepted"`
+	RecordsRejected int      `json:"recordsRejected"`
+	Accepted        int      `json:"accepted"`
+	Rejected        int      `json:"rejected"`
+	Errors          []string `json:"errors"`
+}
+
+// jsonResponse is the wire format of an upload response; alternative field names seen
+// from the server are accepted side by side
+type jsonResponse struct {
+	Success         *bool            `json:"success"`
+	Message         string           `json:"message"`
+	ReferenceID     string           `json:"referenceId"`
+	BatchID         string           `json:"batchId"`
+	RecordsAccepted int              `json:"recordsAccepted"`
+	RecordsRejected int              `json:"recordsRejected"`
+	Files           []jsonFileResult `json:"files"`
+	Errors          []string         `json:"errors"`
+}
+
+// parseJSONResponse fills response from a JSON body
+func parseJSONResponse(body []byte, response *models.UploadResponse) error {
+	var parsed jsonResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	if parsed.Success != nil {
+		response.Success = response.Success && *parsed.Success
+	}
+	response.Message = parsed.Message
+	response.ReferenceID = parsed.ReferenceID
+	if response.ReferenceID == "" {
+		response.ReferenceID = parsed.BatchID
+	}
+	response.Errors = parsed.Errors
+
+	for _, f := range parsed.Files {
+		result := models.FileResult{
+			Type:            models.FileTypeUnknown,
+			FileName:        f.FileName,
+			Success:         f.Success == nil || *f.Success,
+			RecordsAccepted: f.RecordsAccepted + f.Accepted,
+			RecordsRejected: f.RecordsRejected + f.Rejected,
+			Errors:          f.Errors,
+		}
+		if ft, err := models.ParseFileType(f.Type); err == nil {
+			result.Type = ft
+		}
+		response.Files = append(response.Files, result)
+		response.RecordsAccepted += result.RecordsAccepted
+		response.RecordsRejected += result.RecordsRejected
+	}
+
+	// Prefer the server's totals when it sends them
+	if parsed.RecordsAccepted != 0 || parsed.RecordsRejected != 0 {
+		response.RecordsAccepted = parsed.RecordsAccepted
+		response.RecordsRejected = parsed.RecordsRejected
+	}
+
+	return nil
+}
+
+// extractHTMLText returns the visible text of an HTML page with whitespace collapsed
+func extractHTMLText(body []byte) string {
+	var parts []string
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	skip := 0
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return strings.Join(parts, " ")
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); isHiddenTag(name) {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); isHiddenTag(name) && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				if text := strings.Join(strings.Fields(string(tokenizer.Text())), " "); text != "" {
+					parts = append(parts, text)
+				}
+			}
+		}
+	}
+}
+
+// isHiddenTag reports whether the content of a tag is not visible text
+func isHiddenTag(name []byte) bool {
+	switch string(name) {
+	case "script", "style", "head", "noscript":
+		return true
+	}
+	return false
+}
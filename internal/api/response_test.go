@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+func TestDefaultResponseParser_JSON(t *testing.T) {
+	body := []byte(`{
+		"success": true,
+		"message": "Upload received",
+		"batchId": "B-20250313-001",
+		"files": [
+			{"type": "courses", "fileName": "courses.csv", "success": true, "recordsAccepted": 120, "recordsRejected": 0},
+			{"type": "studentcourses", "fileName": "sc.csv", "success": false, "accepted": 10, "rejected": 2, "errors": ["line 4: unknown student"]},
+			{"type": "transcripts", "fileName": "transcripts.csv"}
+		],
+		"errors": ["equivalencies file missing"]
+	}`)
+	header := http.Header{"Content-Type": []string{"application/json; charset=utf-8"}}
+
+	response, err := DefaultResponseParser{}.Parse(http.StatusOK, header, body)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if !response.Success {
+		t.Errorf("Expected success to be true")
+	}
+	if response.Message != "Upload received" {
+		t.Errorf("Expected message 'Upload received', got %q", response.Message)
+	}
+	if response.ReferenceID != "B-20250313-001" {
+		t.Errorf("Expected reference ID from batchId, got %q", response.ReferenceID)
+	}
+	if len(response.Files) != 3 {
+		t.Fatalf("Expected 3 file results, got %d", len(response.Files))
+	}
+	if response.Files[1].Type != models.FileTypeStudentCourses || response.Files[1].Success {
+		t.Errorf("Unexpected second file result: %+v", response.Files[1])
+	}
+	// A type this version does not know is not mistaken for courses
+	if response.Files[2].Type != models.FileTypeUnknown {
+		t.Errorf("Expected an unknown file type, got %s", response.Files[2].Type.String())
+	}
+	if response.RecordsAccepted != 130 || response.RecordsRejected != 2 {
+		t.Errorf("Expected totals 130/2, got %d/%d", response.RecordsAccepted, response.RecordsRejected)
+	}
+	if len(response.Errors) != 1 {
+		t.Errorf("Expected 1 server error, got %v", response.Errors)
+	}
+}
+
+func TestDefaultResponseParser_JSONFailure(t *testing.T) {
+	// A 200 response that reports failure is not a success
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	response, err := DefaultResponseParser{}.Parse(http.StatusOK, header, []byte(`{"success": false, "message": "Invalid API key"}`))
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Success {
+		t.Errorf("Expected success to be false")
+	}
+
+	// Malformed JSON is an error so the client can fall back to the raw body
+	if _, err := (DefaultResponseParser{}).Parse(http.StatusOK, header, []byte(`{"success":`)); err == nil {
+		t.Errorf("Expected an error for malformed JSON")
+	}
+}
+
+func TestDefaultResponseParser_HTML(t *testing.T) {
+	body := []byte(`<!DOCTYPE html>
+<html><head><title>Upload</title><style>body { color: red; }</style></head>
+<body>
+  <h1>Upload   complete</h1>
+  <script>var x = 1;</script>
+  <p>3 files received.</p>
+</body></html>`)
+	header := http.Header{"Content-Type": []string{"text/html; charset=utf-8"}}
+
+	response, err := DefaultResponseParser{}.Parse(http.StatusOK, header, body)
+	if err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Message != "Upload complete 3 files received." {
+		t.Errorf("Unexpected message extracted from HTML: %q", response.Message)
+	}
+}
+
+func TestClient_CustomResponseParser(t *testing.T) {
+	log, request, _ := setupTLSTest(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != acceptHeader {
+			t.Errorf("Expected Accept header %q, got %q", acceptHeader, accept)
+		}
+		w.Write([]byte("OK|REF-42"))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, false, log)
+	client.SetResponseParser(ResponseParserFunc(func(statusCode int, header http.Header, body []byte) (*models.UploadResponse, error) {
+		return &models.UploadResponse{Success: true, Code: statusCode, ReferenceID: string(body[3:])}, nil
+	}))
+
+	response, err := client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if response.ReferenceID != "REF-42" {
+		t.Errorf("Expected reference ID REF-42, got %q", response.ReferenceID)
+	}
+
+	// A failing parser falls back to the raw body
+	client.SetResponseParser(ResponseParserFunc(func(int, http.Header, []byte) (*models.UploadResponse, error) {
+		return nil, errors.New("unrecognized format")
+	}))
+	response, err = client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if response.Message != "OK|REF-42" {
+		t.Errorf("Expected raw body as message, got %q", response.Message)
+	}
+}
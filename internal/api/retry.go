@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how failed upload attempts are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first; values below 1 mean 1
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry; it doubles on each further retry
+	BaseDelay time.Duration
+	// MaxDelay caps the computed backoff
+	MaxDelay time.Duration
+	// Jitter randomizes each backoff by up to this fraction (0 to 1) in either direction
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes that trigger a retry
+	RetryableStatusCodes []int
+	// RetryNetworkErrors retries transient errors that stopped the request from
+	// being sent, such as a refused connection or a failed DNS lookup
+	RetryNetworkErrors bool
+	// RetryAfterSend also retries transient errors that may come after the
+	// server received the request, such as a connection reset or a timeout
+	// waiting for the response. An upload is not idempotent, so such a retry
+	// can send the same files twice; it requires RetryNetworkErrors.
+	RetryAfterSend bool
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            2 * time.Second,
+		MaxDelay:             30 * time.Second,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors:   true,
+	}
+}
+
+// attempts returns the number of attempts the policy allows
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// retryableStatus reports whether a response with the given status code should be retried
+func (p RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableError reports whether a failed attempt should be retried. Errors
+// from connecting to the server are retried with RetryNetworkErrors; errors
+// once the request may have been sent only with RetryAfterSend as well.
+func (p RetryPolicy) retryableError(err error) bool {
+	if !p.RetryNetworkErrors || errors.Is(err, ErrCanceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	if notSent(err) {
+		return true
+	}
+	if !p.RetryAfterSend {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// notSent reports whether err stopped the request before any of it reached
+// the server: the connection was refused or could not be established
+func notSent(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// delay returns how long to wait before the given retry (1 for the first retry).
+// A Retry-After header in the response takes precedence over the computed
+// backoff, but is capped at MaxDelay too.
+func (p RetryPolicy) delay(retry int, header http.Header) time.Duration {
+	if d, ok := parseRetryAfter(header); ok {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+		return d
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < retry && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rand.Float64() - 1))
+	}
+
+	return d
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return canceledError(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fastRetryPolicy retries quickly so tests do not sleep
+func fastRetryPolicy(attempts int) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = attempts
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 5 * time.Millisecond
+	return policy
+}
+
+func TestClient_RetriesTransientStatus(t *testing.T) {
+	log, request, _ := setupTLSTest(t)
+
+	// Fail twice with 503, then succeed; every attempt must carry the full file
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			t.Errorf("Failed to parse multipart form on attempt %d: %v", atomic.LoadInt32(&calls)+1, err)
+		}
+		if _, _, err := r.FormFile("courses"); err != nil {
+			t.Errorf("Expected courses file on every attempt: %v", err)
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, false, log)
+	client.SetRetryPolicy(fastRetryPolicy(3))
+
+	response, err := client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected code to be 200, got %d", response.Code)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestClient_RetryGivesUp(t *testing.T) {
+	log, request, _ := setupTLSTest(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, false, log)
+	client.SetRetryPolicy(fastRetryPolicy(2))
+
+	// The last response is returned once the attempts are used up
+	response, err := client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Expected the final response, got error %v", err)
+	}
+	if response.Code != http.StatusBadGateway {
+		t.Errorf("Expected code to be 502, got %d", response.Code)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	log, request, _ := setupTLSTest(t)
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, false, log)
+	client.SetRetryPolicy(fastRetryPolicy(3))
+
+	if _, err := client.UploadFiles(request); err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 attempt for a 400 response, got %d", got)
+	}
+}
+
+func TestClient_RetriesNetworkErrors(t *testing.T) {
+	log, request, _ := setupTLSTest(t)
+
+	// Drop the connection on the first attempt, after the request was received
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The server may have processed the upload, so by default it is not sent again
+	client := NewClient(server.URL, false, log)
+	client.SetRetryPolicy(fastRetryPolicy(3))
+	if _, err := client.UploadFiles(request); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Expected a network failure, got %v", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+
+	// Retrying after the request was sent is opt-in
+	atomic.StoreInt32(&calls, 0)
+	policy := fastRetryPolicy(3)
+	policy.RetryAfterSend = true
+	client.SetRetryPolicy(policy)
+	response, err := client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected code to be 200, got %d", response.Code)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestRetryPolicyRetryableError(t *testing.T) {
+	refused := fmt.Errorf("failed to send request: %w", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+	reset := fmt.Errorf("failed to read response: %w", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET})
+
+	testCases := []struct {
+		name      string
+		network   bool
+		afterSend bool
+		err       error
+		expected  bool
+	}{
+		{"refused", true, false, refused, true},
+		{"refused without network retries", false, false, refused, false},
+		{"unexpected EOF", true, false, io.ErrUnexpectedEOF, false},
+		{"reset", true, false, reset, false},
+		{"reset after send", true, true, reset, true},
+		{"unexpected EOF after send", true, true, io.ErrUnexpectedEOF, true},
+		{"canceled", true, true, canceledError(context.Canceled), false},
+	}
+	for _, tc := range testCases {
+		policy := RetryPolicy{RetryNetworkErrors: tc.network, RetryAfterSend: tc.afterSend}
+		if got := policy.retryableError(tc.err); got != tc.expected {
+			t.Errorf("%s: retryableError = %t, expected %t", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	testCases := []struct {
+		retry    int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second}, // capped
+		{10, 5 * time.Second},
+	}
+	for _, tc := range testCases {
+		if got := policy.delay(tc.retry, http.Header{}); got != tc.expected {
+			t.Errorf("delay(%d) = %s, expected %s", tc.retry, got, tc.expected)
+		}
+	}
+
+	// Retry-After in seconds takes precedence, within MaxDelay
+	header := http.Header{}
+	header.Set("Retry-After", "3")
+	if got := policy.delay(1, header); got != 3*time.Second {
+		t.Errorf("Expected Retry-After delay of 3s, got %s", got)
+	}
+	header.Set("Retry-After", "3600")
+	if got := policy.delay(1, header); got != 5*time.Second {
+		t.Errorf("Expected Retry-After delay capped at 5s, got %s", got)
+	}
+
+	// Jitter stays within the configured fraction
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(1, http.Header{}); got < 500*time.Millisecond || got > 1500*time.Millisecond {
+			t.Fatalf("Jittered delay %s outside expected range", got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSOptions describes how the client verifies the server and authenticates itself
+type TLSOptions struct {
+	// InsecureSkipVerify disables server certificate verification
+	InsecureSkipVerify bool
+	// CAFile is a PEM bundle of additional trusted root certificates, e.g. for an inspection proxy
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version ("1.0", "1.1", "1.2" or "1.3"); empty means 1.2
+	MinVersion string
+}
+
+// ParseTLSVersion converts a version string such as "1.2" to its crypto/tls constant
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %s", version)
+	}
+}
+
+// tlsConfig builds a crypto/tls configuration from the options
+func (o TLSOptions) tlsConfig() (*tls.Config, error) {
+	minVersion, err := ParseTLSVersion(o.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:         minVersion,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	// Trust the system roots plus the custom CA bundle
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA certificate file %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	// Load the client certificate for mutual TLS
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, fmt.Errorf("both a client certificate and a client key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+// setupTLSTest creates a logger and a file to upload in a temporary directory
+func setupTLSTest(t *testing.T) (*logger.Logger, models.UploadRequest, string) {
+	tempDir := t.TempDir()
+
+	log, err := logger.New(filepath.Join(tempDir, "test.log"), logger.LevelInfo)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	testFilePath := filepath.Join(tempDir, "test.csv")
+	if err := os.WriteFile(testFilePath, []byte("test,data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	request := models.UploadRequest{
+		APIKey: "test-api-key",
+		Files:  []models.UploadFile{{Type: models.FileTypeCourses, FilePath: testFilePath}},
+	}
+
+	return log, request, tempDir
+}
+
+// writeServerCA writes the test server's certificate as a PEM CA bundle
+func writeServerCA(t *testing.T, server *httptest.Server, dir string) string {
+	path := filepath.Join(dir, "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	return path
+}
+
+// writeClientCert generates a self-signed client certificate and returns its cert and key paths
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "trtc-go test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, "client.pem")
+	keyPath := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("Failed to write client certificate: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("Failed to write client key: %v", err)
+	}
+
+	return cert, certPath, keyPath
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestClient_TLSVerification(t *testing.T) {
+	log, request, tempDir := setupTLSTest(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+
+	// The test server's certificate is not trusted by default
+	client := NewClient(server.URL, false, log)
+	if _, err := client.UploadFiles(request); err == nil {
+		t.Errorf("Expected an error for an untrusted certificate, got nil")
+	}
+
+	// Ignoring certificate errors must actually skip verification
+	client = NewClient(server.URL, true, log)
+	if _, err := client.UploadFiles(request); err != nil {
+		t.Errorf("Expected upload to succeed with ignoreCertError, got %v", err)
+	}
+
+	// Trusting the server's certificate through a CA bundle
+	client, err := NewClientWithTLS(server.URL, TLSOptions{CAFile: writeServerCA(t, server, tempDir)}, log)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.UploadFiles(request); err != nil {
+		t.Errorf("Expected upload to succeed with custom CA, got %v", err)
+	}
+}
+
+func TestClient_TLSClientCertificate(t *testing.T) {
+	log, request, tempDir := setupTLSTest(t)
+
+	cert, certPath, keyPath := writeClientCert(t, tempDir)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := writeServerCA(t, server, tempDir)
+
+	// Without a client certificate the handshake is rejected
+	client, err := NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile}, log)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.UploadFiles(request); err == nil {
+		t.Errorf("Expected an error without a client certificate, got nil")
+	}
+
+	// With the client certificate the upload goes through
+	client, err = NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile, CertFile: certPath, KeyFile: keyPath}, log)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	response, err := client.UploadFiles(request)
+	if err != nil {
+		t.Fatalf("Expected upload to succeed with a client certificate, got %v", err)
+	}
+	if response.Code != http.StatusOK {
+		t.Errorf("Expected code to be 200, got %d", response.Code)
+	}
+}
+
+func TestClient_TLSMinVersion(t *testing.T) {
+	log, request, tempDir := setupTLSTest(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := writeServerCA(t, server, tempDir)
+
+	// A TLS 1.2 server satisfies the default minimum
+	client, err := NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile}, log)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.UploadFiles(request); err != nil {
+		t.Errorf("Expected upload to succeed with TLS 1.2, got %v", err)
+	}
+
+	// Requiring TLS 1.3 must refuse the connection
+	client, err = NewClientWithTLS(server.URL, TLSOptions{CAFile: caFile, MinVersion: "1.3"}, log)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	if _, err := client.UploadFiles(request); err == nil {
+		t.Errorf("Expected an error when the server only supports TLS 1.2, got nil")
+	}
+}
+
+func TestNewClientWithTLSInvalidOptions(t *testing.T) {
+	log, _, tempDir := setupTLSTest(t)
+
+	testCases := []struct {
+		name    string
+		options TLSOptions
+	}{
+		{"unknown TLS version", TLSOptions{MinVersion: "2.0"}},
+		{"missing CA file", TLSOptions{CAFile: filepath.Join(tempDir, "missing.pem")}},
+		{"cert without key", TLSOptions{CertFile: filepath.Join(tempDir, "client.pem")}},
+	}
+
+	for _, tc := range testCases {
+		if _, err := NewClientWithTLS("https://example.com", tc.options, log); err == nil {
+			t.Errorf("Expected an error for %s, got nil", tc.name)
+		}
+	}
+}
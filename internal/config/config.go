@@ -5,16 +5,89 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds the application configuration
 type Config struct {
+	// APIKey is a key kept in plaintext, as older versions did; "config set-key"
+	// stores keys encrypted in the secret store instead
 	APIKey          string `mapstructure:"api_key"`
 	APIEndpoint     string `mapstructure:"api_endpoint"`
 	LogFile         string `mapstructure:"log_file"`
 	IgnoreCertError bool   `mapstructure:"ignore_cert_error"`
+	CACertFile      string `mapstructure:"ca_cert_file"`
+	ClientCertFile  string `mapstructure:"client_cert_file"`
+	ClientKeyFile   string `mapstructure:"client_key_file"`
+	MinTLSVersion   string `mapstructure:"min_tls_version"`
+
+	RetryMaxAttempts   int           `mapstructure:"retry_max_attempts"`
+	RetryBaseDelay     time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay      time.Duration `mapstructure:"retry_max_delay"`
+	RetryJitter        float64       `mapstructure:"retry_jitter"`
+	RetryStatusCodes   []int         `mapstructure:"retry_status_codes"`
+	RetryNetworkErrors bool          `mapstructure:"retry_network_errors"`
+	// RetryAfterSend also retries network errors after the request may have
+	// reached the server, which can upload the same files twice
+	RetryAfterSend bool `mapstructure:"retry_after_send"`
+
+	ValidateFiles bool `mapstructure:"validate_files"`
+	// ValidateStrict makes problems found by the unconfirmed file layouts
+	// block an upload rather than only being logged as warnings
+	ValidateStrict bool `mapstructure:"validate_strict"`
+
+	// LogLevel is the lowest level written to the log file: debug, info, warn,
+	// error or off. LogFormat is text or json for every sink.
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"`
+
+	// LogConsoleLevel is the lowest level written to standard error, and
+	// LogSyslogLevel the lowest sent to the syslog socket at LogSyslogAddress
+	// (empty for the system's default socket)
+	LogConsoleLevel  string `mapstructure:"log_console_level"`
+	LogSyslogLevel   string `mapstructure:"log_syslog_level"`
+	LogSyslogAddress string `mapstructure:"log_syslog_address"`
+
+	// The log file is archived once it exceeds LogMaxSize megabytes or is
+	// older than LogMaxAge, keeping LogMaxBackups archives (0 keeps all),
+	// gzipped if LogCompress is set
+	LogMaxSize    int           `mapstructure:"log_max_size"`
+	LogMaxAge     time.Duration `mapstructure:"log_max_age"`
+	LogMaxBackups int           `mapstructure:"log_max_backups"`
+	LogCompress   bool          `mapstructure:"log_compress"`
+
+	Schedules []Schedule `mapstructure:"schedules"`
+
+	// ActiveProfile is the profile used when none is given on the command line; empty means the default profile
+	ActiveProfile string             `mapstructure:"active_profile"`
+	Profiles      map[string]Profile `mapstructure:"profiles"`
+
+	// profile is the name of the profile in use, and base and baseSources hold
+	// the top-level settings it replaced; see UseProfile
+	profile     string
+	base        Profile
+	baseSources map[string]string
+
+	// sources records where each setting came from, and env the settings
+	// replaced by environment variables; see ApplyEnv
+	sources map[string]string
+	env     map[string]envOverride
+}
+
+// Schedule is a recurring upload run by "trtc-go schedule"
+type Schedule struct {
+	// Name identifies the schedule in logs and must be unique
+	Name string `mapstructure:"name" yaml:"name"`
+	// Cron is a cron expression such as "30 2 * * *" in the local time zone
+	Cron string `mapstructure:"cron" yaml:"cron"`
+	// Manifest is the upload manifest to run; it is read again on every run
+	Manifest string `mapstructure:"manifest" yaml:"manifest,omitempty"`
+	// Files maps a file type to the path to upload, when no manifest is given
+	Files map[string]string `mapstructure:"files" yaml:"files,omitempty"`
+	// CatchUp runs the upload once at startup if a run was missed while the scheduler was stopped
+	CatchUp bool `mapstructure:"catch_up" yaml:"catch_up,omitempty"`
 }
 
 // DefaultConfig returns a configuration with default values
@@ -24,6 +97,29 @@ func DefaultConfig() *Config {
 		APIEndpoint:     "https://rts.tnreversetransfer.org/api/Upload",
 		LogFile:         "log.txt",
 		IgnoreCertError: false,
+		MinTLSVersion:   "1.2",
+
+		RetryMaxAttempts:   3,
+		RetryBaseDelay:     2 * time.Second,
+		RetryMaxDelay:      30 * time.Second,
+		RetryJitter:        0.2,
+		RetryStatusCodes:   []int{502, 503, 504},
+		RetryNetworkErrors: true,
+		RetryAfterSend:     false,
+
+		ValidateFiles:  true,
+		ValidateStrict: false,
+
+		LogLevel:  "info",
+		LogFormat: "text",
+
+		LogConsoleLevel: "warn",
+		LogSyslogLevel:  "off",
+
+		LogMaxSize:    10,
+		LogMaxAge:     7 * 24 * time.Hour,
+		LogMaxBackups: 5,
+		LogCompress:   true,
 	}
 }
 
@@ -58,18 +154,60 @@ var getConfigDir getConfigDirFunc = func() (string, error) {
 	return configDir, nil
 }
 
-// LoadConfig loads the configuration from the config file
-func LoadConfig() (*Config, error) {
+// configFile is the config file set by SetFile, if any
+var configFile string
+
+// Dir returns the directory holding the config file and other application data
+func Dir() (string, error) {
+	return getConfigDir()
+}
+
+// SetFile makes LoadConfig and SaveConfig use another config file; an empty
+// path restores config.yaml in Dir. Other application data stays in Dir.
+func SetFile(path string) {
+	configFile = path
+}
+
+// File returns the path of the config file
+func File() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
 	configDir, err := getConfigDir()
 	if err != nil {
-		return nil, err
+		return "", err
+	}
+	return filepath.Join(configDir, "config.yaml"), nil
+}
+
+// LogPath returns the location of the log file. A relative LogFile, such as
+// the default log.txt, is in Dir rather than wherever the program is run.
+func (c *Config) LogPath() (string, error) {
+	logFile := c.LogFile
+	if logFile == "" {
+		logFile = DefaultConfig().LogFile
 	}
+	if filepath.IsAbs(logFile) {
+		return logFile, nil
+	}
+	dir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, logFile), nil
+}
 
-	configPath := filepath.Join(configDir, "config.yaml")
+// LoadConfig loads the configuration from the config file. Profiles and
+// environment variables are applied separately by UseProfile and ApplyEnv.
+func LoadConfig() (*Config, error) {
+	configPath, err := File()
+	if err != nil {
+		return nil, err
+	}
 
 	// If config file doesn't exist, create it with default values
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 			return nil, fmt.Errorf("failed to create config directory: %w", err)
 		}
 
@@ -87,34 +225,117 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Settings missing from older config files fall back to their defaults
+	setDefaults()
+
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Unmarshal drops profiles with no settings of their own
+	for name := range viper.GetStringMap("profiles") {
+		if _, ok := config.Profiles[name]; !ok {
+			if config.Profiles == nil {
+				config.Profiles = map[string]Profile{}
+			}
+			config.Profiles[name] = Profile{}
+		}
+	}
+
+	for _, key := range Keys() {
+		if viper.InConfig(key) {
+			config.SetSource(key, SourceFile)
+		}
+	}
+
 	return &config, nil
 }
 
-// SaveConfig saves the configuration to the config file
+// setDefaults registers the default configuration values with viper
+func setDefaults() {
+	defaults := DefaultConfig()
+
+	viper.SetDefault("api_key", defaults.APIKey)
+	viper.SetDefault("api_endpoint", defaults.APIEndpoint)
+	viper.SetDefault("log_file", defaults.LogFile)
+	viper.SetDefault("ignore_cert_error", defaults.IgnoreCertError)
+	viper.SetDefault("min_tls_version", defaults.MinTLSVersion)
+	viper.SetDefault("retry_max_attempts", defaults.RetryMaxAttempts)
+	viper.SetDefault("retry_base_delay", defaults.RetryBaseDelay.String())
+	viper.SetDefault("retry_max_delay", defaults.RetryMaxDelay.String())
+	viper.SetDefault("retry_jitter", defaults.RetryJitter)
+	viper.SetDefault("retry_status_codes", defaults.RetryStatusCodes)
+	viper.SetDefault("retry_network_errors", defaults.RetryNetworkErrors)
+	viper.SetDefault("retry_after_send", defaults.RetryAfterSend)
+	viper.SetDefault("validate_files", defaults.ValidateFiles)
+	viper.SetDefault("validate_strict", defaults.ValidateStrict)
+	viper.SetDefault("log_level", defaults.LogLevel)
+	viper.SetDefault("log_format", defaults.LogFormat)
+	viper.SetDefault("log_console_level", defaults.LogConsoleLevel)
+	viper.SetDefault("log_syslog_level", defaults.LogSyslogLevel)
+	viper.SetDefault("log_max_size", defaults.LogMaxSize)
+	viper.SetDefault("log_max_age", defaults.LogMaxAge.String())
+	viper.SetDefault("log_max_backups", defaults.LogMaxBackups)
+	viper.SetDefault("log_compress", defaults.LogCompress)
+}
+
+// SaveConfig saves the configuration to the config file. Values read from
+// environment variables are not saved; the ones they replaced are.
 func SaveConfig(config *Config) error {
-	configDir, err := getConfigDir()
+	configPath, err := File()
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	configPath := filepath.Join(configDir, "config.yaml")
-
 	viper.SetConfigFile(configPath)
 	viper.SetConfigType("yaml")
 
-	viper.Set("api_key", config.APIKey)
-	viper.Set("api_endpoint", config.APIEndpoint)
-	viper.Set("log_file", config.LogFile)
-	viper.Set("ignore_cert_error", config.IgnoreCertError)
+	saved := *config
+	saved.removeEnv()
+	config = &saved
+
+	// While a profile is in use, the top-level settings are the ones it
+	// replaced and its own settings are saved to the profile
+	top := config.settings()
+	if config.profile != "" {
+		top = config.base
+		config.Profiles[config.profile] = config.overrides()
+	}
+
+	viper.Set("api_key", top.APIKey)
+	viper.Set("api_endpoint", top.APIEndpoint)
+	viper.Set("log_file", top.LogFile)
+	viper.Set("ignore_cert_error", *top.IgnoreCertError)
+	viper.Set("ca_cert_file", top.CACertFile)
+	viper.Set("client_cert_file", top.ClientCertFile)
+	viper.Set("client_key_file", top.ClientKeyFile)
+	viper.Set("min_tls_version", top.MinTLSVersion)
+	viper.Set("retry_max_attempts", config.RetryMaxAttempts)
+	viper.Set("retry_base_delay", config.RetryBaseDelay.String())
+	viper.Set("retry_max_delay", config.RetryMaxDelay.String())
+	viper.Set("retry_jitter", config.RetryJitter)
+	viper.Set("retry_status_codes", config.RetryStatusCodes)
+	viper.Set("retry_network_errors", config.RetryNetworkErrors)
+	viper.Set("retry_after_send", config.RetryAfterSend)
+	viper.Set("validate_files", config.ValidateFiles)
+	viper.Set("validate_strict", config.ValidateStrict)
+	viper.Set("log_level", top.LogLevel)
+	viper.Set("log_format", top.LogFormat)
+	viper.Set("log_console_level", config.LogConsoleLevel)
+	viper.Set("log_syslog_level", config.LogSyslogLevel)
+	viper.Set("log_syslog_address", config.LogSyslogAddress)
+	viper.Set("log_max_size", config.LogMaxSize)
+	viper.Set("log_max_age", config.LogMaxAge.String())
+	viper.Set("log_max_backups", config.LogMaxBackups)
+	viper.Set("log_compress", config.LogCompress)
+	viper.Set("schedules", config.Schedules)
+	viper.Set("active_profile", config.ActiveProfile)
+	viper.Set("profiles", config.Profiles)
 
 	if err := viper.WriteConfig(); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
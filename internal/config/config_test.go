@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -23,6 +25,25 @@ func TestDefaultConfig(t *testing.T) {
 	if config.IgnoreCertError != false {
 		t.Errorf("Default ignore cert error should be false, got %t", config.IgnoreCertError)
 	}
+	if config.MinTLSVersion != "1.2" {
+		t.Errorf("Default minimum TLS version should be 1.2, got %s", config.MinTLSVersion)
+	}
+	if config.RetryMaxAttempts != 3 {
+		t.Errorf("Default retry attempts should be 3, got %d", config.RetryMaxAttempts)
+	}
+	if len(config.RetryStatusCodes) != 3 {
+		t.Errorf("Default retry status codes should be 502, 503 and 504, got %v", config.RetryStatusCodes)
+	}
+	if config.LogMaxSize != 10 || config.LogMaxAge != 7*24*time.Hour || config.LogMaxBackups != 5 || !config.LogCompress {
+		t.Errorf("Default log rotation should be 10 MB or 7 days with 5 compressed archives, got %d MB, %s, %d, %t",
+			config.LogMaxSize, config.LogMaxAge, config.LogMaxBackups, config.LogCompress)
+	}
+	if config.LogConsoleLevel != "warn" || config.LogSyslogLevel != "off" {
+		t.Errorf("Default console and syslog levels should be warn and off, got %s and %s", config.LogConsoleLevel, config.LogSyslogLevel)
+	}
+	if config.LogLevel != "info" || config.LogFormat != "text" {
+		t.Errorf("Default logging should be info in text, got %s in %s", config.LogLevel, config.LogFormat)
+	}
 }
 
 func TestSaveAndLoadConfig(t *testing.T) {
@@ -49,6 +70,25 @@ func TestSaveAndLoadConfig(t *testing.T) {
 		APIEndpoint:     "https://test-endpoint.com",
 		LogFile:         "test-log.txt",
 		IgnoreCertError: true,
+		CACertFile:      "/path/to/ca.pem",
+		ClientCertFile:  "/path/to/client.pem",
+		ClientKeyFile:   "/path/to/client.key",
+		MinTLSVersion:   "1.3",
+
+		RetryMaxAttempts:   5,
+		RetryBaseDelay:     500 * time.Millisecond,
+		RetryMaxDelay:      time.Minute,
+		RetryJitter:        0.1,
+		RetryStatusCodes:   []int{503},
+		RetryNetworkErrors: false,
+
+		ValidateFiles:  false,
+		ValidateStrict: true,
+
+		Schedules: []Schedule{
+			{Name: "nightly", Cron: "30 2 * * *", Manifest: "/srv/trtc/nightly.yaml", CatchUp: true},
+			{Name: "courses", Cron: "@weekly", Files: map[string]string{"courses": "/srv/trtc/courses.csv"}},
+		},
 	}
 
 	// Save the configuration
@@ -82,4 +122,357 @@ func TestSaveAndLoadConfig(t *testing.T) {
 	if loadedConfig.IgnoreCertError != testConfig.IgnoreCertError {
 		t.Errorf("Loaded ignore cert error does not match: expected %t, got %t", testConfig.IgnoreCertError, loadedConfig.IgnoreCertError)
 	}
+	if loadedConfig.CACertFile != testConfig.CACertFile {
+		t.Errorf("Loaded CA cert file does not match: expected %s, got %s", testConfig.CACertFile, loadedConfig.CACertFile)
+	}
+	if loadedConfig.ClientCertFile != testConfig.ClientCertFile {
+		t.Errorf("Loaded client cert file does not match: expected %s, got %s", testConfig.ClientCertFile, loadedConfig.ClientCertFile)
+	}
+	if loadedConfig.ClientKeyFile != testConfig.ClientKeyFile {
+		t.Errorf("Loaded client key file does not match: expected %s, got %s", testConfig.ClientKeyFile, loadedConfig.ClientKeyFile)
+	}
+	if loadedConfig.MinTLSVersion != testConfig.MinTLSVersion {
+		t.Errorf("Loaded minimum TLS version does not match: expected %s, got %s", testConfig.MinTLSVersion, loadedConfig.MinTLSVersion)
+	}
+	if loadedConfig.RetryMaxAttempts != testConfig.RetryMaxAttempts {
+		t.Errorf("Loaded retry attempts do not match: expected %d, got %d", testConfig.RetryMaxAttempts, loadedConfig.RetryMaxAttempts)
+	}
+	if loadedConfig.RetryBaseDelay != testConfig.RetryBaseDelay {
+		t.Errorf("Loaded retry delay does not match: expected %s, got %s", testConfig.RetryBaseDelay, loadedConfig.RetryBaseDelay)
+	}
+	if loadedConfig.RetryMaxDelay != testConfig.RetryMaxDelay {
+		t.Errorf("Loaded max retry delay does not match: expected %s, got %s", testConfig.RetryMaxDelay, loadedConfig.RetryMaxDelay)
+	}
+	if len(loadedConfig.RetryStatusCodes) != 1 || loadedConfig.RetryStatusCodes[0] != 503 {
+		t.Errorf("Loaded retry status codes do not match: expected %v, got %v", testConfig.RetryStatusCodes, loadedConfig.RetryStatusCodes)
+	}
+	if loadedConfig.RetryNetworkErrors != testConfig.RetryNetworkErrors {
+		t.Errorf("Loaded retry network errors does not match: expected %t, got %t", testConfig.RetryNetworkErrors, loadedConfig.RetryNetworkErrors)
+	}
+	if loadedConfig.ValidateFiles != testConfig.ValidateFiles {
+		t.Errorf("Loaded validate files does not match: expected %t, got %t", testConfig.ValidateFiles, loadedConfig.ValidateFiles)
+	}
+	if loadedConfig.ValidateStrict != testConfig.ValidateStrict {
+		t.Errorf("Loaded strict validation does not match: expected %t, got %t", testConfig.ValidateStrict, loadedConfig.ValidateStrict)
+	}
+	if len(loadedConfig.Schedules) != 2 ||
+		loadedConfig.Schedules[0].Name != "nightly" || loadedConfig.Schedules[0].Cron != "30 2 * * *" ||
+		loadedConfig.Schedules[0].Manifest != "/srv/trtc/nightly.yaml" || !loadedConfig.Schedules[0].CatchUp ||
+		loadedConfig.Schedules[1].Files["courses"] != "/srv/trtc/courses.csv" {
+		t.Errorf("Loaded schedules do not match: expected %+v, got %+v", testConfig.Schedules, loadedConfig.Schedules)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	origGetConfigDir := getConfigDir
+	getConfigDir = func() (string, error) {
+		return tempDir, nil
+	}
+	defer func() {
+		getConfigDir = origGetConfigDir
+	}()
+
+	// Save a configuration with a profile for a test endpoint
+	testConfig := DefaultConfig()
+	testConfig.APIKey = "production-key"
+	ignoreCertError := true
+	if err := testConfig.AddProfile("test", Profile{APIEndpoint: "https://test.example.com/api/Upload", IgnoreCertError: &ignoreCertError}); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+	for _, name := range []string{"test", "default", "Test", "a.b"} {
+		if err := testConfig.AddProfile(name, Profile{}); err == nil {
+			t.Errorf("Expected adding profile %q to fail", name)
+		}
+	}
+	testConfig.ActiveProfile = "test"
+	if err := SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loaded.ActiveProfile != "test" || len(loaded.ProfileNames()) != 2 {
+		t.Fatalf("Expected the test profile to be active, got %q with %v", loaded.ActiveProfile, loaded.ProfileNames())
+	}
+
+	// Using a profile overrides its settings and keeps the others
+	if err := loaded.UseProfile("test"); err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+	if loaded.CurrentProfile() != "test" || loaded.APIEndpoint != "https://test.example.com/api/Upload" || !loaded.IgnoreCertError || loaded.APIKey != "production-key" {
+		t.Errorf("Unexpected settings for the test profile: %+v", loaded)
+	}
+	if err := loaded.UseProfile("missing"); err == nil {
+		t.Error("Expected an error using a missing profile")
+	}
+
+	// Changes made while a profile is in use are saved to the profile
+	loaded.APIKey = "test-key"
+	if err := SaveConfig(loaded); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	if err := loaded.UseProfile(DefaultProfile); err != nil {
+		t.Fatalf("Failed to use the default profile: %v", err)
+	}
+	if loaded.APIKey != "production-key" || loaded.APIEndpoint != DefaultConfig().APIEndpoint || loaded.IgnoreCertError {
+		t.Errorf("Expected the top-level settings to be restored, got %+v", loaded)
+	}
+	if p := loaded.Profiles["test"]; p.APIKey != "test-key" || p.APIEndpoint != "https://test.example.com/api/Upload" {
+		t.Errorf("Expected the API key to be saved to the profile, got %+v", p)
+	}
+
+	// Removing the active profile makes the default profile active
+	if err := loaded.RemoveProfile("test"); err != nil {
+		t.Fatalf("Failed to remove profile: %v", err)
+	}
+	if err := SaveConfig(loaded); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	reloaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if reloaded.ActiveProfile != "" || len(reloaded.Profiles) != 0 || reloaded.APIKey != "production-key" {
+		t.Errorf("Expected the profile to be removed, got %+v", reloaded)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if strings.Contains(string(data), "test.example.com") {
+		t.Errorf("Expected the profile to be removed from the config file:\n%s", data)
+	}
+}
+
+func TestProfileLogSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	origGetConfigDir := getConfigDir
+	getConfigDir = func() (string, error) {
+		return tempDir, nil
+	}
+	defer func() {
+		getConfigDir = origGetConfigDir
+	}()
+
+	testConfig := DefaultConfig()
+	if err := testConfig.AddProfile("test", Profile{LogLevel: "debug", LogFormat: "json"}); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+
+	// A profile's log settings override the top-level ones
+	profiled, err := testConfig.WithProfile("test")
+	if err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+	if profiled.LogLevel != "debug" || profiled.LogFormat != "json" {
+		t.Errorf("Expected the test profile's log settings, got %s and %s", profiled.LogLevel, profiled.LogFormat)
+	}
+	if source := profiled.Source("log_level"); source != SourceProfile+" test" {
+		t.Errorf("Expected log_level to come from the test profile, got %q", source)
+	}
+	if testConfig.LogLevel != "info" || testConfig.LogFormat != "text" {
+		t.Errorf("Expected the default profile to keep its log settings, got %s and %s", testConfig.LogLevel, testConfig.LogFormat)
+	}
+
+	// Changes made while the profile is in use are saved to the profile
+	profiled.LogLevel = "warn"
+	if err := SaveConfig(profiled); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loaded.LogLevel != "info" || loaded.LogFormat != "text" {
+		t.Errorf("Expected the top-level log settings to be unchanged, got %s and %s", loaded.LogLevel, loaded.LogFormat)
+	}
+	if p := loaded.Profiles["test"]; p.LogLevel != "warn" || p.LogFormat != "json" {
+		t.Errorf("Expected the log settings to be saved to the profile, got %+v", p)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+	origGetConfigDir := getConfigDir
+	getConfigDir = func() (string, error) {
+		return tempDir, nil
+	}
+	defer func() {
+		getConfigDir = origGetConfigDir
+	}()
+
+	testConfig := DefaultConfig()
+	testConfig.RetryMaxAttempts = 4
+	if err := testConfig.AddProfile("test", Profile{APIEndpoint: "https://test.example.com/api/Upload"}); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+	if err := SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+
+	t.Setenv("TRTC_API_KEY", "env-key")
+	t.Setenv("TRTC_API_ENDPOINT", "https://env.example.com/api/Upload")
+	t.Setenv("TRTC_RETRY_BASE_DELAY", "5s")
+	t.Setenv("TRTC_RETRY_STATUS_CODES", "500, 503")
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := loaded.UseProfile("test"); err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+	if err := loaded.ApplyEnv(); err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+
+	// Environment variables take precedence over the profile and the file
+	if loaded.APIKey != "env-key" || loaded.APIEndpoint != "https://env.example.com/api/Upload" ||
+		loaded.RetryBaseDelay != 5*time.Second || len(loaded.RetryStatusCodes) != 2 || loaded.RetryStatusCodes[0] != 500 {
+		t.Errorf("Expected the environment to override the settings, got %+v", loaded)
+	}
+	for key, want := range map[string]string{
+		"api_endpoint":       "env TRTC_API_ENDPOINT",
+		"retry_max_attempts": "file",
+		"min_tls_version":    "file",
+	} {
+		if got := loaded.Source(key); got != want {
+			t.Errorf("Expected the source of %s to be %q, got %q", key, want, got)
+		}
+	}
+
+	// Switching profiles keeps the environment in charge
+	if err := loaded.UseProfile(DefaultProfile); err != nil {
+		t.Fatalf("Failed to use the default profile: %v", err)
+	}
+	if loaded.APIEndpoint != "https://env.example.com/api/Upload" {
+		t.Errorf("Expected the environment to override the default profile, got %s", loaded.APIEndpoint)
+	}
+	if err := loaded.UseProfile("test"); err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+
+	// Values from the environment are not saved, but changes to other settings are
+	loaded.RetryMaxAttempts = 6
+	if err := SaveConfig(loaded); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if strings.Contains(string(data), "env") || !strings.Contains(string(data), "retry_max_attempts: 6") {
+		t.Errorf("Expected only the changed setting to be saved:\n%s", data)
+	}
+	if loaded.APIKey != "env-key" {
+		t.Errorf("Expected saving to keep the environment values in use, got %s", loaded.APIKey)
+	}
+
+	// Invalid values are reported with the variable name
+	t.Setenv("TRTC_RETRY_MAX_ATTEMPTS", "many")
+	if err := loaded.ApplyEnv(); err == nil || !strings.Contains(err.Error(), "TRTC_RETRY_MAX_ATTEMPTS") {
+		t.Errorf("Expected an error naming TRTC_RETRY_MAX_ATTEMPTS, got %v", err)
+	}
+	if loaded.RetryMaxAttempts != 6 {
+		t.Errorf("Expected a failed ApplyEnv to leave the settings alone, got %d attempts", loaded.RetryMaxAttempts)
+	}
+}
+
+func TestSetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other", "trtc.yaml")
+	SetFile(path)
+	defer SetFile("")
+
+	if got, err := File(); err != nil || got != path {
+		t.Fatalf("Expected the config file to be %s, got %s (%v)", path, got, err)
+	}
+	testConfig := DefaultConfig()
+	testConfig.APIEndpoint = "https://other.example.com/api/Upload"
+	if err := SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loaded.APIEndpoint != testConfig.APIEndpoint || loaded.Source("api_endpoint") != SourceFile {
+		t.Errorf("Expected the endpoint from %s, got %s from %s", path, loaded.APIEndpoint, loaded.Source("api_endpoint"))
+	}
+}
+
+func TestEmptyProfile(t *testing.T) {
+	tempDir := t.TempDir()
+	origGetConfigDir := getConfigDir
+	getConfigDir = func() (string, error) {
+		return tempDir, nil
+	}
+	defer func() {
+		getConfigDir = origGetConfigDir
+	}()
+
+	// A profile with no settings of its own, such as one whose API key is stored elsewhere, survives loading
+	testConfig := DefaultConfig()
+	if err := testConfig.AddProfile("empty", Profile{}); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+	if err := SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if _, ok := loaded.Profiles["empty"]; !ok {
+		t.Errorf("Expected the empty profile to be loaded, got %v", loaded.ProfileNames())
+	}
+}
+
+func TestLogPath(t *testing.T) {
+	tempDir := t.TempDir()
+	origGetConfigDir := getConfigDir
+	getConfigDir = func() (string, error) {
+		return tempDir, nil
+	}
+	defer func() {
+		getConfigDir = origGetConfigDir
+	}()
+
+	// Relative and empty paths are in the config directory, whatever the working directory
+	c := DefaultConfig()
+	if path, err := c.LogPath(); err != nil || path != filepath.Join(tempDir, "log.txt") {
+		t.Errorf("Expected the default log file in %s, got %s (%v)", tempDir, path, err)
+	}
+	c.LogFile = filepath.Join("logs", "trtc.log")
+	if path, err := c.LogPath(); err != nil || path != filepath.Join(tempDir, "logs", "trtc.log") {
+		t.Errorf("Expected a relative log file in %s, got %s (%v)", tempDir, path, err)
+	}
+	c.LogFile = ""
+	if path, err := c.LogPath(); err != nil || path != filepath.Join(tempDir, "log.txt") {
+		t.Errorf("Expected an empty log file to mean the default, got %s (%v)", path, err)
+	}
+	abs := filepath.Join(t.TempDir(), "trtc.log")
+	c.LogFile = abs
+	if path, err := c.LogPath(); err != nil || path != abs {
+		t.Errorf("Expected %s, got %s (%v)", abs, path, err)
+	}
+
+	// Rotation settings survive a save and load
+	c.LogMaxSize = 25
+	c.LogMaxAge = 48 * time.Hour
+	c.LogMaxBackups = 0
+	c.LogCompress = false
+	if err := SaveConfig(c); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if loaded.LogMaxSize != 25 || loaded.LogMaxAge != 48*time.Hour || loaded.LogMaxBackups != 0 || loaded.LogCompress {
+		t.Errorf("Unexpected rotation settings: %d MB, %s, %d backups, compress %t",
+			loaded.LogMaxSize, loaded.LogMaxAge, loaded.LogMaxBackups, loaded.LogCompress)
+	}
 }
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix starts the names of the environment variables that override settings,
+// such as TRTC_API_ENDPOINT for api_endpoint
+const EnvPrefix = "TRTC"
+
+// Sources of setting values, from lowest to highest precedence
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// durationType is the type of duration settings, which are parsed rather than read as integers
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// envOverride records a setting replaced by an environment variable
+type envOverride struct {
+	// previous and source are the value and source it replaced
+	previous reflect.Value
+	source   string
+	// value is the value read from the environment
+	value reflect.Value
+}
+
+// Keys returns the names of the settings that can be overridden, in the order
+// they appear in Config
+func Keys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("mapstructure")
+		switch key {
+		case "", "schedules", "active_profile", "profiles":
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// EnvVar returns the environment variable that overrides a setting
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(key)
+}
+
+// Source describes where the value of a setting came from: "default", "file",
+// "profile NAME", "env TRTC_NAME" or "flag --name"
+func (c *Config) Source(key string) string {
+	if source, ok := c.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// SetSource records where a setting's value came from, such as a command line flag
+func (c *Config) SetSource(key, source string) {
+	if c.sources == nil {
+		c.sources = map[string]string{}
+	}
+	c.sources[key] = source
+}
+
+// Value returns a setting's value formatted for display
+func (c *Config) Value(key string) string {
+	v := c.field(key)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// ApplyEnv overrides settings with the TRTC_* environment variables that are
+// set, such as TRTC_API_KEY or TRTC_RETRY_MAX_ATTEMPTS. Lists are comma
+// separated and durations are written like "30s". Call it after UseProfile,
+// since environment variables take precedence over profiles; overridden values
+// are never written to the config file.
+func (c *Config) ApplyEnv() error {
+	c.removeEnv()
+
+	env := viper.New()
+	env.SetEnvPrefix(EnvPrefix)
+	overrides := map[string]envOverride{}
+	for _, key := range Keys() {
+		if err := env.BindEnv(key); err != nil {
+			return fmt.Errorf("failed to bind %s: %w", EnvVar(key), err)
+		}
+		if !env.IsSet(key) {
+			continue
+		}
+
+		field := c.field(key)
+		previous := reflect.New(field.Type()).Elem()
+		previous.Set(field)
+		if err := setField(field, env.GetString(key)); err != nil {
+			c.restoreEnv(overrides)
+			return fmt.Errorf("invalid %s: %w", EnvVar(key), err)
+		}
+		overrides[key] = envOverride{previous: previous, source: c.Source(key), value: field}
+		c.SetSource(key, SourceEnv+" "+EnvVar(key))
+	}
+
+	if len(overrides) > 0 {
+		// Keep copies of the values read, since field refers to the live setting
+		for key, o := range overrides {
+			value := reflect.New(o.value.Type()).Elem()
+			value.Set(o.value)
+			o.value = value
+			overrides[key] = o
+		}
+		c.env = overrides
+	}
+	return nil
+}
+
+// removeEnv undoes ApplyEnv, keeping settings changed since, such as by "config set"
+func (c *Config) removeEnv() {
+	if len(c.env) == 0 {
+		return
+	}
+	c.restoreEnv(c.env)
+	c.env = nil
+}
+
+// restoreEnv puts back the values environment variables replaced
+func (c *Config) restoreEnv(overrides map[string]envOverride) {
+	sources := make(map[string]string, len(c.sources))
+	for key, source := range c.sources {
+		sources[key] = source
+	}
+	for key, o := range overrides {
+		field := c.field(key)
+		if reflect.DeepEqual(field.Interface(), o.value.Interface()) {
+			field.Set(o.previous)
+			sources[key] = o.source
+		}
+	}
+	c.sources = sources
+}
+
+// field returns the struct field holding a setting, or an invalid value for unknown keys
+func (c *Config) field(key string) reflect.Value {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("mapstructure") == key {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// setField parses a string into a setting of any of the types Config uses
+func setField(field reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s", raw)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		field.SetFloat(f)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Int:
+		var values []int
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("%q is not a comma-separated list of whole numbers", raw)
+			}
+			values = append(values, n)
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile names the settings at the top level of config.yaml
+const DefaultProfile = "default"
+
+// profileName matches valid profile names. Viper lowercases keys and splits
+// them on dots, so names are limited to lowercase letters, digits, dashes and
+// underscores.
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Profile holds the settings of one institution or environment. Empty values
+// fall back to the settings at the top level of config.yaml.
+type Profile struct {
+	APIKey          string `mapstructure:"api_key" yaml:"api_key,omitempty"`
+	APIEndpoint     string `mapstructure:"api_endpoint" yaml:"api_endpoint,omitempty"`
+	LogFile         string `mapstructure:"log_file" yaml:"log_file,omitempty"`
+	LogLevel        string `mapstructure:"log_level" yaml:"log_level,omitempty"`
+	LogFormat       string `mapstructure:"log_format" yaml:"log_format,omitempty"`
+	IgnoreCertError *bool  `mapstructure:"ignore_cert_error" yaml:"ignore_cert_error,omitempty"`
+	CACertFile      string `mapstructure:"ca_cert_file" yaml:"ca_cert_file,omitempty"`
+	ClientCertFile  string `mapstructure:"client_cert_file" yaml:"client_cert_file,omitempty"`
+	ClientKeyFile   string `mapstructure:"client_key_file" yaml:"client_key_file,omitempty"`
+	MinTLSVersion   string `mapstructure:"min_tls_version" yaml:"min_tls_version,omitempty"`
+}
+
+// CheckProfileName reports whether name can be used for a new profile
+func CheckProfileName(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("%q is reserved for the top-level settings", DefaultProfile)
+	}
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lowercase letters, digits, dashes and underscores", name)
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the profiles, starting with the default profile
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// CurrentProfile returns the name of the profile whose settings are in use
+func (c *Config) CurrentProfile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
+}
+
+// UseProfile replaces the profile settings with those of the named profile
+// for this run. The default profile, or an empty name, restores the top-level
+// settings. SaveConfig writes changes to these settings back to the profile.
+// Environment variables applied by ApplyEnv keep taking precedence.
+func (c *Config) UseProfile(name string) error {
+	if len(c.env) == 0 {
+		return c.useProfile(name)
+	}
+	c.removeEnv()
+	err := c.useProfile(name)
+	if envErr := c.ApplyEnv(); err == nil {
+		err = envErr
+	}
+	return err
+}
+
+// useProfile switches profiles with no environment variables applied
+func (c *Config) useProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		if c.profile != "" {
+			c.restore(c.base)
+			c.profile = ""
+		}
+		return nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	if c.profile != "" {
+		c.restore(c.base)
+	}
+	c.base = c.settings()
+	c.baseSources = c.sources
+	c.apply(name, p)
+	c.profile = name
+	return nil
+}
+
+// WithProfile returns a copy of the configuration using the named profile
+func (c *Config) WithProfile(name string) (*Config, error) {
+	copied := *c
+	copied.sources = copySources(c.sources)
+	if err := copied.UseProfile(name); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// AddProfile adds a profile
+func (c *Config) AddProfile(name string, p Profile) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[name] = p
+	return nil
+}
+
+// RemoveProfile removes a profile. If it is in use or active, the default
+// profile takes its place.
+func (c *Config) RemoveProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %s profile cannot be removed", DefaultProfile)
+	}
+	if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	if c.profile == name {
+		if err := c.UseProfile(DefaultProfile); err != nil {
+			return err
+		}
+	}
+	if c.ActiveProfile == name {
+		c.ActiveProfile = ""
+	}
+	delete(c.Profiles, name)
+	return nil
+}
+
+// settings returns the profile settings currently in use, with every value set
+func (c *Config) settings() Profile {
+	ignoreCertError := c.IgnoreCertError
+	return Profile{
+		APIKey:          c.APIKey,
+		APIEndpoint:     c.APIEndpoint,
+		LogFile:         c.LogFile,
+		LogLevel:        c.LogLevel,
+		LogFormat:       c.LogFormat,
+		IgnoreCertError: &ignoreCertError,
+		CACertFile:      c.CACertFile,
+		ClientCertFile:  c.ClientCertFile,
+		ClientKeyFile:   c.ClientKeyFile,
+		MinTLSVersion:   c.MinTLSVersion,
+	}
+}
+
+// restore sets every profile setting from a complete set of values
+func (c *Config) restore(p Profile) {
+	c.APIKey = p.APIKey
+	c.APIEndpoint = p.APIEndpoint
+	c.LogFile = p.LogFile
+	c.LogLevel = p.LogLevel
+	c.LogFormat = p.LogFormat
+	c.IgnoreCertError = p.IgnoreCertError != nil && *p.IgnoreCertError
+	c.CACertFile = p.CACertFile
+	c.ClientCertFile = p.ClientCertFile
+	c.ClientKeyFile = p.ClientKeyFile
+	c.MinTLSVersion = p.MinTLSVersion
+	c.sources = copySources(c.baseSources)
+}
+
+// apply sets the profile settings that p, the profile called name, overrides
+func (c *Config) apply(name string, p Profile) {
+	c.sources = copySources(c.sources)
+	source := SourceProfile + " " + name
+	for _, s := range []struct {
+		key string
+		dst *string
+		src string
+	}{
+		{"api_key", &c.APIKey, p.APIKey},
+		{"api_endpoint", &c.APIEndpoint, p.APIEndpoint},
+		{"log_file", &c.LogFile, p.LogFile},
+		{"log_level", &c.LogLevel, p.LogLevel},
+		{"log_format", &c.LogFormat, p.LogFormat},
+		{"ca_cert_file", &c.CACertFile, p.CACertFile},
+		{"client_cert_file", &c.ClientCertFile, p.ClientCertFile},
+		{"client_key_file", &c.ClientKeyFile, p.ClientKeyFile},
+		{"min_tls_version", &c.MinTLSVersion, p.MinTLSVersion},
+	} {
+		if s.src != "" {
+			*s.dst = s.src
+			c.SetSource(s.key, source)
+		}
+	}
+	if p.IgnoreCertError != nil {
+		c.IgnoreCertError = *p.IgnoreCertError
+		c.SetSource("ignore_cert_error", source)
+	}
+}
+
+// copySources returns a copy of a sources map, so changing it leaves other
+// copies of the configuration alone
+func copySources(sources map[string]string) map[string]string {
+	copied := make(map[string]string, len(sources))
+	for key, source := range sources {
+		copied[key] = source
+	}
+	return copied
+}
+
+// overrides returns the settings in use that differ from the top-level settings,
+// which is what the profile in use must hold to reproduce them
+func (c *Config) overrides() Profile {
+	current := c.settings()
+	var p Profile
+	for _, s := range []struct {
+		dst       *string
+		base, cur string
+	}{
+		{&p.APIKey, c.base.APIKey, current.APIKey},
+		{&p.APIEndpoint, c.base.APIEndpoint, current.APIEndpoint},
+		{&p.LogFile, c.base.LogFile, current.LogFile},
+		{&p.LogLevel, c.base.LogLevel, current.LogLevel},
+		{&p.LogFormat, c.base.LogFormat, current.LogFormat},
+		{&p.CACertFile, c.base.CACertFile, current.CACertFile},
+		{&p.ClientCertFile, c.base.ClientCertFile, current.ClientCertFile},
+		{&p.ClientKeyFile, c.base.ClientKeyFile, current.ClientKeyFile},
+		{&p.MinTLSVersion, c.base.MinTLSVersion, current.MinTLSVersion},
+	} {
+		if s.cur != s.base {
+			*s.dst = s.cur
+		}
+	}
+	if *current.IgnoreCertError != (c.base.IgnoreCertError != nil && *c.base.IgnoreCertError) {
+		p.IgnoreCertError = current.IgnoreCertError
+	}
+	return p
+}
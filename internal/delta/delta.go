@@ -0,0 +1,316 @@
+package delta
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/validation"
+)
+
+// Row is a data row of a file, with its values in the file's column order
+type Row struct {
+	Line int
+	// Key is the row's key values joined by spaces, or "" if any is empty
+	Key    string
+	Values []string
+	// id identifies the row by its normalized key values
+	id string
+}
+
+// Field is a column whose value differs between two versions of a row
+type Field struct {
+	Column string
+	Old    string
+	New    string
+}
+
+// Change is a row whose key is in both files but whose values differ
+type Change struct {
+	Old    Row
+	New    Row
+	Fields []Field
+}
+
+// Result holds the differences between two files of the same type. Rows are
+// matched by the natural key of the file type's schema, or by their whole
+// content when either file lacks a key column.
+type Result struct {
+	Type models.FileType
+	// Header is the header of the new file; delta files are written in its column order
+	Header []string
+	// Key names the columns rows were matched by
+	Key []string
+	// Added rows are only in the new file, and Changed rows differ between the files; both are in new file order
+	Added   []Row
+	Changed []Change
+	// Removed rows are only in the old file, in old file order
+	Removed   []Row
+	Unchanged int
+}
+
+// Rows returns the number of rows a delta file contains: the added and changed rows
+func (r *Result) Rows() int {
+	return len(r.Added) + len(r.Changed)
+}
+
+// HasChanges reports whether any row was added, changed or removed
+func (r *Result) HasChanges() bool {
+	return r.Rows() > 0 || len(r.Removed) > 0
+}
+
+// WriteDelta writes the header and the added and changed rows of the new file as CSV
+func (r *Result) WriteDelta(w io.Writer) error {
+	rows := make([]Row, 0, r.Rows())
+	rows = append(rows, r.Added...)
+	for _, change := range r.Changed {
+		rows = append(rows, change.New)
+	}
+	// Keep the rows in the order they appear in the new file
+	sort.Slice(rows, func(a, b int) bool {
+		return rows[a].Line < rows[b].Line
+	})
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(r.Header); err != nil {
+		return fmt.Errorf("failed to write delta: %w", err)
+	}
+	for _, row := range rows {
+		if err := writer.Write(row.Values); err != nil {
+			return fmt.Errorf("failed to write delta: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write delta: %w", err)
+	}
+	return nil
+}
+
+// WriteDeltaFile writes the delta to a CSV file, removing the file on failure
+func (r *Result) WriteDeltaFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := r.WriteDelta(f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Diff compares two CSV files of the same type. Rows are matched by the key
+// columns of the type's schema, ignoring case and surrounding space, and
+// columns are matched by name so the files may order them differently.
+// Rows without a complete key cannot be matched and are reported as added.
+//
+// The schema keys are not confirmed by a published TRTC specification, so
+// they only decide whether a row is reported as added or changed: a row is
+// left out of the delta only when the old file has a row with identical
+// values. When either file lacks a key column, rows are matched by all of
+// their values instead.
+func Diff(ft models.FileType, oldPath, newPath string) (*Result, error) {
+	schema, ok := validation.SchemaFor(ft)
+	if !ok {
+		return nil, fmt.Errorf("no schema for file type %s", ft.String())
+	}
+
+	old, err := readFile(oldPath, schema)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readFile(newPath, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	keyColumns, whole := schema.Key, false
+	if !old.has(keyColumns) || !current.has(keyColumns) {
+		keyColumns, whole = current.names, true
+	}
+	old.identify(keyColumns, whole)
+	current.identify(keyColumns, whole)
+
+	result := &Result{Type: ft, Header: current.header, Key: keyColumns}
+
+	// A key may appear more than once, so keep every old row with it
+	oldRows := map[string][]Row{}
+	for _, row := range old.rows {
+		if row.id != "" {
+			oldRows[row.id] = append(oldRows[row.id], row)
+		}
+	}
+
+	matched := map[string]bool{}
+	for _, row := range current.rows {
+		candidates := oldRows[row.id]
+		if row.id == "" || len(candidates) == 0 {
+			result.Added = append(result.Added, row)
+			continue
+		}
+		matched[row.id] = true
+		if same(old, candidates, current, row) {
+			result.Unchanged++
+			continue
+		}
+		// Report the change against the first old row with the key
+		fields := compare(old, candidates[0], current, row)
+		result.Changed = append(result.Changed, Change{Old: candidates[0], New: row, Fields: fields})
+	}
+
+	for _, row := range old.rows {
+		if row.id == "" || matched[row.id] {
+			continue
+		}
+		// Report each removed key once
+		matched[row.id] = true
+		result.Removed = append(result.Removed, row)
+	}
+
+	return result, nil
+}
+
+// file is a parsed CSV file
+type file struct {
+	header []string
+	// columns maps a column name to its position; schema columns use their schema name
+	columns map[string]int
+	// names lists the column names in file order
+	names []string
+	rows  []Row
+}
+
+// readFile reads a CSV file, naming its columns after the schema's
+func readFile(path string, schema validation.Schema) (*file, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	parsed := &file{header: header, columns: map[string]int{}}
+	for i, name := range header {
+		if column, ok := schema.Column(name); ok {
+			name = column.Name
+		} else {
+			name = strings.TrimSpace(name)
+		}
+		// Keep the first of any duplicate columns, as validation does
+		if _, seen := parsed.columns[name]; !seen {
+			parsed.columns[name] = i
+			parsed.names = append(parsed.names, name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		parsed.rows = append(parsed.rows, Row{Line: line, Values: record})
+	}
+
+	return parsed, nil
+}
+
+// has reports whether the file has all of the named columns
+func (f *file) has(names []string) bool {
+	for _, name := range names {
+		if _, ok := f.columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// identify sets the key of each row from the named columns. A key is "" if
+// any of its values is empty, unless whole is set and the columns are the
+// entire row.
+func (f *file) identify(names []string, whole bool) {
+	for i := range f.rows {
+		row := &f.rows[i]
+		row.Key, row.id = "", ""
+		parts := make([]string, len(names))
+		complete := true
+		for j, name := range names {
+			parts[j] = f.value(*row, name)
+			if parts[j] == "" && !whole {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			row.Key, row.id = strings.Join(parts, " "), strings.ToUpper(strings.Join(parts, "\x1f"))
+		}
+	}
+}
+
+// value returns the trimmed value of a named column, or "" if the file lacks it
+func (f *file) value(row Row, name string) string {
+	position, ok := f.columns[name]
+	if !ok || position >= len(row.Values) {
+		return ""
+	}
+	return strings.TrimSpace(row.Values[position])
+}
+
+// same reports whether any of the old rows has the same values as the new row
+func same(old *file, candidates []Row, current *file, row Row) bool {
+	for _, candidate := range candidates {
+		if len(compare(old, candidate, current, row)) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// compare returns the columns whose values differ between two versions of a row
+func compare(old *file, oldRow Row, current *file, newRow Row) []Field {
+	var fields []Field
+	for _, name := range current.names {
+		if o, n := old.value(oldRow, name), current.value(newRow, name); o != n {
+			fields = append(fields, Field{Column: name, Old: o, New: n})
+		}
+	}
+	// Columns dropped from the new file count as cleared values
+	for _, name := range old.names {
+		if _, ok := current.columns[name]; ok {
+			continue
+		}
+		if o := old.value(oldRow, name); o != "" {
+			fields = append(fields, Field{Column: name, Old: o})
+		}
+	}
+	return fields
+}
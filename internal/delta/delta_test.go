@@ -0,0 +1,166 @@
+package delta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// writeFile writes a test file and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDiff(t *testing.T) {
+	old := writeFile(t, "old.csv", "StudentID,FirstName,LastName,Consent\n"+
+		"1001,Ada,Lovelace,Y\n"+
+		"1002,Grace,Hopper,Y\n"+
+		"1003,Alan,Turing,Y\n")
+	// The new file orders its columns differently and changes the case of a key
+	current := writeFile(t, "new.csv", "\ufeffLast Name,student_id,FirstName,Consent\n"+
+		"Lovelace,1001,Ada,Y\n"+
+		"Byron,1002,Grace,Y\n"+
+		"Hamilton,1004,Margaret,Y\n"+
+		"Nobody,,Nemo,N\n")
+
+	result, err := Diff(models.FileTypeStudents, old, current)
+	if err != nil {
+		t.Fatalf("Failed to diff files: %v", err)
+	}
+
+	if len(result.Added) != 2 || result.Added[0].Key != "1004" || result.Added[1].Key != "" {
+		t.Errorf("Expected 1004 and the row without a key to be added, got %+v", result.Added)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].Key != "1003" || result.Removed[0].Line != 4 {
+		t.Errorf("Expected 1003 on line 4 to be removed, got %+v", result.Removed)
+	}
+	if len(result.Changed) != 1 {
+		t.Fatalf("Expected 1 changed row, got %+v", result.Changed)
+	}
+	change := result.Changed[0]
+	if change.New.Key != "1002" || len(change.Fields) != 1 || change.Fields[0] != (Field{Column: "LastName", Old: "Hopper", New: "Byron"}) {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if result.Unchanged != 1 || result.Rows() != 3 || !result.HasChanges() {
+		t.Errorf("Expected 1 unchanged row and 3 delta rows, got %d and %d", result.Unchanged, result.Rows())
+	}
+
+	// The delta keeps the new file's header and row order
+	var buf bytes.Buffer
+	if err := result.WriteDelta(&buf); err != nil {
+		t.Fatalf("Failed to write delta: %v", err)
+	}
+	expected := "Last Name,student_id,FirstName,Consent\n" +
+		"Byron,1002,Grace,Y\n" +
+		"Hamilton,1004,Margaret,Y\n" +
+		"Nobody,,Nemo,N\n"
+	if buf.String() != expected {
+		t.Errorf("Expected delta:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestDiffCompositeKey(t *testing.T) {
+	// Key values containing spaces must not run together
+	old := writeFile(t, "old.csv", "Subject,CourseNumber,Title\nMATH 1,130,Algebra\n")
+	current := writeFile(t, "new.csv", "Subject,CourseNumber,Title\nmath,1 130,Algebra\n")
+
+	result, err := Diff(models.FileTypeCourses, old, current)
+	if err != nil {
+		t.Fatalf("Failed to diff files: %v", err)
+	}
+	if len(result.Added) != 1 || len(result.Removed) != 1 {
+		t.Errorf("Expected one added and one removed row, got %+v", result)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	content := "Subject,CourseNumber,Title\nMATH,1130,Algebra\nENGL,1010,Composition\n"
+	old := writeFile(t, "old.csv", content)
+	// Reordered rows and surrounding space are not changes
+	current := writeFile(t, "new.csv", "Subject,CourseNumber,Title\nENGL,1010, Composition\nMATH,1130,Algebra\n")
+
+	result, err := Diff(models.FileTypeCourses, old, current)
+	if err != nil {
+		t.Fatalf("Failed to diff files: %v", err)
+	}
+	if result.HasChanges() || result.Unchanged != 2 {
+		t.Errorf("Expected no changes, got %+v", result)
+	}
+}
+
+func TestDiffMissingKeyColumn(t *testing.T) {
+	old := writeFile(t, "old.csv", "Subject,Title\nMATH,Algebra\nENGL,Composition\n")
+	current := writeFile(t, "new.csv", "Title,Subject\nAlgebra,MATH\nComposition,ENGL 2\n")
+
+	// Without the CourseNumber key column, rows are matched by all of their values
+	result, err := Diff(models.FileTypeCourses, old, current)
+	if err != nil {
+		t.Fatalf("Failed to diff files: %v", err)
+	}
+	if len(result.Key) != 2 || result.Unchanged != 1 || len(result.Added) != 1 || len(result.Removed) != 1 {
+		t.Errorf("Expected rows matched by their values, got %+v", result)
+	}
+}
+
+func TestDiffDuplicateKey(t *testing.T) {
+	// A key that is not unique must not hide a row that differs from the one matched first
+	old := writeFile(t, "old.csv", "StudentID,FirstName\n1001,Ada\n1001,Augusta\n")
+	current := writeFile(t, "new.csv", "StudentID,FirstName\n1001,Augusta\n1001,Byron\n")
+
+	result, err := Diff(models.FileTypeStudents, old, current)
+	if err != nil {
+		t.Fatalf("Failed to diff files: %v", err)
+	}
+	if result.Unchanged != 1 || len(result.Changed) != 1 || result.Changed[0].New.Values[1] != "Byron" {
+		t.Errorf("Expected Augusta unchanged and Byron changed, got %+v", result)
+	}
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(t.TempDir())
+	src := writeFile(t, "courses.csv", "Subject,CourseNumber\nMATH,1130\n")
+
+	if _, ok := store.Snapshot("", "https://example.com", models.FileTypeCourses); ok {
+		t.Fatal("Expected no snapshot before one is saved")
+	}
+	if err := store.Save("", "https://example.com", models.FileTypeCourses, src); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	path, ok := store.Snapshot("", "https://example.com", models.FileTypeCourses)
+	if !ok {
+		t.Fatal("Expected a snapshot after saving")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "Subject,CourseNumber\nMATH,1130\n" {
+		t.Errorf("Unexpected snapshot content: %q", data)
+	}
+
+	// Snapshots are kept per endpoint and profile; no name is the default profile
+	if _, ok := store.Snapshot("", "https://other.example.com", models.FileTypeCourses); ok {
+		t.Error("Expected no snapshot for another endpoint")
+	}
+	if _, ok := store.Snapshot("college-b", "https://example.com", models.FileTypeCourses); ok {
+		t.Error("Expected no snapshot for another profile")
+	}
+	if _, ok := store.Snapshot("default", "https://example.com", models.FileTypeCourses); !ok {
+		t.Error("Expected the snapshot for the default profile")
+	}
+
+	if err := store.Remove("", "https://example.com", models.FileTypeCourses); err != nil {
+		t.Fatalf("Failed to remove snapshot: %v", err)
+	}
+	if _, ok := store.Snapshot("", "https://example.com", models.FileTypeCourses); ok {
+		t.Error("Expected the snapshot to be removed")
+	}
+	if err := store.Remove("", "https://example.com", models.FileTypeCourses); err != nil {
+		t.Errorf("Expected removing a missing snapshot to succeed, got %v", err)
+	}
+}
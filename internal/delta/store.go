@@ -0,0 +1,99 @@
+package delta
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// DirName is the name of the snapshot directory in the config directory
+const DirName = "snapshots"
+
+// Store keeps a snapshot of the last successfully uploaded file of each type.
+// Snapshots are kept per profile and endpoint so a test server never stands in
+// for production, and one institution's upload never stands in for another's.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a snapshot store in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// OpenStore returns the snapshot store in the config directory
+func OpenStore() (*Store, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(filepath.Join(dir, DirName)), nil
+}
+
+// path returns the location of the snapshot for a file type, profile and
+// endpoint. The default profile keeps the location of older versions, which
+// kept snapshots by endpoint alone.
+func (s *Store) path(profile, endpoint string, ft models.FileType) string {
+	key := endpoint
+	if profile != "" && profile != config.DefaultProfile {
+		key = profile + "\n" + endpoint
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8]), ft.String()+".csv")
+}
+
+// Snapshot returns the path of the snapshot for a file type, profile and endpoint, if there is one
+func (s *Store) Snapshot(profile, endpoint string, ft models.FileType) (string, bool) {
+	path := s.path(profile, endpoint, ft)
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// Save replaces the snapshot for a file type, profile and endpoint with a copy of src
+func (s *Store) Save(profile, endpoint string, ft models.FileType, src string) error {
+	path := s.path(profile, endpoint, ft)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	// Write to a temporary file first so a failed copy never leaves a partial snapshot
+	out, err := os.CreateTemp(filepath.Dir(path), ft.String()+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := os.Rename(out.Name(), path); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the snapshot for a file type, profile and endpoint
+func (s *Store) Remove(profile, endpoint string, ft models.FileType) error {
+	if err := os.Remove(s.path(profile, endpoint, ft)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+	return nil
+}
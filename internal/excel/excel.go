@@ -0,0 +1,182 @@
+package excel
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrLegacyFormat is returned for binary .xls workbooks, which cannot be read
+var ErrLegacyFormat = errors.New("legacy .xls workbooks are not supported; save the file as .xlsx or .csv")
+
+// Options configures a conversion
+type Options struct {
+	// Sheet selects the worksheet by name or 1-based index; empty means the first sheet
+	Sheet string
+}
+
+// Result describes a completed conversion
+type Result struct {
+	// Sheet is the name of the converted worksheet
+	Sheet string
+	// Rows is the number of rows written, including the header
+	Rows int
+}
+
+// IsWorkbook reports whether the path names an Excel workbook
+func IsWorkbook(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xlsx", ".xlsm", ".xls":
+		return true
+	default:
+		return false
+	}
+}
+
+// Sheets returns the names of the worksheets in a workbook, in order
+func Sheets(path string) ([]string, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.GetSheetList(), nil
+}
+
+// ConvertFile converts a worksheet of the workbook at src to a CSV file at dst
+func ConvertFile(src, dst string, options Options) (*Result, error) {
+	out, err := os.Create(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+
+	result, err := Convert(src, out, options)
+	if closeErr := out.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", dst, closeErr)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Convert writes a worksheet of the workbook at path to w as CSV.
+// Dates are written as YYYY-MM-DD, numbers are written in full without
+// scientific notation or floating point noise, and numbers formatted with
+// leading zeros keep them. Blank rows are skipped and every row is padded
+// to the width of the widest row.
+func Convert(path string, w io.Writer, options Options) (*Result, error) {
+	f, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheet, err := resolveSheet(f.GetSheetList(), options.Sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Raw values are the numbers as stored; formatted values are what Excel displays
+	raw, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %w", sheet, err)
+	}
+	formatted, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet %s: %w", sheet, err)
+	}
+
+	cells, err := newCellFormatter(f, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	width := 0
+	for _, row := range raw {
+		width = max(width, len(row))
+	}
+
+	result := &Result{Sheet: sheet}
+	writer := csv.NewWriter(w)
+	record := make([]string, width)
+	for r, row := range raw {
+		blank := true
+		for c := range record {
+			record[c] = ""
+			if c >= len(row) {
+				continue
+			}
+			display := row[c]
+			if r < len(formatted) && c < len(formatted[r]) {
+				display = formatted[r][c]
+			}
+			value, err := cells.value(c+1, r+1, row[c], display)
+			if err != nil {
+				return nil, err
+			}
+			record[c] = value
+			if strings.TrimSpace(value) != "" {
+				blank = false
+			}
+		}
+		if blank {
+			continue
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("failed to write CSV: %w", err)
+		}
+		result.Rows++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	return result, nil
+}
+
+// open opens a workbook, rejecting formats that cannot be read
+func open(path string) (*excelize.File, error) {
+	if strings.EqualFold(filepath.Ext(path), ".xls") {
+		return nil, ErrLegacyFormat
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// resolveSheet finds a sheet by name (case-insensitively) or by 1-based index
+func resolveSheet(sheets []string, sheet string) (string, error) {
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("workbook has no sheets")
+	}
+	if sheet == "" {
+		return sheets[0], nil
+	}
+
+	for _, name := range sheets {
+		if strings.EqualFold(name, sheet) {
+			return name, nil
+		}
+	}
+	if index, err := strconv.Atoi(sheet); err == nil && index >= 1 && index <= len(sheets) {
+		return sheets[index-1], nil
+	}
+
+	return "", fmt.Errorf("sheet %q not found; available sheets: %s", sheet, strings.Join(sheets, ", "))
+}
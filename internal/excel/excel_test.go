@@ -0,0 +1,165 @@
+package excel
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// createWorkbook writes a workbook with a students sheet exercising the cell normalizations
+func createWorkbook(t *testing.T) string {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	if err := f.SetSheetName("Sheet1", "Notes"); err != nil {
+		t.Fatalf("Failed to rename sheet: %v", err)
+	}
+	f.SetCellValue("Notes", "A1", "Exported from the SIS")
+
+	if _, err := f.NewSheet("Students"); err != nil {
+		t.Fatalf("Failed to create sheet: %v", err)
+	}
+	padded := "000000"
+	paddedStyle, _ := f.NewStyle(&excelize.Style{CustomNumFmt: &padded})
+	dateStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 14})
+	thousandsStyle, _ := f.NewStyle(&excelize.Style{NumFmt: 3})
+
+	rows := [][]interface{}{
+		{"StudentID", "Name", "DateOfBirth", "Balance", "Phone", "Ratio"},
+		{42, "Ada, Countess", time.Date(1965, 12, 10, 0, 0, 0, 0, time.UTC), 1234567, 123456789012345678.0, 0.1 + 0.2},
+		{},
+		{7, "O'Brien", time.Date(1906, 12, 9, 0, 0, 0, 0, time.UTC), 12, "0042", 1.5},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Students", cell, &row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	f.SetCellStyle("Students", "A2", "A4", paddedStyle)
+	f.SetCellStyle("Students", "C2", "C4", dateStyle)
+	f.SetCellStyle("Students", "D2", "D4", thousandsStyle)
+
+	path := filepath.Join(t.TempDir(), "students.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("Failed to save workbook: %v", err)
+	}
+	return path
+}
+
+func TestConvert(t *testing.T) {
+	path := createWorkbook(t)
+
+	var out bytes.Buffer
+	result, err := Convert(path, &out, Options{Sheet: "students"})
+	if err != nil {
+		t.Fatalf("Failed to convert workbook: %v", err)
+	}
+
+	expected := "StudentID,Name,DateOfBirth,Balance,Phone,Ratio\n" +
+		"000042,\"Ada, Countess\",1965-12-10,1234567,123456789012346000,0.3\n" +
+		"000007,O'Brien,1906-12-09,12,0042,1.5\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if result.Sheet != "Students" || result.Rows != 3 {
+		t.Errorf("Expected 3 rows from Students, got %d from %s", result.Rows, result.Sheet)
+	}
+}
+
+func TestConvertSheetSelection(t *testing.T) {
+	path := createWorkbook(t)
+
+	sheets, err := Sheets(path)
+	if err != nil {
+		t.Fatalf("Failed to list sheets: %v", err)
+	}
+	if len(sheets) != 2 || sheets[0] != "Notes" || sheets[1] != "Students" {
+		t.Fatalf("Unexpected sheets: %v", sheets)
+	}
+
+	testCases := []struct {
+		sheet    string
+		expected string
+	}{
+		{"", "Notes"},
+		{"1", "Notes"},
+		{"2", "Students"},
+		{"NOTES", "Notes"},
+	}
+	for _, tc := range testCases {
+		result, err := Convert(path, &bytes.Buffer{}, Options{Sheet: tc.sheet})
+		if err != nil {
+			t.Errorf("Sheet %q: unexpected error: %v", tc.sheet, err)
+			continue
+		}
+		if result.Sheet != tc.expected {
+			t.Errorf("Sheet %q: expected %s, got %s", tc.sheet, tc.expected, result.Sheet)
+		}
+	}
+
+	if _, err := Convert(path, &bytes.Buffer{}, Options{Sheet: "3"}); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+}
+
+func TestConvertFile(t *testing.T) {
+	path := createWorkbook(t)
+	dst := filepath.Join(t.TempDir(), "students.csv")
+
+	if _, err := ConvertFile(path, dst, Options{Sheet: "Students"}); err != nil {
+		t.Fatalf("Failed to convert workbook: %v", err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Size() == 0 {
+		t.Errorf("Expected a CSV file at %s", dst)
+	}
+
+	// A failed conversion leaves no partial file behind
+	failed := filepath.Join(t.TempDir(), "missing.csv")
+	if _, err := ConvertFile(path, failed, Options{Sheet: "Grades"}); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Error("Expected the partial CSV file to be removed")
+	}
+}
+
+func TestLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "courses.xls")
+	if err := os.WriteFile(path, []byte("not a workbook"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if !IsWorkbook(path) {
+		t.Error("Expected .xls to be recognized as a workbook")
+	}
+	if _, err := Convert(path, &bytes.Buffer{}, Options{}); !errors.Is(err, ErrLegacyFormat) {
+		t.Errorf("Expected ErrLegacyFormat, got %v", err)
+	}
+}
+
+func TestClassifyFormat(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected formatKind
+	}{
+		{"yyyy-mm-dd", formatDate},
+		{"[$-409]mmmm d, yyyy;@", formatDate},
+		{"h:mm AM/PM", formatDate},
+		{"00000", formatPadded},
+		{"000\\-00\\-0000", formatPadded},
+		{"#,##0.00", formatNumber},
+		{"0.00\"hrs\"", formatNumber},
+		{"[Red]0.0%", formatNumber},
+	}
+	for _, tc := range testCases {
+		if kind := classifyFormat(tc.code); kind != tc.expected {
+			t.Errorf("%q: expected kind %d, got %d", tc.code, tc.expected, kind)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package excel
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// formatKind classifies a number format by how its cells are written to CSV
+type formatKind int
+
+const (
+	// formatNumber cells are written as the stored number
+	formatNumber formatKind = iota
+	// formatDate cells are written as an ISO date or time
+	formatDate
+	// formatPadded cells are written as displayed, keeping leading zeros
+	formatPadded
+)
+
+// builtInDateFormats are the built-in number format IDs that display dates or times
+var builtInDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	27: true, 28: true, 29: true, 30: true, 31: true, 32: true, 33: true, 34: true, 35: true, 36: true,
+	45: true, 46: true, 47: true,
+	50: true, 51: true, 52: true, 53: true, 54: true, 55: true, 56: true, 57: true, 58: true,
+}
+
+// cellFormatter converts cell values to their CSV form
+type cellFormatter struct {
+	file     *excelize.File
+	sheet    string
+	date1904 bool
+	kinds    map[int]formatKind
+}
+
+// newCellFormatter creates a cellFormatter for a sheet
+func newCellFormatter(f *excelize.File, sheet string) (*cellFormatter, error) {
+	props, err := f.GetWorkbookProps()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workbook properties: %w", err)
+	}
+
+	return &cellFormatter{
+		file:     f,
+		sheet:    sheet,
+		date1904: props.Date1904 != nil && *props.Date1904,
+		kinds:    map[int]formatKind{},
+	}, nil
+}
+
+// value returns the CSV value of the cell at col, row (1-based) given its raw and displayed values
+func (cf *cellFormatter) value(col, row int, raw, display string) (string, error) {
+	// Text and plainly displayed numbers need no conversion
+	if raw == display {
+		return raw, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return display, nil
+	}
+
+	kind, err := cf.kind(col, row)
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case formatDate:
+		return cf.date(number, display), nil
+	case formatPadded:
+		return display, nil
+	default:
+		return normalizeNumber(number), nil
+	}
+}
+
+// kind returns the format kind of the cell at col, row
+func (cf *cellFormatter) kind(col, row int) (formatKind, error) {
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return formatNumber, err
+	}
+	styleID, err := cf.file.GetCellStyle(cf.sheet, cell)
+	if err != nil {
+		return formatNumber, fmt.Errorf("failed to read style of cell %s: %w", cell, err)
+	}
+
+	if kind, ok := cf.kinds[styleID]; ok {
+		return kind, nil
+	}
+
+	kind := formatNumber
+	style, err := cf.file.GetStyle(styleID)
+	if err != nil {
+		return formatNumber, fmt.Errorf("failed to read style of cell %s: %w", cell, err)
+	}
+	if style.CustomNumFmt != nil {
+		kind = classifyFormat(*style.CustomNumFmt)
+	} else if builtInDateFormats[style.NumFmt] {
+		kind = formatDate
+	}
+
+	cf.kinds[styleID] = kind
+	return kind, nil
+}
+
+// date formats a date serial number as YYYY-MM-DD, adding the time of day when present
+func (cf *cellFormatter) date(serial float64, display string) string {
+	t, err := excelize.ExcelDateToTime(serial, cf.date1904)
+	if err != nil {
+		return display
+	}
+
+	switch {
+	case serial < 1:
+		return t.Format("15:04:05")
+	case serial == math.Trunc(serial):
+		return t.Format("2006-01-02")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// classifyFormat classifies a custom number format code
+func classifyFormat(code string) formatKind {
+	// Only the format for positive numbers matters
+	if i := strings.IndexByte(code, ';'); i >= 0 {
+		code = code[:i]
+	}
+
+	// Drop quoted literals, escaped characters and bracketed colors, locales and conditions
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case '"':
+			if end := strings.IndexByte(code[i+1:], '"'); end >= 0 {
+				i += end + 1
+			} else {
+				i = len(code)
+			}
+		case '\\', '_', '*':
+			i++
+		case '[':
+			if end := strings.IndexByte(code[i:], ']'); end >= 0 {
+				i += end
+			} else {
+				i = len(code)
+			}
+		default:
+			b.WriteByte(code[i])
+		}
+	}
+	code = strings.ToLower(b.String())
+
+	if strings.ContainsAny(code, "ymdhs") {
+		return formatDate
+	}
+	if strings.HasPrefix(code, "00") && !strings.ContainsAny(code, ".#?e%,") {
+		return formatPadded
+	}
+	return formatNumber
+}
+
+// normalizeNumber formats a number in full, rounded to the 15 significant
+// digits Excel keeps, without an exponent
+func normalizeNumber(number float64) string {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(number, 'g', 15, 64), 64)
+	if err != nil {
+		rounded = number
+	}
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
@@ -0,0 +1,377 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// FileName is the name of the journal file in the config directory
+const FileName = "history.jsonl"
+
+// Status is the outcome of an upload run
+type Status string
+
+const (
+	// StatusSuccess means the server accepted the upload
+	StatusSuccess Status = "success"
+	// StatusFailed means the server rejected the upload or it could not be sent
+	StatusFailed Status = "failed"
+	// StatusCancelled means the upload was cancelled or timed out
+	StatusCancelled Status = "cancelled"
+	// StatusSkipped means nothing was sent because every file was unchanged
+	StatusSkipped Status = "skipped"
+)
+
+// ParseStatus converts a status name to a Status
+func ParseStatus(name string) (Status, error) {
+	switch status := Status(strings.ToLower(name)); status {
+	case StatusSuccess, StatusFailed, StatusCancelled, StatusSkipped:
+		return status, nil
+	default:
+		return "", fmt.Errorf("unknown status: %s (use success, failed, cancelled or skipped)", name)
+	}
+}
+
+// ErrNotFound is returned when no entry matches an ID
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry records a single upload run. Its ID is the run ID that the run's log
+// records carry.
+type Entry struct {
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Endpoint string    `json:"endpoint"`
+	// Profile is the configuration profile the run used; entries written by
+	// older versions have none and count as the default profile
+	Profile     string `json:"profile,omitempty"`
+	Files       []File `json:"files"`
+	Status      Status `json:"status"`
+	Code        int    `json:"code,omitempty"`
+	Message     string `json:"message,omitempty"`
+	ReferenceID string `json:"referenceId,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// Duration is how long the request to the server took
+	Duration time.Duration `json:"duration"`
+	// Timings breaks down the whole run; entries written by older versions have none
+	Timings *Timings `json:"timings,omitempty"`
+}
+
+// Timings records how long each step of an upload run took. Steps that did
+// not run are zero.
+type Timings struct {
+	Convert  time.Duration `json:"convert,omitempty"`
+	Validate time.Duration `json:"validate,omitempty"`
+	Upload   time.Duration `json:"upload,omitempty"`
+	Total    time.Duration `json:"total"`
+}
+
+// File records a file sent in an upload
+type File struct {
+	Type   models.FileType `json:"type"`
+	Path   string          `json:"path"`
+	Size   int64           `json:"size"`
+	SHA256 string          `json:"sha256"`
+	// Rows is the number of data rows, excluding the header
+	Rows int `json:"rows"`
+	// DeltaRows is the number of added and changed rows sent when only a delta was uploaded
+	DeltaRows int `json:"deltaRows,omitempty"`
+}
+
+// Bytes returns the total size of the entry's files
+func (e Entry) Bytes() int64 {
+	var total int64
+	for _, f := range e.Files {
+		total += f.Size
+	}
+	return total
+}
+
+// HasType reports whether the entry includes a file of the given type
+func (e Entry) HasType(ft models.FileType) bool {
+	_, ok := e.File(ft)
+	return ok
+}
+
+// File returns the entry's file of the given type
+func (e Entry) File(ft models.FileType) (File, bool) {
+	for _, f := range e.Files {
+		if f.Type == ft {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Filter selects journal entries; zero fields match everything
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	Types  []models.FileType
+	Status Status
+	// Limit keeps only the most recent entries
+	Limit int
+}
+
+// matches reports whether an entry passes the filter
+func (f Filter) matches(e *Entry) bool {
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	if f.Status != "" && e.Status != f.Status {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, ft := range f.Types {
+			if e.HasType(ft) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Journal is an append-only record of upload runs stored as JSON lines
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New creates a journal stored at path
+func New(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Open returns the journal in the config directory
+func Open() (*Journal, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return New(filepath.Join(dir, FileName)), nil
+}
+
+// Path returns the location of the journal file
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// NewID returns a new entry or run ID that sorts by time
+func NewID(t time.Time) string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return t.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Append adds an entry to the journal, assigning an ID and time if they are unset
+func (j *Journal) Append(entry *Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = NewID(entry.Time)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history entry: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the entries that match the filter, oldest first
+func (j *Journal) Entries(filter Filter) ([]Entry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A line cut short by a crash should not hide the rest of the history
+			continue
+		}
+		if filter.matches(&entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].Time.Before(entries[b].Time)
+	})
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// LastSuccessful returns, for each file type, the most recent successful upload
+// to endpoint with profile that included it. Profiles are told apart because
+// each may upload with another institution's key to the same endpoint.
+func (j *Journal) LastSuccessful(profile, endpoint string) (map[models.FileType]Entry, error) {
+	entries, err := j.Entries(Filter{Status: StatusSuccess})
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[models.FileType]Entry{}
+	for _, entry := range entries {
+		if entry.Endpoint != endpoint || profileName(entry.Profile) != profileName(profile) {
+			continue
+		}
+		// Entries are oldest first, so later uploads replace earlier ones
+		for _, f := range entry.Files {
+			last[f.Type] = entry
+		}
+	}
+	return last, nil
+}
+
+// Find returns the entry with the given ID, or the only entry whose ID starts with it
+func (j *Journal) Find(id string) (*Entry, error) {
+	entries, err := j.Entries(Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var match *Entry
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+		if strings.HasPrefix(entries[i].ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("history ID %s is ambiguous", id)
+			}
+			match = &entries[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return match, nil
+}
+
+// Describe computes the size, SHA-256 checksum and data row count of a file
+func Describe(file models.UploadFile) (File, error) {
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to open file %s: %w", file.FilePath, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	counter := &lineCounter{}
+	size, err := io.Copy(io.MultiWriter(hash, counter), f)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to read file %s: %w", file.FilePath, err)
+	}
+
+	rows := counter.lines()
+	if rows > 0 {
+		rows-- // header
+	}
+
+	return File{
+		Type:   file.Type,
+		Path:   file.FilePath,
+		Size:   size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Rows:   rows,
+	}, nil
+}
+
+// lineCounter counts non-empty lines written to it
+type lineCounter struct {
+	count  int
+	inLine bool
+}
+
+// Write counts the lines in p
+func (c *lineCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		switch b {
+		case '\n':
+			if c.inLine {
+				c.count++
+			}
+			c.inLine = false
+		case '\r':
+		default:
+			c.inLine = true
+		}
+	}
+	return len(p), nil
+}
+
+// lines returns the number of non-empty lines, including an unterminated last line
+func (c *lineCounter) lines() int {
+	if c.inLine {
+		return c.count + 1
+	}
+	return c.count
+}
+
+// profileName returns the name of a profile, counting an empty name as the default profile
+func profileName(name string) string {
+	if name == "" {
+		return config.DefaultProfile
+	}
+	return name
+}
@@ -0,0 +1,165 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+func TestJournalAppendAndFilter(t *testing.T) {
+	journal := New(filepath.Join(t.TempDir(), "history", FileName))
+	base := time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)
+
+	entries := []*Entry{
+		{Time: base, Status: StatusSuccess, Files: []File{{Type: models.FileTypeCourses}}},
+		{Time: base.Add(24 * time.Hour), Status: StatusFailed, Files: []File{{Type: models.FileTypeStudents}}},
+		{Time: base.Add(48 * time.Hour), Status: StatusSuccess, Files: []File{{Type: models.FileTypeStudents}, {Type: models.FileTypeStudentCourses}}},
+	}
+	for _, entry := range entries {
+		if err := journal.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+		if entry.ID == "" {
+			t.Error("Expected an ID to be assigned")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		expected int
+	}{
+		{"all", Filter{}, 3},
+		{"since", Filter{Since: base.Add(time.Hour)}, 2},
+		{"until", Filter{Until: base.Add(24 * time.Hour)}, 1},
+		{"status", Filter{Status: StatusSuccess}, 2},
+		{"type", Filter{Types: []models.FileType{models.FileTypeStudents}}, 2},
+		{"limit", Filter{Limit: 1}, 1},
+	}
+	for _, tc := range testCases {
+		result, err := journal.Entries(tc.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to read entries: %v", tc.name, err)
+		}
+		if len(result) != tc.expected {
+			t.Errorf("%s: expected %d entries, got %d", tc.name, tc.expected, len(result))
+		}
+	}
+
+	// The limit keeps the most recent entries
+	latest, _ := journal.Entries(Filter{Limit: 1})
+	if latest[0].ID != entries[2].ID {
+		t.Errorf("Expected the latest entry, got %s", latest[0].ID)
+	}
+}
+
+func TestJournalLastSuccessful(t *testing.T) {
+	journal := New(filepath.Join(t.TempDir(), FileName))
+	base := time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)
+	endpoint := "https://example.com/api/Upload"
+
+	entries := []*Entry{
+		{Time: base, Endpoint: endpoint, Status: StatusSuccess, Files: []File{{Type: models.FileTypeCourses, SHA256: "old"}, {Type: models.FileTypeStudents, SHA256: "students"}}},
+		{Time: base.Add(time.Hour), Endpoint: endpoint, Status: StatusSuccess, Files: []File{{Type: models.FileTypeCourses, SHA256: "new"}}},
+		{Time: base.Add(2 * time.Hour), Endpoint: endpoint, Status: StatusFailed, Files: []File{{Type: models.FileTypeCourses, SHA256: "failed"}}},
+		{Time: base.Add(3 * time.Hour), Endpoint: "https://other.example.com", Status: StatusSuccess, Files: []File{{Type: models.FileTypeCourses, SHA256: "other"}}},
+		{Time: base.Add(4 * time.Hour), Endpoint: endpoint, Profile: "college-b", Status: StatusSuccess, Files: []File{{Type: models.FileTypeCourses, SHA256: "college-b"}}},
+	}
+	for _, entry := range entries {
+		if err := journal.Append(entry); err != nil {
+			t.Fatalf("Failed to append entry: %v", err)
+		}
+	}
+
+	// Entries without a profile belong to the default profile
+	last, err := journal.LastSuccessful("default", endpoint)
+	if err != nil {
+		t.Fatalf("Failed to read last successful uploads: %v", err)
+	}
+	if len(last) != 2 {
+		t.Fatalf("Expected 2 file types, got %d", len(last))
+	}
+	if file, _ := last[models.FileTypeCourses].File(models.FileTypeCourses); file.SHA256 != "new" {
+		t.Errorf("Expected the latest successful courses upload, got %s", file.SHA256)
+	}
+	if last[models.FileTypeStudents].ID != entries[0].ID {
+		t.Errorf("Expected the students file from the first upload, got %s", last[models.FileTypeStudents].ID)
+	}
+
+	// Another profile uploading to the same endpoint has its own history
+	last, err = journal.LastSuccessful("college-b", endpoint)
+	if err != nil {
+		t.Fatalf("Failed to read last successful uploads: %v", err)
+	}
+	if file, _ := last[models.FileTypeCourses].File(models.FileTypeCourses); len(last) != 1 || file.SHA256 != "college-b" {
+		t.Errorf("Expected only the college-b upload, got %+v", last)
+	}
+}
+
+func TestJournalFind(t *testing.T) {
+	journal := New(filepath.Join(t.TempDir(), FileName))
+
+	// Reading a journal that does not exist yet returns nothing
+	if entries, err := journal.Entries(Filter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("Expected no entries, got %v, %v", entries, err)
+	}
+
+	entry := &Entry{ID: "20240313-090000-abcd", Status: StatusSuccess, ReferenceID: "REF-1"}
+	if err := journal.Append(entry); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+
+	// A truncated line is skipped
+	f, _ := os.OpenFile(journal.Path(), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"id":"20240314`)
+	f.Close()
+
+	found, err := journal.Find("20240313-0900")
+	if err != nil {
+		t.Fatalf("Failed to find entry by prefix: %v", err)
+	}
+	if found.ReferenceID != "REF-1" {
+		t.Errorf("Expected REF-1, got %s", found.ReferenceID)
+	}
+	if _, err := journal.Find("2025"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "courses.csv")
+	data := []byte("Subject,CourseNumber\r\nMATH,1130\r\n\r\nENGL,1010")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	file, err := Describe(models.UploadFile{Type: models.FileTypeCourses, FilePath: path})
+	if err != nil {
+		t.Fatalf("Failed to describe file: %v", err)
+	}
+	if file.Rows != 2 || file.Size != int64(len(data)) {
+		t.Errorf("Expected 2 rows and %d bytes, got %d rows and %d bytes", len(data), file.Rows, file.Size)
+	}
+	sum := sha256.Sum256(data)
+	if file.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected checksum %s", file.SHA256)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	if status, err := ParseStatus("Failed"); err != nil || status != StatusFailed {
+		t.Errorf("Expected failed, got %s, %v", status, err)
+	}
+	if status, err := ParseStatus("skipped"); err != nil || status != StatusSkipped {
+		t.Errorf("Expected skipped, got %s, %v", status, err)
+	}
+	if _, err := ParseStatus("pending"); err == nil {
+		t.Error("Expected an error for an unknown status")
+	}
+}
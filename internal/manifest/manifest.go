@@ -0,0 +1,219 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// goTypeName matches the Go type names in yaml decoding errors
+var goTypeName = regexp.MustCompile(` in type manifest\.\w+`)
+
+// Version is the manifest format version this build understands
+const Version = 1
+
+// Manifest describes a repeatable upload job
+type Manifest struct {
+	// Version is the manifest format version and must be 1
+	Version int `yaml:"version" json:"version"`
+	// Name identifies the job in logs
+	Name string `yaml:"name" json:"name"`
+	// Profile selects the configuration profile, unless one is given with --profile
+	Profile string `yaml:"profile" json:"profile"`
+	// Endpoint overrides the configured API endpoint
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// Files maps a file type to a path or glob pattern; relative paths are resolved against the manifest's directory
+	Files map[string]string `yaml:"files" json:"files"`
+	// Sheet selects the worksheet converted from Excel workbooks
+	Sheet string `yaml:"sheet" json:"sheet"`
+	// Validate overrides the validate_files setting
+	Validate *bool `yaml:"validate" json:"validate"`
+	// Strict overrides the validate_strict setting
+	Strict *bool `yaml:"strict" json:"strict"`
+	// Timeout is the upload deadline, such as "30m"
+	Timeout string `yaml:"timeout" json:"timeout"`
+	// Force uploads files even when they are unchanged since the last successful upload
+	Force bool `yaml:"force" json:"force"`
+	// Delta sends only the rows added or changed since the last successful upload
+	Delta bool `yaml:"delta" json:"delta"`
+	// After lists the actions taken once the upload succeeds
+	After After `yaml:"after" json:"after"`
+
+	// dir is the directory relative paths are resolved against
+	dir string
+}
+
+// After describes what to do with the source files after a successful upload
+type After struct {
+	// Archive moves the files into a timestamped folder under this directory
+	Archive string `yaml:"archive" json:"archive"`
+	// Delete removes the files
+	Delete bool `yaml:"delete" json:"delete"`
+}
+
+// Load reads a manifest from a YAML or JSON file and checks it. Files ending
+// in .json are read as JSON and anything else as YAML. Unknown keys are errors.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&m)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&m)
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid manifest %s: the file is empty", path)
+	}
+	if err != nil {
+		// Drop the Go type names yaml adds, such as "in type manifest.Manifest"
+		message := goTypeName.ReplaceAllString(err.Error(), "")
+		return nil, fmt.Errorf("invalid manifest %s: %s", path, message)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path for manifest: %w", err)
+	}
+	m.dir = filepath.Dir(abs)
+
+	if err := m.Check(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Check reports the first problem with the manifest's values
+func (m *Manifest) Check() error {
+	if m.Version != Version {
+		return fmt.Errorf("version must be %d, got %d", Version, m.Version)
+	}
+	if m.Endpoint != "" {
+		if u, err := url.Parse(m.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("endpoint %q is not a URL", m.Endpoint)
+		}
+	}
+	if len(m.Files) == 0 {
+		return fmt.Errorf("files must list at least one file")
+	}
+	seen := map[models.FileType]string{}
+	for name, path := range m.Files {
+		ft, err := models.ParseFileType(name)
+		if err != nil {
+			return fmt.Errorf("files: %w", err)
+		}
+		if other, ok := seen[ft]; ok {
+			return fmt.Errorf("files: %s and %s are the same file type", other, name)
+		}
+		seen[ft] = name
+		if strings.TrimSpace(path) == "" {
+			return fmt.Errorf("files: %s has no path", name)
+		}
+		if _, err := filepath.Match(path, ""); err != nil {
+			return fmt.Errorf("files: %s: invalid pattern %q", name, path)
+		}
+	}
+	if _, err := m.TimeoutDuration(); err != nil {
+		return err
+	}
+	if m.After.Archive != "" && m.After.Delete {
+		return fmt.Errorf("after: archive and delete cannot both be set")
+	}
+	return nil
+}
+
+// TimeoutDuration returns the upload deadline, or zero if none is set
+func (m *Manifest) TimeoutDuration() (time.Duration, error) {
+	if m.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(m.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("timeout %q is not a positive duration such as 30m", m.Timeout)
+	}
+	return timeout, nil
+}
+
+// UploadFiles resolves the manifest's paths and patterns to the files to upload.
+// A pattern must match exactly one file so a job never picks up the wrong export.
+func (m *Manifest) UploadFiles() ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	for name, pattern := range m.Files {
+		ft, err := models.ParseFileType(name)
+		if err != nil {
+			return nil, err
+		}
+
+		path := m.resolve(pattern)
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q", name, pattern)
+		}
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("%s file does not exist: %s", ft.String(), path)
+		case 1:
+			files = append(files, models.UploadFile{Type: ft, FilePath: matches[0]})
+		default:
+			return nil, fmt.Errorf("%s pattern %s matches %d files; it must match exactly one", ft.String(), pattern, len(matches))
+		}
+	}
+
+	// Keep the usual file type order regardless of how the manifest lists them
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].Type < files[b].Type
+	})
+	return files, nil
+}
+
+// RunAfter applies the after actions to the uploaded source files
+func (m *Manifest) RunAfter(files []models.UploadFile, now time.Time) error {
+	switch {
+	case m.After.Delete:
+		for _, file := range files {
+			if err := os.Remove(file.FilePath); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", file.FilePath, err)
+			}
+		}
+	case m.After.Archive != "":
+		dir := filepath.Join(m.resolve(m.After.Archive), now.Format("20060102-150405"))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		for _, file := range files {
+			dst := filepath.Join(dir, filepath.Base(file.FilePath))
+			if err := os.Rename(file.FilePath, dst); err != nil {
+				return fmt.Errorf("failed to archive %s: %w", file.FilePath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolve makes a path relative to the manifest's directory absolute
+func (m *Manifest) resolve(path string) string {
+	if filepath.IsAbs(path) || m.dir == "" {
+		return path
+	}
+	return filepath.Join(m.dir, path)
+}
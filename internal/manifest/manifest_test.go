@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// writeFile writes a test file under dir and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	dir := t.TempDir()
+	courses := writeFile(t, dir, "exports/courses.csv", "Subject,CourseNumber\n")
+	students := writeFile(t, dir, "exports/students-20240313.csv", "StudentID\n")
+	path := writeFile(t, dir, "job.yaml", `version: 1
+name: nightly
+profile: test
+endpoint: https://example.com/api/Upload
+files:
+  Student Courses: exports/missing.csv
+  students: exports/students-*.csv
+  courses: `+courses+`
+sheet: "2"
+validate: false
+strict: true
+timeout: 30m
+delta: true
+after:
+  archive: archive
+`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if m.Name != "nightly" || m.Profile != "test" || m.Sheet != "2" || !m.Delta || m.Validate == nil || *m.Validate || m.Strict == nil || !*m.Strict {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+	if timeout, _ := m.TimeoutDuration(); timeout != 30*time.Minute {
+		t.Errorf("Expected a 30m timeout, got %s", timeout)
+	}
+
+	// A path that matches nothing is an error
+	if _, err := m.UploadFiles(); err == nil || !strings.Contains(err.Error(), "missing.csv") {
+		t.Errorf("Expected a missing file error, got %v", err)
+	}
+
+	delete(m.Files, "Student Courses")
+	files, err := m.UploadFiles()
+	if err != nil {
+		t.Fatalf("Failed to resolve files: %v", err)
+	}
+	expected := []models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: courses},
+		{Type: models.FileTypeStudents, FilePath: students},
+	}
+	if len(files) != len(expected) || files[0] != expected[0] || files[1] != expected[1] {
+		t.Errorf("Expected %v, got %v", expected, files)
+	}
+
+	// Patterns must match exactly one file
+	writeFile(t, dir, "exports/students-20240314.csv", "StudentID\n")
+	if _, err := m.UploadFiles(); err == nil || !strings.Contains(err.Error(), "matches 2 files") {
+		t.Errorf("Expected an ambiguous pattern error, got %v", err)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "job.json", `{"version": 1, "files": {"equivalencies": "equivalencies.csv"}, "force": true}`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	if !m.Force || m.Files["equivalencies"] != "equivalencies.csv" {
+		t.Errorf("Unexpected manifest: %+v", m)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		file     string
+		content  string
+		expected string
+	}{
+		{"unknown key", "job.yaml", "version: 1\nfiles:\n  courses: c.csv\nretries: 3\n", "field retries not found"},
+		{"unknown nested key", "job.yaml", "version: 1\nfiles:\n  courses: c.csv\nafter:\n  email: ops@example.com\n", "field email not found"},
+		{"unknown JSON key", "job.json", `{"version": 1, "files": {"courses": "c.csv"}, "retries": 3}`, `unknown field "retries"`},
+		{"version", "job.yaml", "files:\n  courses: c.csv\n", "version must be 1"},
+		{"no files", "job.yaml", "version: 1\n", "at least one file"},
+		{"file type", "job.yaml", "version: 1\nfiles:\n  grades: g.csv\n", "unknown file type: grades"},
+		{"same type", "job.yaml", "version: 1\nfiles:\n  students: a.csv\n  Students: b.csv\n", "the same file type"},
+		{"endpoint", "job.yaml", "version: 1\nendpoint: example.com\nfiles:\n  courses: c.csv\n", "not a URL"},
+		{"timeout", "job.yaml", "version: 1\ntimeout: soon\nfiles:\n  courses: c.csv\n", "not a positive duration"},
+		{"after", "job.yaml", "version: 1\nfiles:\n  courses: c.csv\nafter:\n  archive: done\n  delete: true\n", "cannot both be set"},
+		{"empty", "job.yaml", "", "empty"},
+	}
+
+	for _, tc := range testCases {
+		path := writeFile(t, t.TempDir(), tc.file, tc.content)
+		_, err := Load(path)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.expected, err)
+		}
+	}
+}
+
+func TestRunAfter(t *testing.T) {
+	dir := t.TempDir()
+	courses := writeFile(t, dir, "courses.csv", "Subject,CourseNumber\n")
+	files := []models.UploadFile{{Type: models.FileTypeCourses, FilePath: courses}}
+	now := time.Date(2024, 3, 13, 14, 15, 2, 0, time.UTC)
+
+	m := &Manifest{After: After{Archive: "archive"}, dir: dir}
+	if err := m.RunAfter(files, now); err != nil {
+		t.Fatalf("Failed to archive files: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive", "20240313-141502", "courses.csv")); err != nil {
+		t.Errorf("Expected the file to be archived: %v", err)
+	}
+	if _, err := os.Stat(courses); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be moved, got %v", err)
+	}
+
+	writeFile(t, dir, "courses.csv", "Subject,CourseNumber\n")
+	m = &Manifest{After: After{Delete: true}, dir: dir}
+	if err := m.RunAfter(files, now); err != nil {
+		t.Fatalf("Failed to delete files: %v", err)
+	}
+	if _, err := os.Stat(courses); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be deleted, got %v", err)
+	}
+}
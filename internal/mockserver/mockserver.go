@@ -0,0 +1,302 @@
+package mockserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+// Options configures the mock TRTC server
+type Options struct {
+	// APIKeys are the accepted API keys; when empty any non-empty key is accepted
+	APIKeys []string
+	// StorageDir is where received files are written; when empty files are discarded
+	StorageDir string
+	// Latency delays every response
+	Latency time.Duration
+	// StatusCode, when non-zero, is returned for every upload instead of processing it
+	StatusCode int
+	// FailFirst makes the first N uploads fail with FailStatus
+	FailFirst int
+	// ErrorRate is the probability (0 to 1) that an upload fails with FailStatus
+	ErrorRate float64
+	// FailStatus is the status used for injected failures; defaults to 503
+	FailStatus int
+	// MaxMemory is the multipart memory limit before files spill to disk; defaults to 32 MB
+	MaxMemory int64
+	// Logger, when set, receives the errors writing responses
+	Logger *logger.Logger
+}
+
+// Upload records a request the server accepted
+type Upload struct {
+	ReferenceID string
+	APIKey      string
+	Files       []ReceivedFile
+	Received    time.Time
+}
+
+// ReceivedFile describes one file received in an upload
+type ReceivedFile struct {
+	Type     models.FileType
+	FileName string
+	Size     int64
+	Records  int
+	// Path is where the file was stored, empty if StorageDir is not set
+	Path string
+}
+
+// Server is an http.Handler implementing the TRTC upload endpoint
+type Server struct {
+	options  Options
+	mu       sync.Mutex
+	requests int
+	uploads  []Upload
+}
+
+// New creates a new mock server
+func New(options Options) *Server {
+	if options.FailStatus == 0 {
+		options.FailStatus = http.StatusServiceUnavailable
+	}
+	if options.MaxMemory == 0 {
+		options.MaxMemory = 32 << 20
+	}
+	return &Server{options: options}
+}
+
+// Uploads returns the uploads accepted so far
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Upload(nil), s.uploads...)
+}
+
+// Requests returns the number of requests received so far
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// response is the JSON body returned by the server
+type response struct {
+	Success         bool           `json:"success"`
+	Message         string         `json:"message"`
+	ReferenceID     string         `json:"referenceId,omitempty"`
+	RecordsAccepted int            `json:"recordsAccepted"`
+	Files           []fileResponse `json:"files,omitempty"`
+	Errors          []string       `json:"errors,omitempty"`
+}
+
+// fileResponse is the per-file part of the JSON body
+type fileResponse struct {
+	Type            string `json:"type"`
+	FileName        string `json:"fileName"`
+	Success         bool   `json:"success"`
+	RecordsAccepted int    `json:"recordsAccepted"`
+}
+
+// ServeHTTP handles an upload request
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests++
+	request := s.requests
+	s.mu.Unlock()
+
+	if s.options.Latency > 0 {
+		select {
+		case <-time.After(s.options.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Injected failures
+	if s.options.StatusCode != 0 {
+		s.writeError(w, s.options.StatusCode, http.StatusText(s.options.StatusCode))
+		return
+	}
+	if request <= s.options.FailFirst || (s.options.ErrorRate > 0 && rand.Float64() < s.options.ErrorRate) {
+		s.writeError(w, s.options.FailStatus, http.StatusText(s.options.FailStatus))
+		return
+	}
+
+	if err := r.ParseMultipartForm(s.options.MaxMemory); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid multipart form: %v", err))
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	apiKey := r.FormValue("apikey")
+	if !s.validKey(apiKey) {
+		s.writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+
+	// Only the four known file fields are accepted
+	for field := range r.MultipartForm.File {
+		if !knownField(field) {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("unexpected file field: %s", field))
+			return
+		}
+	}
+
+	upload := Upload{
+		ReferenceID: fmt.Sprintf("MOCK-%s-%04d", time.Now().Format("20060102150405"), request),
+		APIKey:      apiKey,
+		Received:    time.Now(),
+	}
+	for _, ft := range models.FileTypes {
+		for _, header := range r.MultipartForm.File[ft.String()] {
+			file, err := s.receive(upload.ReferenceID, ft, header)
+			if err != nil {
+				s.writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			upload.Files = append(upload.Files, file)
+		}
+	}
+	if len(upload.Files) == 0 {
+		s.writeError(w, http.StatusBadRequest, "no files were uploaded")
+		return
+	}
+
+	s.mu.Lock()
+	s.uploads = append(s.uploads, upload)
+	s.mu.Unlock()
+
+	body := response{
+		Success:     true,
+		Message:     fmt.Sprintf("%d file(s) received", len(upload.Files)),
+		ReferenceID: upload.ReferenceID,
+	}
+	for _, f := range upload.Files {
+		body.Files = append(body.Files, fileResponse{
+			Type:            f.Type.String(),
+			FileName:        f.FileName,
+			Success:         true,
+			RecordsAccepted: f.Records,
+		})
+		body.RecordsAccepted += f.Records
+	}
+	s.writeJSON(w, http.StatusOK, body)
+}
+
+// validKey reports whether the API key is accepted
+func (s *Server) validKey(apiKey string) bool {
+	if apiKey == "" {
+		return false
+	}
+	if len(s.options.APIKeys) == 0 {
+		return true
+	}
+	for _, key := range s.options.APIKeys {
+		if key == apiKey {
+			return true
+		}
+	}
+	return false
+}
+
+// receive reads an uploaded file, counting its records and storing it if configured
+func (s *Server) receive(referenceID string, ft models.FileType, header *multipart.FileHeader) (ReceivedFile, error) {
+	src, err := header.Open()
+	if err != nil {
+		return ReceivedFile{}, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	received := ReceivedFile{
+		Type:     ft,
+		FileName: filepath.Base(header.Filename),
+		Size:     header.Size,
+	}
+
+	var reader io.Reader = src
+	if s.options.StorageDir != "" {
+		dir := filepath.Join(s.options.StorageDir, referenceID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return ReceivedFile{}, fmt.Errorf("failed to create storage directory: %w", err)
+		}
+		received.Path = filepath.Join(dir, ft.String()+"_"+received.FileName)
+		dst, err := os.Create(received.Path)
+		if err != nil {
+			return ReceivedFile{}, fmt.Errorf("failed to store uploaded file: %w", err)
+		}
+		defer dst.Close()
+		reader = io.TeeReader(src, dst)
+	}
+
+	// Count records, excluding the header line
+	lines := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			lines++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ReceivedFile{}, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if lines > 0 {
+		received.Records = lines - 1
+	}
+
+	return received, nil
+}
+
+// knownField reports whether a form field is named after one of the file types
+func knownField(field string) bool {
+	for _, ft := range models.FileTypes {
+		if ft.String() == field {
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes a JSON failure response
+func (s *Server) writeError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, response{Success: false, Message: message, Errors: []string{message}})
+}
+
+// writeJSON writes v as a JSON response. A body that cannot be encoded is
+// answered with a 500 instead, so a client never sees a truncated success.
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logError("Failed to encode response: %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		s.logError("Failed to write response: %v", err)
+	}
+}
+
+// logError logs an error to the configured logger, if any
+func (s *Server) logError(format string, v ...interface{}) {
+	if s.options.Logger != nil {
+		s.options.Logger.Error(format, v...)
+	}
+}
@@ -0,0 +1,125 @@
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// postUpload sends a multipart upload with the given API key and form files
+func postUpload(t *testing.T, url, apiKey string, files map[string]string) (*http.Response, response) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("apikey", apiKey); err != nil {
+		t.Fatalf("Failed to write API key: %v", err)
+	}
+	for field, content := range files {
+		fw, err := w.CreateFormFile(field, field+".csv")
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		fw.Write([]byte(content))
+	}
+	w.Close()
+
+	resp, err := http.Post(url, w.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var parsed response
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp, parsed
+}
+
+func TestServer_AcceptsUpload(t *testing.T) {
+	storageDir := t.TempDir()
+	handler := New(Options{APIKeys: []string{"good-key"}, StorageDir: storageDir})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, body := postUpload(t, server.URL, "good-key", map[string]string{
+		"courses":  "id,title\n1,Math\n2,English\n",
+		"students": "id,name\n1,Ada\n",
+	})
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d (%s)", resp.StatusCode, body.Message)
+	}
+	if !body.Success || body.ReferenceID == "" {
+		t.Errorf("Expected a successful response with a reference ID, got %+v", body)
+	}
+	if body.RecordsAccepted != 3 {
+		t.Errorf("Expected 3 records accepted, got %d", body.RecordsAccepted)
+	}
+
+	uploads := handler.Uploads()
+	if len(uploads) != 1 || len(uploads[0].Files) != 2 {
+		t.Fatalf("Expected one upload with two files, got %+v", uploads)
+	}
+	for _, f := range uploads[0].Files {
+		if _, err := os.Stat(f.Path); err != nil {
+			t.Errorf("Expected %s to be stored on disk: %v", f.FileName, err)
+		}
+	}
+}
+
+func TestServer_RejectsInvalidRequests(t *testing.T) {
+	server := httptest.NewServer(New(Options{APIKeys: []string{"good-key"}}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		apiKey   string
+		files    map[string]string
+		expected int
+	}{
+		{"wrong API key", "bad-key", map[string]string{"courses": "id\n1\n"}, http.StatusUnauthorized},
+		{"missing API key", "", map[string]string{"courses": "id\n1\n"}, http.StatusUnauthorized},
+		{"unknown field", "good-key", map[string]string{"grades": "id\n1\n"}, http.StatusBadRequest},
+		{"no files", "good-key", nil, http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		resp, body := postUpload(t, server.URL, tc.apiKey, tc.files)
+		if resp.StatusCode != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.expected, resp.StatusCode)
+		}
+		if body.Success {
+			t.Errorf("%s: expected success to be false", tc.name)
+		}
+	}
+}
+
+func TestServer_InjectsFailures(t *testing.T) {
+	handler := New(Options{FailFirst: 2, FailStatus: http.StatusBadGateway})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	files := map[string]string{"courses": "id\n1\n"}
+	for i := 1; i <= 2; i++ {
+		if resp, _ := postUpload(t, server.URL, "any-key", files); resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("Request %d: expected status 502, got %d", i, resp.StatusCode)
+		}
+	}
+	if resp, _ := postUpload(t, server.URL, "any-key", files); resp.StatusCode != http.StatusOK {
+		t.Errorf("Request 3: expected status 200, got %d", resp.StatusCode)
+	}
+	if handler.Requests() != 3 {
+		t.Errorf("Expected 3 requests, got %d", handler.Requests())
+	}
+
+	// A fixed status code applies to every upload
+	fixed := httptest.NewServer(New(Options{StatusCode: http.StatusInternalServerError}))
+	defer fixed.Close()
+	if resp, _ := postUpload(t, fixed.URL, "any-key", files); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", resp.StatusCode)
+	}
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // FileType represents the type of file being uploaded
 type FileType int
 
@@ -14,6 +20,10 @@ const (
 	FileTypeStudentCourses
 )
 
+// FileTypeUnknown stands for a type that is none of FileTypes, such as one a
+// server response names that this version does not know
+const FileTypeUnknown FileType = -1
+
 // String returns the string representation of a FileType
 func (ft FileType) String() string {
 	switch ft {
@@ -30,6 +40,40 @@ func (ft FileType) String() string {
 	}
 }
 
+// FileTypes lists every file type in upload order
+var FileTypes = []FileType{FileTypeCourses, FileTypeEquivalencies, FileTypeStudents, FileTypeStudentCourses}
+
+// ParseFileType converts a name such as "studentcourses" back to a FileType.
+// Matching ignores case, spaces, dashes and underscores, so "Student Courses" is accepted.
+func ParseFileType(name string) (FileType, error) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+	for _, ft := range FileTypes {
+		if ft.String() == normalized {
+			return ft, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown file type: %s", name)
+}
+
+// MarshalText encodes a FileType by name, so it reads naturally in JSON and YAML
+func (ft FileType) MarshalText() ([]byte, error) {
+	return []byte(ft.String()), nil
+}
+
+// UnmarshalText decodes a FileType from its name, accepting "unknown" for FileTypeUnknown
+func (ft *FileType) UnmarshalText(text []byte) error {
+	if string(text) == FileTypeUnknown.String() {
+		*ft = FileTypeUnknown
+		return nil
+	}
+	parsed, err := ParseFileType(string(text))
+	if err != nil {
+		return err
+	}
+	*ft = parsed
+	return nil
+}
+
 // UploadFile represents a file to be uploaded
 type UploadFile struct {
 	Type     FileType
@@ -40,11 +84,47 @@ type UploadFile struct {
 type UploadRequest struct {
 	APIKey string
 	Files  []UploadFile
+	// Timeout is the deadline for the whole upload; zero means the client default
+	Timeout time.Duration
+	// OnProgress, if set, is called as file content is sent
+	OnProgress ProgressFunc
 }
 
-// UploadResponse represents the response from an upload request
+// Progress reports how much of an upload has been sent
+type Progress struct {
+	File      UploadFile
+	FileSent  int64
+	FileTotal int64
+	Sent      int64
+	Total     int64
+}
+
+// ProgressFunc receives progress updates during an upload
+type ProgressFunc func(Progress)
+
+// UploadResponse represents the response from an upload request. Its JSON
+// encoding is part of the documented machine-readable output of the CLI.
 type UploadResponse struct {
-	Success bool
-	Message string
-	Code    int
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	// ReferenceID is the batch or reference identifier assigned by the server, if any
+	ReferenceID string `json:"referenceId"`
+	// RecordsAccepted and RecordsRejected are the record counts across all files
+	RecordsAccepted int `json:"recordsAccepted"`
+	RecordsRejected int `json:"recordsRejected"`
+	// Files holds the per-file outcomes reported by the server
+	Files []FileResult `json:"files"`
+	// Errors lists server-side errors that are not tied to a single file
+	Errors []string `json:"errors"`
+}
+
+// FileResult represents the server's outcome for a single uploaded file
+type FileResult struct {
+	Type            FileType `json:"type"`
+	FileName        string   `json:"fileName"`
+	Success         bool     `json:"success"`
+	RecordsAccepted int      `json:"recordsAccepted"`
+	RecordsRejected int      `json:"recordsRejected"`
+	Errors          []string `json:"errors"`
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -14,6 +15,7 @@ func TestFileTypeString(t *testing.T) {
 		{FileTypeEquivalencies, "equivalencies"},
 		{FileTypeStudents, "students"},
 		{FileTypeStudentCourses, "studentcourses"},
+		{FileTypeUnknown, "unknown"},
 		{FileType(99), "unknown"}, // Unknown file type
 	}
 
@@ -25,6 +27,62 @@ func TestFileTypeString(t *testing.T) {
 	}
 }
 
+func TestParseFileType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected FileType
+	}{
+		{"courses", FileTypeCourses},
+		{"Equivalencies", FileTypeEquivalencies},
+		{"STUDENTS", FileTypeStudents},
+		{"studentcourses", FileTypeStudentCourses},
+		{"Student Courses", FileTypeStudentCourses},
+		{"student_courses", FileTypeStudentCourses},
+	}
+
+	for _, tc := range testCases {
+		result, err := ParseFileType(tc.name)
+		if err != nil {
+			t.Errorf("ParseFileType(%q) returned error: %v", tc.name, err)
+			continue
+		}
+		if result != tc.expected {
+			t.Errorf("ParseFileType(%q) returned %s, expected %s", tc.name, result, tc.expected)
+		}
+	}
+
+	if _, err := ParseFileType("grades"); err == nil {
+		t.Errorf("ParseFileType(\"grades\") should return an error")
+	}
+}
+
+func TestFileTypeJSON(t *testing.T) {
+	data, err := json.Marshal(UploadFile{Type: FileTypeStudentCourses, FilePath: "sc.csv"})
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	if string(data) != `{"Type":"studentcourses","FilePath":"sc.csv"}` {
+		t.Errorf("Unexpected JSON: %s", data)
+	}
+
+	var file UploadFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+	if file.Type != FileTypeStudentCourses {
+		t.Errorf("Expected studentcourses, got %s", file.Type)
+	}
+
+	if err := json.Unmarshal([]byte(`{"Type":"grades"}`), &file); err == nil {
+		t.Error("Expected an error for an unknown file type")
+	}
+
+	// FileTypeUnknown survives a round trip
+	if err := json.Unmarshal([]byte(`{"Type":"unknown"}`), &file); err != nil || file.Type != FileTypeUnknown {
+		t.Errorf("Expected FileTypeUnknown, got %d, %v", file.Type, err)
+	}
+}
+
 func TestUploadFile(t *testing.T) {
 	// Test UploadFile struct
 	file := UploadFile{
@@ -90,3 +148,24 @@ func TestUploadResponse(t *testing.T) {
 		t.Errorf("UploadResponse.Code should be 200, got %d", response.Code)
 	}
 }
+
+func TestUploadResponseJSON(t *testing.T) {
+	response := UploadResponse{
+		Success:         true,
+		Code:            200,
+		ReferenceID:     "BATCH-1",
+		RecordsAccepted: 2,
+		Files:           []FileResult{{Type: FileTypeStudents, FileName: "students.csv", Success: true, RecordsAccepted: 2}},
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	// The field names are part of the CLI's documented output
+	expected := `{"success":true,"message":"","code":200,"referenceId":"BATCH-1","recordsAccepted":2,"recordsRejected":0,` +
+		`"files":[{"type":"students","fileName":"students.csv","success":true,"recordsAccepted":2,"recordsRejected":0,"errors":null}],"errors":null}`
+	if string(data) != expected {
+		t.Errorf("Unexpected JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+}
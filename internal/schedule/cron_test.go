@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// Wednesday, March 13, 2024
+	from := time.Date(2024, 3, 13, 14, 15, 30, 0, time.UTC)
+
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 13, 14, 16, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 3, 14, 2, 30, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2024, 3, 13, 14, 20, 0, 0, time.UTC)},
+		{"5/15 14 * * *", time.Date(2024, 3, 13, 14, 20, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2024, 3, 13, 15, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2024, 3, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 6 1 jan-jun *", time.Date(2024, 4, 1, 6, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches
+		{"0 0 20 * fri", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		// A day field starting with "*" is unrestricted: both must match
+		{"0 0 */2 * 1", time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		cron, err := Parse(tc.spec)
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", tc.spec, err)
+			continue
+		}
+		if next := cron.Next(from); !next.Equal(tc.expected) {
+			t.Errorf("%s: expected %s, got %s", tc.spec, tc.expected, next)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"60 * * * *", "minute: 60 is outside 0-59"},
+		{"* * * foo *", `month: "foo" is not a number`},
+		{"*/0 * * * *", "invalid step"},
+		{"0 17-9 * * *", "backwards"},
+		{"0 0 31 feb *", "never runs"},
+		{"@often", "expected 5 fields"},
+	}
+
+	for _, tc := range testCases {
+		_, err := Parse(tc.spec)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.spec, tc.expected, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LockFileName is the lock file held while a scheduled upload runs
+const LockFileName = "schedule.lock"
+
+// ErrLocked is returned when another scheduled upload holds the lock
+var ErrLocked = errors.New("another scheduled upload is running")
+
+// Lock is an advisory lock on a file, which holds the ID of the process
+// holding it for messages
+type Lock struct {
+	f *os.File
+}
+
+// AcquireLock locks the file at path, creating it if needed. It returns
+// ErrLocked if another process, or another Lock in this one, holds it. The
+// operating system releases the lock when its holder exits, so a lock is
+// never left behind by a crashed run.
+func AcquireLock(path string) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		holder, _ := io.ReadAll(f)
+		f.Close()
+		if errors.Is(err, errWouldBlock) {
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				return nil, fmt.Errorf("%w (process %s holds %s)", ErrLocked, pid, path)
+			}
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	// Record the holder; failing to do so does not weaken the lock
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release unlocks the lock file. The file is kept, since removing it would let
+// a process that opened it before the removal lock it alongside one that
+// creates it afresh.
+func (l *Lock) Release() error {
+	l.f.Truncate(0)
+	unlockErr := unlockFile(l.f)
+	if err := l.f.Close(); err != nil && unlockErr == nil {
+		unlockErr = err
+	}
+	if unlockErr != nil {
+		return fmt.Errorf("failed to release lock file: %w", unlockErr)
+	}
+	return nil
+}
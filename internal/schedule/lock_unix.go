@@ -0,0 +1,21 @@
+//go:build !windows
+
+package schedule
+
+import (
+	"os"
+	"syscall"
+)
+
+// errWouldBlock is returned by lockFile when another holder has the lock
+var errWouldBlock = syscall.EWOULDBLOCK
+
+// lockFile takes an exclusive flock on f without waiting
+func lockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+}
+
+// unlockFile releases the flock on f
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package schedule
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// errWouldBlock is returned by lockFile when another holder has the lock
+var errWouldBlock = windows.ERROR_LOCK_VIOLATION
+
+// lockFile takes an exclusive lock on the first byte of f without waiting
+func lockFile(f *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+}
+
+// unlockFile releases the lock on f
+func unlockFile(f *os.File) error {
+	var overlapped windows.Overlapped
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &overlapped)
+}
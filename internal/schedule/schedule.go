@@ -0,0 +1,231 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+// StateFileName records when each schedule last ran, for catching up on missed runs
+const StateFileName = "schedule.json"
+
+// Job is a schedule from the configuration with its parsed cron expression
+type Job struct {
+	config.Schedule
+	Cron *Cron
+}
+
+// Jobs parses and checks the configured schedules
+func Jobs(schedules []config.Schedule) ([]Job, error) {
+	jobs := make([]Job, 0, len(schedules))
+	seen := map[string]bool{}
+	for i, s := range schedules {
+		if s.Name == "" {
+			return nil, fmt.Errorf("schedule %d has no name", i+1)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("schedule %s is defined more than once", s.Name)
+		}
+		seen[s.Name] = true
+
+		if (s.Manifest == "") == (len(s.Files) == 0) {
+			return nil, fmt.Errorf("schedule %s must have either a manifest or files", s.Name)
+		}
+		cron, err := Parse(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", s.Name, err)
+		}
+		jobs = append(jobs, Job{Schedule: s, Cron: cron})
+	}
+	return jobs, nil
+}
+
+// RunFunc runs the upload of a schedule
+type RunFunc func(ctx context.Context, schedule config.Schedule) error
+
+// Scheduler runs jobs at their scheduled times, one at a time
+type Scheduler struct {
+	jobs   []Job
+	run    RunFunc
+	dir    string
+	logger *logger.Logger
+
+	// next holds the next run time of each job, by name
+	next map[string]time.Time
+	// now returns the current time; tests replace it
+	now func() time.Time
+}
+
+// New creates a scheduler for jobs that keeps its lock and state files in dir
+func New(jobs []Job, run RunFunc, dir string, logger *logger.Logger) *Scheduler {
+	return &Scheduler{
+		jobs:   jobs,
+		run:    run,
+		dir:    dir,
+		logger: logger,
+		next:   map[string]time.Time{},
+		now:    time.Now,
+	}
+}
+
+// Run runs the jobs at their scheduled times until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.jobs) == 0 {
+		return fmt.Errorf("no schedules are configured")
+	}
+
+	if err := s.plan(); err != nil {
+		return err
+	}
+
+	for {
+		// Run the jobs that are due, then sleep until the next one
+		s.runDue(ctx)
+		if ctx.Err() != nil {
+			s.logger.Info("Scheduler stopped")
+			return nil
+		}
+
+		wake := s.nextWake()
+		s.logger.Debug("Next scheduled upload at %s", wake.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.logger.Info("Scheduler stopped")
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// plan sets each job's first run time. A job that catches up and missed a run
+// since it last ran is due immediately; several missed runs are caught up once.
+func (s *Scheduler) plan() error {
+	state, err := LoadState(s.dir)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	for _, job := range s.jobs {
+		if last, ok := state[job.Name]; ok && job.CatchUp {
+			if missed := job.Cron.Next(last); !missed.After(now) {
+				s.logger.Info("Schedule %s missed its run at %s; running it now", job.Name, missed.Format(time.RFC3339))
+				s.next[job.Name] = now
+				continue
+			}
+		}
+		s.next[job.Name] = job.Cron.Next(now)
+		s.logger.Info("Schedule %s (%s) next runs at %s", job.Name, job.Cron, s.next[job.Name].Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runDue runs the jobs whose time has come, in configuration order
+func (s *Scheduler) runDue(ctx context.Context) {
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if s.next[job.Name].After(s.now()) {
+			continue
+		}
+		s.runJob(ctx, job)
+		// Runs missed while this one ran are skipped
+		s.next[job.Name] = job.Cron.Next(s.now())
+	}
+}
+
+// nextWake returns the earliest next run time
+func (s *Scheduler) nextWake() time.Time {
+	var wake time.Time
+	for _, t := range s.next {
+		if wake.IsZero() || t.Before(wake) {
+			wake = t
+		}
+	}
+	return wake
+}
+
+// runJob runs one job under the lock file and logs the outcome
+func (s *Scheduler) runJob(ctx context.Context, job Job) {
+	lock, err := AcquireLock(filepath.Join(s.dir, LockFileName))
+	if errors.Is(err, ErrLocked) {
+		s.logger.Warning("Skipping schedule %s: %v", job.Name, err)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Schedule %s failed: %v", job.Name, err)
+		return
+	}
+	defer func() {
+		if err := lock.Release(); err != nil {
+			s.logger.Warning("%v", err)
+		}
+	}()
+
+	start := s.now()
+	s.logger.Info("Running schedule %s", job.Name)
+	err = s.run(ctx, job.Schedule)
+	duration := s.now().Sub(start).Round(time.Millisecond)
+	if err != nil {
+		s.logger.Error("Schedule %s failed after %s: %v", job.Name, duration, err)
+	} else {
+		s.logger.Info("Schedule %s succeeded in %s", job.Name, duration)
+	}
+
+	// An interrupted run counts as missed so it is caught up
+	if ctx.Err() != nil {
+		return
+	}
+	if err := SaveRun(s.dir, job.Name, start); err != nil {
+		s.logger.Warning("Failed to record the run of schedule %s: %v", job.Name, err)
+	}
+}
+
+// LoadState returns the time each schedule last ran, by name
+func LoadState(dir string) (map[string]time.Time, error) {
+	state := map[string]time.Time{}
+	data, err := os.ReadFile(filepath.Join(dir, StateFileName))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule state %s: %w", filepath.Join(dir, StateFileName), err)
+	}
+	return state, nil
+}
+
+// SaveRun records that a schedule ran at t
+func SaveRun(dir, name string, t time.Time) error {
+	state, err := LoadState(dir)
+	if err != nil {
+		return err
+	}
+	state[name] = t
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedule state: %w", err)
+	}
+	path := filepath.Join(dir, StateFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write schedule state: %w", err)
+	}
+	return nil
+}
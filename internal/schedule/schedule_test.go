@@ -0,0 +1,178 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+func TestJobs(t *testing.T) {
+	files := map[string]string{"courses": "courses.csv"}
+	testCases := []struct {
+		name      string
+		schedules []config.Schedule
+		expected  string
+	}{
+		{"no name", []config.Schedule{{Cron: "@daily", Files: files}}, "has no name"},
+		{"duplicate", []config.Schedule{{Name: "a", Cron: "@daily", Files: files}, {Name: "a", Cron: "@hourly", Files: files}}, "more than once"},
+		{"no target", []config.Schedule{{Name: "a", Cron: "@daily"}}, "either a manifest or files"},
+		{"both targets", []config.Schedule{{Name: "a", Cron: "@daily", Manifest: "job.yaml", Files: files}}, "either a manifest or files"},
+		{"cron", []config.Schedule{{Name: "a", Cron: "daily", Files: files}}, "schedule a: invalid cron expression"},
+	}
+
+	for _, tc := range testCases {
+		_, err := Jobs(tc.schedules)
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.expected, err)
+		}
+	}
+
+	jobs, err := Jobs([]config.Schedule{{Name: "nightly", Cron: "30 2 * * *", Manifest: "nightly.yaml"}})
+	if err != nil || len(jobs) != 1 || jobs[0].Cron.String() != "30 2 * * *" {
+		t.Errorf("Expected one job, got %v, %v", jobs, err)
+	}
+}
+
+// newTestScheduler creates a scheduler whose clock is set to now
+func newTestScheduler(t *testing.T, schedules []config.Schedule, run RunFunc, now time.Time) *Scheduler {
+	t.Helper()
+	log, err := logger.New(filepath.Join(t.TempDir(), "test.log"), logger.LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	jobs, err := Jobs(schedules)
+	if err != nil {
+		t.Fatalf("Failed to parse schedules: %v", err)
+	}
+	s := New(jobs, run, t.TempDir(), log)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestCatchUp(t *testing.T) {
+	now := time.Date(2024, 3, 13, 9, 0, 0, 0, time.Local)
+	files := map[string]string{"courses": "courses.csv"}
+	schedules := []config.Schedule{
+		{Name: "missed", Cron: "30 2 * * *", Files: files, CatchUp: true},
+		{Name: "on time", Cron: "30 2 * * *", Files: files, CatchUp: true},
+		{Name: "no catch up", Cron: "30 2 * * *", Files: files},
+		{Name: "never ran", Cron: "30 2 * * *", Files: files, CatchUp: true},
+	}
+
+	var ran []string
+	s := newTestScheduler(t, schedules, func(ctx context.Context, schedule config.Schedule) error {
+		ran = append(ran, schedule.Name)
+		return nil
+	}, now)
+
+	// Two days of runs were missed, and one schedule ran this morning
+	for name, last := range map[string]time.Time{
+		"missed":      now.AddDate(0, 0, -2),
+		"on time":     time.Date(2024, 3, 13, 2, 30, 0, 0, time.Local),
+		"no catch up": now.AddDate(0, 0, -2),
+	} {
+		if err := SaveRun(s.dir, name, last); err != nil {
+			t.Fatalf("Failed to save state: %v", err)
+		}
+	}
+
+	if err := s.plan(); err != nil {
+		t.Fatalf("Failed to plan: %v", err)
+	}
+	tomorrow := time.Date(2024, 3, 14, 2, 30, 0, 0, time.Local)
+	if !s.next["missed"].Equal(now) {
+		t.Errorf("Expected the missed schedule to run now, got %s", s.next["missed"])
+	}
+	for _, name := range []string{"on time", "no catch up", "never ran"} {
+		if !s.next[name].Equal(tomorrow) {
+			t.Errorf("Expected %s to run at %s, got %s", name, tomorrow, s.next[name])
+		}
+	}
+
+	// The missed run happens once and is recorded
+	s.runDue(context.Background())
+	if len(ran) != 1 || ran[0] != "missed" {
+		t.Errorf("Expected only the missed schedule to run, got %v", ran)
+	}
+	if !s.next["missed"].Equal(tomorrow) {
+		t.Errorf("Expected the missed schedule to run next at %s, got %s", tomorrow, s.next["missed"])
+	}
+	state, err := LoadState(s.dir)
+	if err != nil || !state["missed"].Equal(now) {
+		t.Errorf("Expected the run to be recorded at %s, got %v, %v", now, state["missed"], err)
+	}
+}
+
+func TestRunJobLocked(t *testing.T) {
+	now := time.Date(2024, 3, 13, 9, 0, 0, 0, time.Local)
+	schedules := []config.Schedule{{Name: "nightly", Cron: "@daily", Manifest: "nightly.yaml"}}
+
+	runs := 0
+	var s *Scheduler
+	s = newTestScheduler(t, schedules, func(ctx context.Context, schedule config.Schedule) error {
+		runs++
+		if _, err := os.Stat(filepath.Join(s.dir, LockFileName)); err != nil {
+			t.Errorf("Expected the lock to be held during the run: %v", err)
+		}
+		return errors.New("upload failed")
+	}, now)
+
+	// Another running process holds the lock
+	lock, err := AcquireLock(filepath.Join(s.dir, LockFileName))
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	if _, err := AcquireLock(filepath.Join(s.dir, LockFileName)); !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), fmt.Sprintf("process %d", os.Getpid())) {
+		t.Errorf("Expected ErrLocked naming this process, got %v", err)
+	}
+	s.runJob(context.Background(), s.jobs[0])
+	if runs != 0 {
+		t.Errorf("Expected the run to be skipped while locked, got %d runs", runs)
+	}
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+
+	// A failed run is still recorded and releases the lock
+	s.runJob(context.Background(), s.jobs[0])
+	if runs != 1 {
+		t.Errorf("Expected one run, got %d", runs)
+	}
+	lock, err = AcquireLock(filepath.Join(s.dir, LockFileName))
+	if err != nil {
+		t.Errorf("Expected the lock to be released, got %v", err)
+	} else {
+		lock.Release()
+	}
+	if state, _ := LoadState(s.dir); !state["nightly"].Equal(now) {
+		t.Errorf("Expected the run to be recorded, got %v", state)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+
+	// A lock file left by a process that is no longer running is not locked
+	if err := os.WriteFile(path, []byte("999999999\n"), 0644); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	lock, err := AcquireLock(path)
+	if err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected the lock file to hold this process ID, got %q", data)
+	}
+	lock.Release()
+}
@@ -0,0 +1,337 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+)
+
+// FileName is the name of the secrets file in the config directory
+const FileName = "secrets.json"
+
+// KeyFileName is the name of the file holding the encryption key of a
+// secrets file with no passphrase, next to the secrets file
+const KeyFileName = "secrets.key"
+
+// Key derivation methods of a secrets file
+const (
+	// KDFScrypt derives the key from a passphrase with scrypt
+	KDFScrypt = "scrypt"
+	// KDFKeyFile reads a random key from KeyFileName, so the secrets are
+	// unreadable without it but need no passphrase
+	KDFKeyFile = "keyfile"
+)
+
+// scrypt parameters recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keySize = 32
+)
+
+// checkValue is encrypted with the key so a wrong passphrase is detected
+// before any secret is read
+var checkValue = []byte("trtc-go secrets")
+
+// secretsFile is the format of the secrets file
+type secretsFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt,omitempty"`
+	Check   []byte `json:"check"`
+	// Secrets maps names to their values, encrypted with AES-256-GCM and prefixed with the nonce
+	Secrets map[string][]byte `json:"secrets"`
+}
+
+// FileStore is a Store kept in a JSON file with every value encrypted. The
+// key is derived from a passphrase, if one is given when the file is
+// created, or else kept in a separate key file readable only by the owner.
+// Removing the last secret removes the files, so the next one may choose
+// differently.
+type FileStore struct {
+	path       string
+	passphrase string
+	mu         sync.Mutex
+}
+
+// NewFileStore creates a store kept in the file at path, using passphrase if
+// the file is protected by one
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+// OpenFileStore returns the store in the config directory, using the
+// passphrase in TRTC_PASSPHRASE if set
+func OpenFileStore() (*FileStore, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(filepath.Join(dir, FileName), os.Getenv(PassphraseEnv)), nil
+}
+
+// Path returns the location of the secrets file
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// SetPassphrase sets the passphrase used to read and write the secrets
+func (s *FileStore) SetPassphrase(passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passphrase = passphrase
+}
+
+// Protected reports whether the secrets are protected by a passphrase
+func (s *FileStore) Protected() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.read()
+	if err != nil || f == nil {
+		return false, err
+	}
+	return f.KDF == KDFScrypt, nil
+}
+
+// Get returns the secret stored under name
+func (s *FileStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	if f == nil || f.Secrets[name] == nil {
+		return "", ErrNotFound
+	}
+	aead, err := s.cipher(f)
+	if err != nil {
+		return "", err
+	}
+	value, err := open(aead, f.Secrets[name], name)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret %s: %w", name, err)
+	}
+	return string(value), nil
+}
+
+// Has reports whether a secret is stored under name
+func (s *FileStore) Has(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read()
+	if err != nil || f == nil {
+		return false, err
+	}
+	return f.Secrets[name] != nil, nil
+}
+
+// Set stores a secret under name. A new file is protected by the passphrase
+// if there is one.
+func (s *FileStore) Set(name, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read()
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		if f, err = s.create(); err != nil {
+			return err
+		}
+	}
+	aead, err := s.cipher(f)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(aead, []byte(value), name)
+	if err != nil {
+		return err
+	}
+	f.Secrets[name] = sealed
+	return s.write(f)
+}
+
+// Delete removes the secret stored under name
+func (s *FileStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read()
+	if err != nil || f == nil || f.Secrets[name] == nil {
+		return err
+	}
+	delete(f.Secrets, name)
+	if len(f.Secrets) > 0 {
+		return s.write(f)
+	}
+
+	if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to remove secrets file: %w", err)
+	}
+	if err := os.Remove(s.keyPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove secrets key file: %w", err)
+	}
+	return nil
+}
+
+// keyPath returns the location of the key file
+func (s *FileStore) keyPath() string {
+	return filepath.Join(filepath.Dir(s.path), KeyFileName)
+}
+
+// read loads the secrets file, returning nil if it does not exist
+func (s *FileStore) read() (*secretsFile, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	var f secretsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file %s: %w", s.path, err)
+	}
+	if f.Secrets == nil {
+		f.Secrets = map[string][]byte{}
+	}
+	return &f, nil
+}
+
+// write saves the secrets file, readable only by the owner
+func (s *FileStore) write(f *secretsFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write a temporary file and rename it so a crash never leaves a partial file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// create starts a new secrets file, protected by the passphrase if there is one
+func (s *FileStore) create() (*secretsFile, error) {
+	f := &secretsFile{Version: 1, KDF: KDFKeyFile, Secrets: map[string][]byte{}}
+	if s.passphrase != "" {
+		f.KDF = KDFScrypt
+		f.Salt = make([]byte, 16)
+		if _, err := rand.Read(f.Salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	} else {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %w", err)
+		}
+		if err := os.WriteFile(s.keyPath(), key, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write secrets key file: %w", err)
+		}
+	}
+
+	aead, err := s.key(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Check, err = seal(aead, checkValue, ""); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// cipher returns the cipher for a secrets file after checking the key
+func (s *FileStore) cipher(f *secretsFile) (cipher.AEAD, error) {
+	aead, err := s.key(f)
+	if err != nil {
+		return nil, err
+	}
+	check, err := open(aead, f.Check, "")
+	if err != nil || !bytes.Equal(check, checkValue) {
+		if f.KDF == KDFScrypt {
+			return nil, ErrWrongPassphrase
+		}
+		return nil, fmt.Errorf("%s does not match %s", s.keyPath(), s.path)
+	}
+	return aead, nil
+}
+
+// key returns the cipher for the key of a secrets file
+func (s *FileStore) key(f *secretsFile) (cipher.AEAD, error) {
+	var key []byte
+	switch f.KDF {
+	case KDFScrypt:
+		if s.passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		var err error
+		key, err = scrypt.Key([]byte(s.passphrase), f.Salt, scryptN, scryptR, scryptP, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+	case KDFKeyFile:
+		var err error
+		key, err = os.ReadFile(s.keyPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secrets key file: %w", err)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("secrets key file %s is corrupt", s.keyPath())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported key derivation %q in %s", f.KDF, s.path)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts a value, binding it to its name so values cannot be swapped
+func seal(aead cipher.AEAD, value []byte, name string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+// open decrypts a value sealed under name
+func open(aead cipher.AEAD, sealed []byte, name string) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("value is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(name))
+}
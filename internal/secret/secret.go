@@ -0,0 +1,82 @@
+package secret
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+)
+
+// ErrNotFound is returned when no secret is stored under a name
+var ErrNotFound = errors.New("secret not found")
+
+// ErrPassphraseRequired is returned when the secrets are protected by a
+// passphrase and none was given
+var ErrPassphraseRequired = errors.New("the stored secrets are protected by a passphrase")
+
+// ErrWrongPassphrase is returned when the passphrase does not decrypt the secrets
+var ErrWrongPassphrase = errors.New("wrong passphrase for the stored secrets")
+
+// PassphraseEnv is the environment variable holding the passphrase for
+// unattended runs
+const PassphraseEnv = config.EnvPrefix + "_PASSPHRASE"
+
+// Store keeps secrets, such as the API key of each profile, by name
+type Store interface {
+	// Get returns the secret stored under name, or ErrNotFound
+	Get(name string) (string, error)
+	// Has reports whether a secret is stored under name, without decrypting it
+	Has(name string) (bool, error)
+	// Set stores a secret under name, replacing any already there
+	Set(name, value string) error
+	// Delete removes the secret stored under name, if any
+	Delete(name string) error
+}
+
+// APIKey names the API key of a profile in a store
+func APIKey(profile string) string {
+	if profile == "" {
+		profile = config.DefaultProfile
+	}
+	return "api_key/" + profile
+}
+
+// LookupAPIKey returns the API key to use when none is given on the command
+// line, with where it came from. A key in the environment comes first, then
+// the stored key of the profile in use, then a key in the config file, then
+// the stored key of the default profile. It returns ErrNotFound if there is
+// no key at all.
+func LookupAPIKey(c *config.Config, s Store) (string, string, error) {
+	if strings.HasPrefix(c.Source("api_key"), config.SourceEnv) && c.APIKey != "" {
+		return c.APIKey, c.Source("api_key"), nil
+	}
+
+	names := []string{APIKey(c.CurrentProfile())}
+	if c.CurrentProfile() != config.DefaultProfile {
+		names = append(names, APIKey(config.DefaultProfile))
+	}
+
+	key, err := s.Get(names[0])
+	if err == nil {
+		return key, SourceStore, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", "", err
+	}
+	if c.APIKey != "" {
+		return c.APIKey, c.Source("api_key"), nil
+	}
+	for _, name := range names[1:] {
+		key, err := s.Get(name)
+		if err == nil {
+			return key, SourceStore, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return "", "", err
+		}
+	}
+	return "", "", ErrNotFound
+}
+
+// SourceStore describes keys that come from a store, as config.Config.Source does
+const SourceStore = "secret store"
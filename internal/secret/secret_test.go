@@ -0,0 +1,134 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/config"
+)
+
+func TestFileStoreKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	s := NewFileStore(path, "")
+
+	if _, err := s.Get("api_key/default"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound from an empty store, got %v", err)
+	}
+	if err := s.Set("api_key/default", "production-key"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := s.Set("api_key/test", "test-key"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+
+	// The value is encrypted and the files are private
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secrets file: %v", err)
+	}
+	if strings.Contains(string(data), "production-key") {
+		t.Errorf("Expected the secret to be encrypted:\n%s", data)
+	}
+	for _, p := range []string{path, filepath.Join(dir, KeyFileName)} {
+		if info, err := os.Stat(p); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("Expected %s to be readable only by the owner, got %v (%v)", p, info.Mode(), err)
+		}
+	}
+
+	// A new store on the same file reads the secrets without a passphrase
+	reopened := NewFileStore(path, "")
+	if value, err := reopened.Get("api_key/default"); err != nil || value != "production-key" {
+		t.Errorf("Expected production-key, got %q (%v)", value, err)
+	}
+	if ok, err := reopened.Has("api_key/test"); err != nil || !ok {
+		t.Errorf("Expected the test key to be stored, got %t (%v)", ok, err)
+	}
+
+	// Deleting the last secret removes the files
+	for _, name := range []string{"api_key/default", "api_key/test"} {
+		if err := reopened.Delete(name); err != nil {
+			t.Fatalf("Failed to delete secret: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, KeyFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the key file to be removed, got %v", err)
+	}
+}
+
+func TestFileStorePassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := NewFileStore(path, "correct horse").Set("api_key/default", "production-key"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), KeyFileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected no key file for a passphrase-protected store, got %v", err)
+	}
+
+	s := NewFileStore(path, "")
+	if protected, err := s.Protected(); err != nil || !protected {
+		t.Errorf("Expected the store to be protected, got %t (%v)", protected, err)
+	}
+	if _, err := s.Get("api_key/default"); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Expected ErrPassphraseRequired, got %v", err)
+	}
+	s.SetPassphrase("wrong")
+	if _, err := s.Get("api_key/default"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Expected ErrWrongPassphrase, got %v", err)
+	}
+	s.SetPassphrase("correct horse")
+	if value, err := s.Get("api_key/default"); err != nil || value != "production-key" {
+		t.Errorf("Expected production-key, got %q (%v)", value, err)
+	}
+}
+
+func TestLookupAPIKey(t *testing.T) {
+	s := NewFileStore(filepath.Join(t.TempDir(), FileName), "")
+	c := config.DefaultConfig()
+	if err := c.AddProfile("test", config.Profile{}); err != nil {
+		t.Fatalf("Failed to add profile: %v", err)
+	}
+
+	if _, _, err := LookupAPIKey(c, s); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound with no key anywhere, got %v", err)
+	}
+
+	// A key in the config file is used, but the profile's stored key comes first
+	c.APIKey = "plaintext-key"
+	if key, source, err := LookupAPIKey(c, s); err != nil || key != "plaintext-key" || source != config.SourceDefault {
+		t.Errorf("Expected the key from the config, got %q from %q (%v)", key, source, err)
+	}
+	if err := s.Set(APIKey(config.DefaultProfile), "stored-key"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if key, source, err := LookupAPIKey(c, s); err != nil || key != "stored-key" || source != SourceStore {
+		t.Errorf("Expected the stored key, got %q from %q (%v)", key, source, err)
+	}
+
+	// Other profiles fall back to the default profile's stored key
+	c.APIKey = ""
+	if err := c.UseProfile("test"); err != nil {
+		t.Fatalf("Failed to use profile: %v", err)
+	}
+	if key, _, err := LookupAPIKey(c, s); err != nil || key != "stored-key" {
+		t.Errorf("Expected the default profile's stored key, got %q (%v)", key, err)
+	}
+	if err := s.Set(APIKey("test"), "test-key"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if key, _, err := LookupAPIKey(c, s); err != nil || key != "test-key" {
+		t.Errorf("Expected the test profile's stored key, got %q (%v)", key, err)
+	}
+
+	// The environment overrides every stored key
+	t.Setenv("TRTC_API_KEY", "env-key")
+	if err := c.ApplyEnv(); err != nil {
+		t.Fatalf("Failed to apply environment: %v", err)
+	}
+	if key, source, err := LookupAPIKey(c, s); err != nil || key != "env-key" || source != "env TRTC_API_KEY" {
+		t.Errorf("Expected the key from the environment, got %q from %q (%v)", key, source, err)
+	}
+}
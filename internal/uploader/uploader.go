@@ -1,31 +1,68 @@
 package uploader
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/chatt-state/trtc-go/internal/api"
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/excel"
+	"github.com/chatt-state/trtc-go/internal/history"
 	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/validation"
 	"github.com/chatt-state/trtc-go/pkg/logger"
 )
 
+// ErrUnchanged is returned when every file matches the last successful upload of its type, so nothing is sent
+var ErrUnchanged = errors.New("all files are unchanged since the last successful upload")
+
 // Uploader handles file uploads to the TRTC API
 type Uploader struct {
-	client api.APIClient
-	config *config.Config
-	logger *logger.Logger
+	client     api.APIClient
+	config     *config.Config
+	logger     *logger.Logger
+	timeout    time.Duration
+	onProgress models.ProgressFunc
+	sheet      string
+	journal    *history.Journal
+	force      bool
+	snapshots  *delta.Store
+	delta      bool
 }
 
 // New creates a new uploader
-func New(config *config.Config, logger *logger.Logger) *Uploader {
-	client := api.NewClient(config.APIEndpoint, config.IgnoreCertError, logger)
+func New(config *config.Config, logger *logger.Logger) (*Uploader, error) {
+	client, err := api.NewClientWithTLS(config.APIEndpoint, api.TLSOptions{
+		InsecureSkipVerify: config.IgnoreCertError,
+		CAFile:             config.CACertFile,
+		CertFile:           config.ClientCertFile,
+		KeyFile:            config.ClientKeyFile,
+		MinVersion:         config.MinTLSVersion,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	client.SetRetryPolicy(api.RetryPolicy{
+		MaxAttempts:          config.RetryMaxAttempts,
+		BaseDelay:            config.RetryBaseDelay,
+		MaxDelay:             config.RetryMaxDelay,
+		Jitter:               config.RetryJitter,
+		RetryableStatusCodes: config.RetryStatusCodes,
+		RetryNetworkErrors:   config.RetryNetworkErrors,
+		RetryAfterSend:       config.RetryAfterSend,
+	})
+
 	return &Uploader{
 		client: client,
 		config: config,
 		logger: logger,
-	}
+	}, nil
 }
 
 // NewWithClient creates a new uploader with a custom API client
@@ -37,8 +74,55 @@ func NewWithClient(client api.APIClient, config *config.Config, logger *logger.L
 	}
 }
 
+// SetTimeout sets the deadline applied to each upload; zero uses the client default
+func (u *Uploader) SetTimeout(timeout time.Duration) {
+	u.timeout = timeout
+}
+
+// SetProgressFunc sets a callback that receives bytes sent per file and overall during uploads
+func (u *Uploader) SetProgressFunc(onProgress models.ProgressFunc) {
+	u.onProgress = onProgress
+}
+
+// SetSheet selects the worksheet, by name or 1-based index, converted from Excel workbooks.
+// When empty, a sheet named after the file type is used if present, otherwise the first sheet.
+func (u *Uploader) SetSheet(sheet string) {
+	u.sheet = sheet
+}
+
+// SetJournal sets the history journal that records each upload run; nil disables recording
+func (u *Uploader) SetJournal(journal *history.Journal) {
+	u.journal = journal
+}
+
+// SetForce controls whether files are uploaded even when they match the last
+// successful upload of their type to the same endpoint. Unchanged files are only
+// detected when a journal is set.
+func (u *Uploader) SetForce(force bool) {
+	u.force = force
+}
+
+// SetSnapshots sets the store that keeps a copy of the last successfully uploaded
+// file of each type, which delta uploads are compared against; nil disables snapshots
+func (u *Uploader) SetSnapshots(snapshots *delta.Store) {
+	u.snapshots = snapshots
+}
+
+// SetDelta controls whether only the rows added or changed since the last
+// successful upload are sent. Files without a snapshot are sent in full.
+func (u *Uploader) SetDelta(enabled bool) {
+	u.delta = enabled
+}
+
 // UploadFiles uploads files to the TRTC API
 func (u *Uploader) UploadFiles(apiKey string, files []models.UploadFile) (*models.UploadResponse, error) {
+	return u.UploadFilesWithContext(context.Background(), apiKey, files)
+}
+
+// UploadFilesWithContext uploads files to the TRTC API, aborting when ctx is cancelled.
+// The run uses the run ID carried by ctx, or a new one; its log records carry the
+// ID and, when a journal is set, its summary is recorded in the history under it.
+func (u *Uploader) UploadFilesWithContext(ctx context.Context, apiKey string, files []models.UploadFile) (*models.UploadResponse, error) {
 	// Validate API key
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key is required")
@@ -56,68 +140,461 @@ func (u *Uploader) UploadFiles(apiKey string, files []models.UploadFile) (*model
 		}
 	}
 
+	// Give the run an ID shared by its log records and history entry
+	runID := logger.RunID(ctx)
+	if runID == "" {
+		runID = history.NewID(time.Now())
+		ctx = logger.WithRunID(ctx, runID)
+	}
+	summary := &history.Entry{
+		ID:       runID,
+		Time:     time.Now(),
+		Endpoint: u.config.APIEndpoint,
+		Profile:  u.config.CurrentProfile(),
+		Files:    listFiles(files),
+		Timings:  &history.Timings{},
+	}
+
+	response, err := u.run(ctx, apiKey, files, summary)
+	u.finish(ctx, summary, response, err)
+	return response, err
+}
+
+// run converts, validates and uploads files, noting in summary the files sent
+// and how long each step took
+func (u *Uploader) run(ctx context.Context, apiKey string, files []models.UploadFile, summary *history.Entry) (*models.UploadResponse, error) {
+	// Convert Excel workbooks to the CSV that is actually sent
+	sources := files
+	step := time.Now()
+	files, cleanup, err := u.convertWorkbooks(ctx, files)
+	summary.Timings.Convert = time.Since(step)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// Validate file contents before sending anything
+	if u.config.ValidateFiles {
+		step = time.Now()
+		err := u.validate(ctx, files, sources)
+		summary.Timings.Validate = time.Since(step)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Describe the files before sending so the journal records exactly what was uploaded
+	described := describeFiles(files, sources, u.logger.WithContext(ctx))
+	summary.Files = described
+	if u.journal != nil && !u.force {
+		files, described = u.skipUnchanged(ctx, files, described)
+		if len(files) == 0 {
+			return nil, ErrUnchanged
+		}
+	}
+
+	// Send only the rows that changed since the last successful upload
+	full := files
+	if u.delta && u.snapshots != nil {
+		var cleanupDelta func()
+		files, described, cleanupDelta, err = u.deltaFiles(ctx, files, described)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanupDelta()
+		if len(files) == 0 {
+			return nil, ErrUnchanged
+		}
+	}
+	summary.Files = described
+
 	// Create upload request
 	request := models.UploadRequest{
-		APIKey: apiKey,
-		Files:  files,
+		APIKey:     apiKey,
+		Files:      files,
+		Timeout:    u.timeout,
+		OnProgress: u.onProgress,
 	}
 
 	// Upload files
-	return u.client.UploadFiles(request)
+	step = time.Now()
+	response, err := u.client.UploadFilesWithContext(ctx, request)
+	summary.Duration = time.Since(step)
+	summary.Timings.Upload = summary.Duration
+	if u.snapshots != nil {
+		u.updateSnapshots(ctx, full, files, response, err)
+	}
+	return response, err
 }
 
-// UploadFilesFromPaths uploads files to the TRTC API from file paths
-func (u *Uploader) UploadFilesFromPaths(apiKey string, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) (*models.UploadResponse, error) {
-	var files []models.UploadFile
+// listFiles describes files by type, path and size alone, for runs that end
+// before the files are read
+func listFiles(files []models.UploadFile) []history.File {
+	listed := make([]history.File, len(files))
+	for i, file := range files {
+		listed[i] = history.File{Type: file.Type, Path: file.FilePath}
+		if info, err := os.Stat(file.FilePath); err == nil {
+			listed[i].Size = info.Size()
+		}
+	}
+	return listed
+}
 
-	// Add courses file if provided
-	if coursesPath != "" {
-		absPath, err := filepath.Abs(coursesPath)
+// describeFiles computes the checksums of the files being sent, reporting them against their source paths
+func describeFiles(files, sources []models.UploadFile, logger *logger.Logger) []history.File {
+	described := make([]history.File, 0, len(files))
+	for i, file := range files {
+		f, err := history.Describe(file)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for courses file: %w", err)
+			logger.Warning("Failed to describe %s: %v", file.FilePath, err)
+			f = history.File{Type: file.Type}
 		}
-		files = append(files, models.UploadFile{
-			Type:     models.FileTypeCourses,
-			FilePath: absPath,
-		})
+		f.Path = sources[i].FilePath
+		described = append(described, f)
+	}
+	return described
+}
+
+// skipUnchanged drops the files whose checksum matches the last successful upload
+// of their type with the same profile and endpoint, returning the files that still need sending
+func (u *Uploader) skipUnchanged(ctx context.Context, files []models.UploadFile, described []history.File) ([]models.UploadFile, []history.File) {
+	log := u.logger.WithContext(ctx)
+	last, err := u.journal.LastSuccessful(u.config.CurrentProfile(), u.config.APIEndpoint)
+	if err != nil {
+		log.Warning("Failed to read upload history, uploading all files: %v", err)
+		return files, described
+	}
+
+	var keptFiles []models.UploadFile
+	var keptDescribed []history.File
+	for i, file := range files {
+		entry, ok := last[file.Type]
+		if ok && described[i].SHA256 != "" {
+			if previous, _ := entry.File(file.Type); previous.SHA256 == described[i].SHA256 {
+				log.Warning("Skipping %s: unchanged since upload %s on %s",
+					described[i].Path, entry.ID, entry.Time.Local().Format("2006-01-02 15:04"))
+				continue
+			}
+		}
+		keptFiles = append(keptFiles, file)
+		keptDescribed = append(keptDescribed, described[i])
+	}
+	return keptFiles, keptDescribed
+}
+
+// deltaFiles replaces each file that has a snapshot with a file of the rows added
+// or changed since, dropping files with no such rows. The returned cleanup
+// function removes the delta files.
+func (u *Uploader) deltaFiles(ctx context.Context, files []models.UploadFile, described []history.File) (send []models.UploadFile, info []history.File, cleanup func(), err error) {
+	log := u.logger.WithContext(ctx)
+	tempDir, err := os.MkdirTemp("", "trtc-go-delta-")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	cleanup = func() {
+		os.RemoveAll(tempDir)
 	}
 
-	// Add equivalencies file if provided
-	if equivalenciesPath != "" {
-		absPath, err := filepath.Abs(equivalenciesPath)
+	for i, file := range files {
+		snapshot, ok := u.snapshots.Snapshot(u.config.CurrentProfile(), u.config.APIEndpoint, file.Type)
+		if !ok {
+			log.Info("No snapshot of a previous %s upload, sending %s in full", file.Type.String(), described[i].Path)
+			send = append(send, file)
+			info = append(info, described[i])
+			continue
+		}
+
+		result, err := delta.Diff(file.Type, snapshot, file.FilePath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for equivalencies file: %w", err)
+			log.Warning("Failed to compare %s with the last upload, sending it in full: %v", described[i].Path, err)
+			send = append(send, file)
+			info = append(info, described[i])
+			continue
+		}
+		if len(result.Removed) > 0 {
+			log.Warning("%s: %d row(s) from the last upload are missing; a delta cannot remove them from TRTC", described[i].Path, len(result.Removed))
+		}
+		if result.Rows() == 0 {
+			log.Info("Skipping %s: no rows added or changed since the last upload", described[i].Path)
+			continue
+		}
+
+		dir := filepath.Join(tempDir, file.Type.String())
+		if err := os.Mkdir(dir, 0700); err != nil {
+			cleanup()
+			return nil, nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		path := filepath.Join(dir, filepath.Base(file.FilePath))
+		if err := result.WriteDeltaFile(path); err != nil {
+			cleanup()
+			return nil, nil, nil, err
+		}
+		log.Info("Sending %d of %d rows of %s (%d added, %d changed)", result.Rows(), described[i].Rows, described[i].Path, len(result.Added), len(result.Changed))
+
+		send = append(send, models.UploadFile{Type: file.Type, FilePath: path})
+		f := described[i]
+		f.DeltaRows = result.Rows()
+		info = append(info, f)
+	}
+
+	return send, info, cleanup, nil
+}
+
+// updateSnapshots keeps the snapshots in step with what the server holds. After a
+// clean upload every file becomes the new snapshot of its type; otherwise the
+// snapshots of the files sent are dropped so the next delta upload sends them in full.
+func (u *Uploader) updateSnapshots(ctx context.Context, full, sent []models.UploadFile, response *models.UploadResponse, err error) {
+	log := u.logger.WithContext(ctx)
+	if err == nil && response != nil && response.Success && response.RecordsRejected == 0 {
+		for _, file := range full {
+			if err := u.snapshots.Save(u.config.CurrentProfile(), u.config.APIEndpoint, file.Type, file.FilePath); err != nil {
+				log.Warning("Failed to save the %s snapshot: %v", file.Type.String(), err)
+			}
+		}
+		return
+	}
+
+	for _, file := range sent {
+		if err := u.snapshots.Remove(u.config.CurrentProfile(), u.config.APIEndpoint, file.Type); err != nil {
+			log.Warning("Failed to remove the %s snapshot: %v", file.Type.String(), err)
 		}
-		files = append(files, models.UploadFile{
-			Type:     models.FileTypeEquivalencies,
-			FilePath: absPath,
-		})
 	}
+}
+
+// finish completes a run's summary with its outcome, writes it to the log as a
+// single record and appends it to the journal. A journal failure is logged
+// rather than returned so it never masks the upload result.
+func (u *Uploader) finish(ctx context.Context, summary *history.Entry, response *models.UploadResponse, err error) {
+	log := u.logger.WithContext(ctx)
+	summary.Timings.Total = time.Since(summary.Time)
+	summary.Status = history.StatusFailed
+	if response != nil {
+		summary.Code = response.Code
+		summary.Message = logger.Redact(response.Message)
+		summary.ReferenceID = response.ReferenceID
+		if response.Success && err == nil {
+			summary.Status = history.StatusSuccess
+		}
+	}
+	if err != nil {
+		summary.Error = logger.Redact(err.Error())
+		switch {
+		case errors.Is(err, ErrUnchanged):
+			summary.Status = history.StatusSkipped
+		case errors.Is(err, api.ErrCanceled):
+			summary.Status = history.StatusCancelled
+		}
+	}
+
+	args := []any{
+		"outcome", summary.Status,
+		"files", len(summary.Files),
+		"bytes", summary.Bytes(),
+		logger.KeyDuration, summary.Timings.Total,
+		"convert", summary.Timings.Convert,
+		"validate", summary.Timings.Validate,
+		"upload", summary.Timings.Upload,
+	}
+	if summary.Code != 0 {
+		args = append(args, logger.KeyStatus, summary.Code)
+	}
+	if summary.ReferenceID != "" {
+		args = append(args, "reference_id", summary.ReferenceID)
+	}
+	if summary.Error != "" {
+		args = append(args, logger.KeyError, summary.Error)
+	}
+	u.logger.Log(ctx, logger.LevelInfo, "Run finished", args...)
+
+	if u.journal == nil {
+		return
+	}
+	if err := u.journal.Append(summary); err != nil {
+		log.Warning("Failed to record upload history: %v", err)
+		return
+	}
+	log.Debug("Recorded run %s in %s", summary.ID, u.journal.Path())
+}
 
-	// Add students file if provided
-	if studentsPath != "" {
-		absPath, err := filepath.Abs(studentsPath)
+// UploadFilesFromPaths uploads files to the TRTC API from file paths
+func (u *Uploader) UploadFilesFromPaths(apiKey string, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) (*models.UploadResponse, error) {
+	return u.UploadFilesFromPathsWithContext(context.Background(), apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+}
+
+// UploadFilesFromPathsWithContext uploads files to the TRTC API from file paths, aborting when ctx is cancelled
+func (u *Uploader) UploadFilesFromPathsWithContext(ctx context.Context, apiKey string, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) (*models.UploadResponse, error) {
+	files, err := FilesFromPaths(coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Upload files
+	return u.UploadFilesWithContext(ctx, apiKey, files)
+}
+
+// convertWorkbooks converts Excel workbooks to CSV files in a temporary directory.
+// The returned cleanup function removes the converted files.
+func (u *Uploader) convertWorkbooks(ctx context.Context, files []models.UploadFile) (converted []models.UploadFile, cleanup func(), err error) {
+	log := u.logger.WithContext(ctx)
+	var tempDir string
+	defer func() {
+		if err != nil && tempDir != "" {
+			os.RemoveAll(tempDir)
+		}
+	}()
+
+	converted = make([]models.UploadFile, len(files))
+	copy(converted, files)
+
+	for i, file := range files {
+		if !excel.IsWorkbook(file.FilePath) {
+			continue
+		}
+
+		if tempDir == "" {
+			if tempDir, err = os.MkdirTemp("", "trtc-go-"); err != nil {
+				return nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+			}
+		}
+
+		sheet, err := u.sheetFor(file)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for students file: %w", err)
+			return nil, nil, err
 		}
-		files = append(files, models.UploadFile{
-			Type:     models.FileTypeStudents,
-			FilePath: absPath,
-		})
+
+		// Keep the workbook's base name so the server sees a familiar file name
+		dir := filepath.Join(tempDir, file.Type.String())
+		if err := os.Mkdir(dir, 0700); err != nil {
+			return nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file.FilePath), filepath.Ext(file.FilePath)) + ".csv"
+		dst := filepath.Join(dir, name)
+
+		result, err := excel.ConvertFile(file.FilePath, dst, excel.Options{Sheet: sheet})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert %s: %w", file.FilePath, err)
+		}
+		log.Info("Converted sheet %s of %s to CSV (%d rows)", result.Sheet, file.FilePath, result.Rows)
+
+		converted[i].FilePath = dst
+	}
+
+	cleanup = func() {
+		if tempDir != "" {
+			os.RemoveAll(tempDir)
+		}
+	}
+	return converted, cleanup, nil
+}
+
+// sheetFor returns the sheet to convert from a workbook
+func (u *Uploader) sheetFor(file models.UploadFile) (string, error) {
+	if u.sheet != "" {
+		return u.sheet, nil
+	}
+
+	sheets, err := excel.Sheets(file.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s: %w", file.FilePath, err)
+	}
+	for _, sheet := range sheets {
+		if ft, err := models.ParseFileType(sheet); err == nil && ft == file.Type {
+			return sheet, nil
+		}
+	}
+	return "", nil
+}
+
+// Validate checks files against their schemas and each other without uploading them, converting Excel workbooks first.
+// It returns a *validation.Error alongside the report when any file has errors.
+func (u *Uploader) Validate(files []models.UploadFile, options validation.Options) (*validation.Report, error) {
+	converted, cleanup, err := u.convertWorkbooks(context.Background(), files)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return validateConverted(converted, files, options)
+}
+
+// validate checks the files against their schemas, logging every issue found
+func (u *Uploader) validate(ctx context.Context, files, sources []models.UploadFile) error {
+	report, err := validateConverted(files, sources, validation.Options{Strict: u.config.ValidateStrict})
+	if report == nil {
+		return err
 	}
 
-	// Add student courses file if provided
-	if studentCoursesPath != "" {
-		absPath, err := filepath.Abs(studentCoursesPath)
+	for _, file := range report.Files {
+		fileLogger := u.logger.WithContext(ctx).With(logger.KeyPath, file.Path)
+		for _, issue := range file.Issues {
+			if issue.Severity == validation.SeverityError {
+				fileLogger.Error("%s: %s", file.Path, issue)
+			} else {
+				fileLogger.Warning("%s: %s", file.Path, issue)
+			}
+		}
+		if file.Truncated > 0 {
+			fileLogger.Error("%s: %d more error(s) not shown", file.Path, file.Truncated)
+		}
+	}
+
+	return err
+}
+
+// validateConverted checks converted files against their schemas and, when they pass, against each other.
+// Issues are reported against the source paths the files were converted from.
+func validateConverted(files, sources []models.UploadFile, options validation.Options) (*validation.Report, error) {
+	report, err := validation.Validate(files, options)
+	if report == nil {
+		return nil, err
+	}
+
+	// Cross-file checks are only meaningful once every file has the expected layout
+	if err == nil {
+		integrity, err := validation.CheckIntegrity(models.UploadRequest{Files: files}, options)
+		if integrity == nil {
+			return nil, err
+		}
+		report.Merge(integrity)
+	}
+
+	for i, file := range report.Files {
+		file.Path = sources[i].FilePath
+	}
+	if report.HasErrors() {
+		return report, &validation.Error{Report: report}
+	}
+	return report, nil
+}
+
+// FilesFromPaths builds the upload file list from the four optional file paths, skipping empty ones
+func FilesFromPaths(coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+
+	paths := []struct {
+		fileType models.FileType
+		path     string
+		name     string
+	}{
+		{models.FileTypeCourses, coursesPath, "courses"},
+		{models.FileTypeEquivalencies, equivalenciesPath, "equivalencies"},
+		{models.FileTypeStudents, studentsPath, "students"},
+		{models.FileTypeStudentCourses, studentCoursesPath, "student courses"},
+	}
+
+	for _, p := range paths {
+		if p.path == "" {
+			continue
+		}
+		absPath, err := filepath.Abs(p.path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get absolute path for student courses file: %w", err)
+			return nil, fmt.Errorf("failed to get absolute path for %s file: %w", p.name, err)
 		}
 		files = append(files, models.UploadFile{
-			Type:     models.FileTypeStudentCourses,
+			Type:     p.fileType,
 			FilePath: absPath,
 		})
 	}
 
-	// Upload files
-	return u.UploadFiles(apiKey, files)
+	return files, nil
 }
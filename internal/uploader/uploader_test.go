@@ -1,15 +1,26 @@
 package uploader
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/chatt-state/trtc-go/internal/api"
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/history"
+	"github.com/chatt-state/trtc-go/internal/mockserver"
 	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/internal/validation"
 	"github.com/chatt-state/trtc-go/pkg/logger"
+	"github.com/xuri/excelize/v2"
 )
 
 func setupTest(t *testing.T) (*logger.Logger, *config.Config, string) {
@@ -136,6 +147,388 @@ func TestUploadFilesError(t *testing.T) {
 	}
 }
 
+func TestUploadFilesWithContextCancelled(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that should never be reached
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			t.Errorf("Expected upload to be cancelled before reaching the client")
+			return nil, nil
+		},
+	}
+
+	// Create an uploader with the mock client
+	uploader := NewWithClient(mockClient, config, logger)
+
+	// Create a temporary file for testing
+	testFilePath := filepath.Join(tempDir, "test.csv")
+	if err := os.WriteFile(testFilePath, []byte("test,data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Test uploading with an already cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := uploader.UploadFilesWithContext(ctx, "test-api-key", []models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: testFilePath},
+	})
+	if !errors.Is(err, api.ErrCanceled) {
+		t.Errorf("Expected api.ErrCanceled, got %v", err)
+	}
+}
+
+func TestUploadFilesProgress(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that reports progress for each file
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			if request.OnProgress == nil {
+				t.Fatalf("Expected the progress callback to be passed to the client")
+			}
+			for _, file := range request.Files {
+				request.OnProgress(models.Progress{File: file, FileSent: 9, FileTotal: 9, Sent: 9, Total: 9})
+			}
+			return &models.UploadResponse{Success: true, Code: 200}, nil
+		},
+	}
+
+	// Create an uploader with the mock client
+	uploader := NewWithClient(mockClient, config, logger)
+
+	var updates []models.Progress
+	uploader.SetProgressFunc(func(p models.Progress) {
+		updates = append(updates, p)
+	})
+
+	// Create a temporary file for testing
+	testFilePath := filepath.Join(tempDir, "test.csv")
+	if err := os.WriteFile(testFilePath, []byte("test,data"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := uploader.UploadFiles("test-api-key", []models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: testFilePath},
+	}); err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+
+	if len(updates) != 1 || updates[0].File.Type != models.FileTypeCourses {
+		t.Errorf("Expected one progress update for the courses file, got %v", updates)
+	}
+}
+
+func TestUploadFilesJournal(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that accepts the first upload and rejects the second
+	calls := 0
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			calls++
+			if calls == 1 {
+				return &models.UploadResponse{Success: true, Code: 200, Message: "OK", ReferenceID: "BATCH-1"}, nil
+			}
+			return &models.UploadResponse{Success: false, Code: 400, Message: "Bad Request"}, nil
+		},
+	}
+
+	// Create an uploader that records to a temporary journal
+	uploader := NewWithClient(mockClient, config, logger)
+	journal := history.New(filepath.Join(tempDir, history.FileName))
+	uploader.SetJournal(journal)
+	uploader.SetForce(true)
+
+	testFilePath := filepath.Join(tempDir, "courses.csv")
+	if err := os.WriteFile(testFilePath, []byte("Subject,CourseNumber\nMATH,1130\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	files := []models.UploadFile{{Type: models.FileTypeCourses, FilePath: testFilePath}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := uploader.UploadFiles("test-api-key", files); err != nil {
+			t.Fatalf("Failed to upload file: %v", err)
+		}
+	}
+
+	entries, err := journal.Entries(history.Filter{})
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 journal entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Status != history.StatusSuccess || first.Code != 200 || first.ReferenceID != "BATCH-1" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if first.Endpoint != config.APIEndpoint {
+		t.Errorf("Expected endpoint %s, got %s", config.APIEndpoint, first.Endpoint)
+	}
+	if len(first.Files) != 1 || first.Files[0].Path != testFilePath || first.Files[0].Rows != 1 || first.Files[0].SHA256 == "" {
+		t.Errorf("Unexpected files in first entry: %+v", first.Files)
+	}
+	if entries[1].Status != history.StatusFailed || entries[1].Message != "Bad Request" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+}
+
+func TestUploadFilesRunSummary(t *testing.T) {
+	tempDir := t.TempDir()
+	logFilePath := filepath.Join(tempDir, "test.log")
+	log, err := logger.NewWithOptions(logFilePath, logger.Options{Level: logger.LevelInfo, Format: logger.FormatJSON})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	config := &config.Config{APIEndpoint: "https://test-endpoint.com"}
+
+	// The client sees the run ID in its context
+	var clientRunID string
+	mockClient := &api.MockClient{
+		UploadFilesWithContextFunc: func(ctx context.Context, request models.UploadRequest) (*models.UploadResponse, error) {
+			clientRunID = logger.RunID(ctx)
+			return &models.UploadResponse{Success: true, Code: 200, ReferenceID: "BATCH-1"}, nil
+		},
+	}
+	uploader := NewWithClient(mockClient, config, log)
+	journal := history.New(filepath.Join(tempDir, history.FileName))
+	uploader.SetJournal(journal)
+
+	testFilePath := filepath.Join(tempDir, "courses.csv")
+	content := "Subject,CourseNumber\nMATH,1130\n"
+	if err := os.WriteFile(testFilePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	files := []models.UploadFile{{Type: models.FileTypeCourses, FilePath: testFilePath}}
+
+	// A run ID in the context is used; otherwise each run gets its own
+	ctx := logger.WithRunID(context.Background(), "20250313-130420-ab12")
+	if _, err := uploader.UploadFilesWithContext(ctx, "test-api-key", files); err != nil {
+		t.Fatalf("Failed to upload file: %v", err)
+	}
+	if clientRunID != "20250313-130420-ab12" {
+		t.Errorf("Expected the client to see the run ID, got %q", clientRunID)
+	}
+	if _, err := uploader.UploadFiles("test-api-key", files); !errors.Is(err, ErrUnchanged) {
+		t.Fatalf("Expected ErrUnchanged, got %v", err)
+	}
+	log.Close()
+
+	// Every run is recorded under its ID, including those that sent nothing
+	entries, err := journal.Entries(history.Filter{})
+	if err != nil {
+		t.Fatalf("Failed to read journal: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 journal entries, got %d", len(entries))
+	}
+	first := entries[0]
+	if first.ID != "20250313-130420-ab12" || first.Status != history.StatusSuccess || first.ReferenceID != "BATCH-1" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if first.Timings == nil || first.Timings.Total <= 0 || first.Bytes() != int64(len(content)) {
+		t.Errorf("Expected the timings and size of the run, got %+v", first)
+	}
+	if entries[1].ID == first.ID || entries[1].Status != history.StatusSkipped {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	// Each run ends with a single summary record carrying its ID
+	data, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	var summaries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected a JSON record, got %q: %v", line, err)
+		}
+		if record["msg"] == "Run finished" {
+			summaries = append(summaries, record)
+		}
+	}
+	if len(summaries) != 2 {
+		t.Fatalf("Expected 2 summary records, got %d:\n%s", len(summaries), data)
+	}
+	for key, want := range map[string]any{
+		logger.KeyRunID: "20250313-130420-ab12",
+		"outcome":       "success",
+		"files":         float64(1),
+		"bytes":         float64(len(content)),
+		"reference_id":  "BATCH-1",
+	} {
+		if summaries[0][key] != want {
+			t.Errorf("Expected %s to be %v, got %v", key, want, summaries[0][key])
+		}
+	}
+	if summaries[1][logger.KeyRunID] != entries[1].ID || summaries[1]["outcome"] != "skipped" {
+		t.Errorf("Unexpected second summary: %v", summaries[1])
+	}
+}
+
+func TestUploadFilesSkipsUnchanged(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that records the file types it receives
+	var sent [][]models.FileType
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			var types []models.FileType
+			for _, file := range request.Files {
+				types = append(types, file.Type)
+			}
+			sent = append(sent, types)
+			return &models.UploadResponse{Success: true, Code: 200}, nil
+		},
+	}
+
+	uploader := NewWithClient(mockClient, config, logger)
+	uploader.SetJournal(history.New(filepath.Join(tempDir, history.FileName)))
+
+	coursesPath := filepath.Join(tempDir, "courses.csv")
+	studentsPath := filepath.Join(tempDir, "students.csv")
+	writeFiles := func(students string) {
+		if err := os.WriteFile(coursesPath, []byte("Subject,CourseNumber\nMATH,1130\n"), 0644); err != nil {
+			t.Fatalf("Failed to create courses file: %v", err)
+		}
+		if err := os.WriteFile(studentsPath, []byte(students), 0644); err != nil {
+			t.Fatalf("Failed to create students file: %v", err)
+		}
+	}
+	files := []models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: coursesPath},
+		{Type: models.FileTypeStudents, FilePath: studentsPath},
+	}
+
+	// The first upload sends everything
+	writeFiles("StudentID\n1001\n")
+	if _, err := uploader.UploadFiles("test-api-key", files); err != nil {
+		t.Fatalf("Failed to upload files: %v", err)
+	}
+
+	// Only the changed students file is sent next time
+	writeFiles("StudentID\n1001\n1002\n")
+	if _, err := uploader.UploadFiles("test-api-key", files); err != nil {
+		t.Fatalf("Failed to upload files: %v", err)
+	}
+
+	// Nothing is sent when every file is unchanged
+	if _, err := uploader.UploadFiles("test-api-key", files); !errors.Is(err, ErrUnchanged) {
+		t.Errorf("Expected ErrUnchanged, got %v", err)
+	}
+
+	// Forcing sends everything again
+	uploader.SetForce(true)
+	if _, err := uploader.UploadFiles("test-api-key", files); err != nil {
+		t.Fatalf("Failed to upload files: %v", err)
+	}
+
+	expected := [][]models.FileType{
+		{models.FileTypeCourses, models.FileTypeStudents},
+		{models.FileTypeStudents},
+		{models.FileTypeCourses, models.FileTypeStudents},
+	}
+	if len(sent) != len(expected) {
+		t.Fatalf("Expected %d uploads, got %v", len(expected), sent)
+	}
+	for i := range expected {
+		if len(sent[i]) != len(expected[i]) {
+			t.Errorf("Upload %d: expected %v, got %v", i+1, expected[i], sent[i])
+			continue
+		}
+		for j := range expected[i] {
+			if sent[i][j] != expected[i][j] {
+				t.Errorf("Upload %d: expected %v, got %v", i+1, expected[i], sent[i])
+			}
+		}
+	}
+}
+
+func TestUploadFilesDelta(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that records what it receives and rejects a record when asked
+	var sent []string
+	rejected := 0
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			data, err := os.ReadFile(request.Files[0].FilePath)
+			if err != nil {
+				t.Fatalf("Failed to read uploaded file: %v", err)
+			}
+			sent = append(sent, string(data))
+			return &models.UploadResponse{Success: true, Code: 200, RecordsRejected: rejected}, nil
+		},
+	}
+
+	uploader := NewWithClient(mockClient, config, logger)
+	uploader.SetSnapshots(delta.NewStore(filepath.Join(tempDir, delta.DirName)))
+	uploader.SetDelta(true)
+
+	studentsPath := filepath.Join(tempDir, "students.csv")
+	upload := func(content string) error {
+		if err := os.WriteFile(studentsPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create students file: %v", err)
+		}
+		_, err := uploader.UploadFiles("test-api-key", []models.UploadFile{{Type: models.FileTypeStudents, FilePath: studentsPath}})
+		return err
+	}
+
+	// Without a snapshot the whole file is sent
+	if err := upload("StudentID,LastName\n1001,Lovelace\n1002,Hopper\n"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	// Only the changed and added rows are sent next; the server rejects one, so the snapshot is dropped
+	rejected = 1
+	if err := upload("StudentID,LastName\n1001,Lovelace\n1002,Byron\n1003,Turing\n"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	// With the snapshot dropped the whole file is sent again
+	rejected = 0
+	if err := upload("StudentID,LastName\n1001,Lovelace\n1002,Byron\n1003,Turing\n"); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+
+	// Nothing is sent when no rows changed
+	if err := upload("StudentID,LastName\n1002,Byron\n1001,Lovelace\n1003,Turing\n"); !errors.Is(err, ErrUnchanged) {
+		t.Errorf("Expected ErrUnchanged, got %v", err)
+	}
+
+	expected := []string{
+		"StudentID,LastName\n1001,Lovelace\n1002,Hopper\n",
+		"StudentID,LastName\n1002,Byron\n1003,Turing\n",
+		"StudentID,LastName\n1001,Lovelace\n1002,Byron\n1003,Turing\n",
+	}
+	if len(sent) != len(expected) {
+		t.Fatalf("Expected %d uploads, got %d: %q", len(expected), len(sent), sent)
+	}
+	for i := range expected {
+		if sent[i] != expected[i] {
+			t.Errorf("Upload %d: expected %q, got %q", i+1, expected[i], sent[i])
+		}
+	}
+}
+
 func TestUploadFilesFromPaths(t *testing.T) {
 	// Setup test
 	logger, config, tempDir := setupTest(t)
@@ -194,6 +587,50 @@ func TestUploadFilesFromPaths(t *testing.T) {
 	}
 }
 
+func TestUploadFilesMockServer(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Start a mock TRTC server that fails the first attempt
+	handler := mockserver.New(mockserver.Options{APIKeys: []string{"test-api-key"}, FailFirst: 1})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	config.APIEndpoint = server.URL
+	config.RetryMaxAttempts = 2
+	config.RetryBaseDelay = time.Millisecond
+	config.RetryStatusCodes = []int{http.StatusServiceUnavailable}
+	uploader, err := New(config, logger)
+	if err != nil {
+		t.Fatalf("Failed to create uploader: %v", err)
+	}
+
+	// Create a temporary file for testing
+	coursesFilePath := filepath.Join(tempDir, "courses.csv")
+	if err := os.WriteFile(coursesFilePath, []byte("id,title\n1,Math\n"), 0644); err != nil {
+		t.Fatalf("Failed to create courses file: %v", err)
+	}
+
+	response, err := uploader.UploadFilesFromPaths("test-api-key", coursesFilePath, "", "", "")
+	if err != nil {
+		t.Fatalf("Failed to upload files: %v", err)
+	}
+	if !response.Success || response.Code != http.StatusOK {
+		t.Errorf("Expected a successful response, got %d: %s", response.Code, response.Message)
+	}
+	if response.ReferenceID == "" {
+		t.Errorf("Expected a reference ID from the mock server")
+	}
+	if handler.Requests() != 2 {
+		t.Errorf("Expected 2 requests (one retry), got %d", handler.Requests())
+	}
+	if uploads := handler.Uploads(); len(uploads) != 1 || uploads[0].Files[0].Records != 1 {
+		t.Errorf("Expected one upload with one record, got %+v", uploads)
+	}
+}
+
 func TestUploadFilesValidation(t *testing.T) {
 	// Setup test
 	logger, config, tempDir := setupTest(t)
@@ -201,10 +638,13 @@ func TestUploadFilesValidation(t *testing.T) {
 	defer logger.Close()
 
 	// Create an uploader
-	uploader := New(config, logger)
+	uploader, err := New(config, logger)
+	if err != nil {
+		t.Fatalf("Failed to create uploader: %v", err)
+	}
 
 	// Test with empty API key
-	_, err := uploader.UploadFiles("", []models.UploadFile{})
+	_, err = uploader.UploadFiles("", []models.UploadFile{})
 	if err == nil {
 		t.Fatalf("Expected an error for empty API key, got nil")
 	}
@@ -235,3 +675,154 @@ func TestUploadFilesValidation(t *testing.T) {
 		t.Errorf("Expected error message to be 'file does not exist: %s', got %s", filepath.Join(tempDir, "non-existent.csv"), err.Error())
 	}
 }
+
+func TestUploadFilesSchemaValidation(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a mock API client that counts uploads
+	uploads := 0
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			uploads++
+			return &models.UploadResponse{Success: true, Code: 200}, nil
+		},
+	}
+
+	config.ValidateFiles = true
+	config.ValidateStrict = true
+	uploader := NewWithClient(mockClient, config, logger)
+
+	// Create a courses file with a missing title and a bad credit hours value
+	coursesFilePath := filepath.Join(tempDir, "courses.csv")
+	if err := os.WriteFile(coursesFilePath, []byte("Subject,CourseNumber,Title,CreditHours\nMATH,1130,,three\n"), 0644); err != nil {
+		t.Fatalf("Failed to create courses file: %v", err)
+	}
+
+	_, err := uploader.UploadFilesFromPaths("test-api-key", coursesFilePath, "", "", "")
+	var validationErr *validation.Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if validationErr.Report.Errors() != 2 {
+		t.Errorf("Expected 2 validation errors, got %d", validationErr.Report.Errors())
+	}
+	if uploads != 0 {
+		t.Errorf("Expected no upload after failed validation, got %d", uploads)
+	}
+
+	// Without strict validation the problems are only warnings
+	config.ValidateStrict = false
+	if _, err := uploader.UploadFilesFromPaths("test-api-key", coursesFilePath, "", "", ""); err != nil {
+		t.Fatalf("Expected upload to succeed with warnings, got %v", err)
+	}
+	if uploads != 1 {
+		t.Errorf("Expected 1 upload, got %d", uploads)
+	}
+
+	// Opting out sends the file anyway
+	config.ValidateFiles = false
+	config.ValidateStrict = true
+	if _, err := uploader.UploadFilesFromPaths("test-api-key", coursesFilePath, "", "", ""); err != nil {
+		t.Fatalf("Expected upload to succeed without validation, got %v", err)
+	}
+	if uploads != 2 {
+		t.Errorf("Expected 2 uploads, got %d", uploads)
+	}
+}
+
+func TestUploadFilesConvertsWorkbooks(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	// Create a workbook with the courses on a sheet named after the file type
+	workbook := excelize.NewFile()
+	workbook.SetSheetName("Sheet1", "Summary")
+	workbook.NewSheet("Courses")
+	workbook.SetSheetRow("Courses", "A1", &[]interface{}{"Subject", "CourseNumber", "Title", "CreditHours"})
+	workbook.SetSheetRow("Courses", "A2", &[]interface{}{"MATH", "1130", "College Algebra", 3})
+	workbookPath := filepath.Join(tempDir, "courses.xlsx")
+	if err := workbook.SaveAs(workbookPath); err != nil {
+		t.Fatalf("Failed to create workbook: %v", err)
+	}
+
+	var sentPath, sentContent string
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			sentPath = request.Files[0].FilePath
+			content, err := os.ReadFile(sentPath)
+			if err != nil {
+				t.Errorf("Failed to read converted file: %v", err)
+			}
+			sentContent = string(content)
+			return &models.UploadResponse{Success: true, Code: 200}, nil
+		},
+	}
+
+	config.ValidateFiles = true
+	uploader := NewWithClient(mockClient, config, logger)
+
+	if _, err := uploader.UploadFilesFromPaths("test-api-key", workbookPath, "", "", ""); err != nil {
+		t.Fatalf("Failed to upload workbook: %v", err)
+	}
+	if filepath.Base(sentPath) != "courses.csv" {
+		t.Errorf("Expected courses.csv to be sent, got %s", sentPath)
+	}
+	if !strings.HasPrefix(sentContent, "Subject,CourseNumber,Title,CreditHours\nMATH,1130,College Algebra,3\n") {
+		t.Errorf("Unexpected converted content: %q", sentContent)
+	}
+	if _, err := os.Stat(sentPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the converted file to be removed after upload")
+	}
+
+	// An explicit sheet that does not exist fails before upload
+	uploader.SetSheet("Grades")
+	if _, err := uploader.UploadFilesFromPaths("test-api-key", workbookPath, "", "", ""); err == nil || !strings.Contains(err.Error(), "sheet") {
+		t.Errorf("Expected a missing sheet error, got %v", err)
+	}
+}
+
+func TestUploadFilesIntegrityCheck(t *testing.T) {
+	// Setup test
+	logger, config, tempDir := setupTest(t)
+	defer os.RemoveAll(tempDir)
+	defer logger.Close()
+
+	uploads := 0
+	mockClient := &api.MockClient{
+		UploadFilesFunc: func(request models.UploadRequest) (*models.UploadResponse, error) {
+			uploads++
+			return &models.UploadResponse{Success: true, Code: 200}, nil
+		},
+	}
+
+	config.ValidateFiles = true
+	config.ValidateStrict = true
+	uploader := NewWithClient(mockClient, config, logger)
+
+	// The student courses file references a student missing from the students file
+	studentsFilePath := filepath.Join(tempDir, "students.csv")
+	if err := os.WriteFile(studentsFilePath, []byte("StudentID,FirstName,LastName,DateOfBirth,Consent\n1001,Ada,Lovelace,1965-12-10,Y\n"), 0644); err != nil {
+		t.Fatalf("Failed to create students file: %v", err)
+	}
+	studentCoursesFilePath := filepath.Join(tempDir, "studentcourses.csv")
+	if err := os.WriteFile(studentCoursesFilePath, []byte("StudentID,Subject,CourseNumber,Term,Grade,CreditHours\n1002,MATH,1130,202410,A,3\n"), 0644); err != nil {
+		t.Fatalf("Failed to create student courses file: %v", err)
+	}
+
+	_, err := uploader.UploadFilesFromPaths("test-api-key", "", "", studentsFilePath, studentCoursesFilePath)
+	var validationErr *validation.Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if report := validationErr.Report; len(report.Files) != 2 || report.Files[1].Errors() != 1 || report.Files[1].Path != studentCoursesFilePath {
+		t.Errorf("Expected one error in the student courses file, got %+v", report.Files)
+	}
+	if uploads != 0 {
+		t.Errorf("Expected no upload after failed integrity check, got %d", uploads)
+	}
+}
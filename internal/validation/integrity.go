@@ -0,0 +1,323 @@
+package validation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// reference is a set of columns in one file type that must match the key of another
+type reference struct {
+	from    models.FileType
+	columns []string
+	to      models.FileType
+	// name describes the referenced record in messages
+	name string
+}
+
+// references are the cross-file references checked by CheckIntegrity
+var references = []reference{
+	{from: models.FileTypeStudentCourses, columns: []string{"StudentID"}, to: models.FileTypeStudents, name: "student"},
+	{from: models.FileTypeStudentCourses, columns: []string{"Subject", "CourseNumber"}, to: models.FileTypeCourses, name: "course"},
+	{from: models.FileTypeEquivalencies, columns: []string{"Subject", "CourseNumber"}, to: models.FileTypeCourses, name: "course"},
+}
+
+// table holds the rows of one file, with values in schema column order
+type table struct {
+	file    models.UploadFile
+	schema  Schema
+	report  *FileReport
+	present []bool
+	rows    []row
+}
+
+// row is a single data row of a table
+type row struct {
+	line   int
+	values []string
+}
+
+// has reports whether the file has all of the named columns
+func (t *table) has(columns []string) bool {
+	for _, name := range columns {
+		if t.schema.index(name) < 0 || !t.present[t.schema.index(name)] {
+			return false
+		}
+	}
+	return true
+}
+
+// key returns the normalized values of the named columns, or "" if any is empty
+func (t *table) key(r row, columns []string) string {
+	parts := make([]string, len(columns))
+	for i, name := range columns {
+		value := strings.ToUpper(strings.TrimSpace(r.values[t.schema.index(name)]))
+		if value == "" {
+			return ""
+		}
+		parts[i] = value
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// describe formats the values of the named columns for messages
+func (t *table) describe(r row, columns []string) string {
+	parts := make([]string, len(columns))
+	for i, name := range columns {
+		parts[i] = strings.TrimSpace(r.values[t.schema.index(name)])
+	}
+	return strings.Join(parts, " ")
+}
+
+// CheckIntegrity checks the files of an upload against each other. It reports
+// rows that share a key within a file type, student courses that reference
+// students or courses missing from the accompanying files, equivalencies that
+// reference missing courses, and credit hours that differ between a student
+// course and its course. References are only checked when the referenced file
+// type is part of the request. It returns an *Error alongside the report when
+// any errors are found. Conflicting keys and missing references rely on the
+// unconfirmed layouts, so they are only errors with options.Strict.
+func CheckIntegrity(request models.UploadRequest, options Options) (*Report, error) {
+	report := &Report{}
+	tables := map[models.FileType][]*table{}
+
+	for _, file := range request.Files {
+		t, err := readTable(file, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Files = append(report.Files, t.report)
+		if t.rows != nil {
+			tables[file.Type] = append(tables[file.Type], t)
+		}
+	}
+
+	for _, ft := range models.FileTypes {
+		checkDuplicates(tables[ft], options)
+	}
+	for _, ref := range references {
+		checkReferences(ref, tables[ref.from], tables[ref.to], options)
+	}
+	checkCreditHours(tables[models.FileTypeStudentCourses], tables[models.FileTypeCourses], options)
+
+	if report.HasErrors() {
+		return report, &Error{Report: report}
+	}
+	return report, nil
+}
+
+// readTable reads the rows of a file for the cross-file checks
+func readTable(file models.UploadFile, options Options) (*table, error) {
+	schema, ok := SchemaFor(file.Type)
+	if !ok {
+		return nil, fmt.Errorf("no schema for file type %s", file.Type.String())
+	}
+	t := &table{
+		file:    file,
+		schema:  schema,
+		report:  &FileReport{Type: file.Type, Path: file.FilePath},
+		present: make([]bool, len(schema.Columns)),
+	}
+
+	if ext := strings.ToLower(filepath.Ext(file.FilePath)); ext != ".csv" && ext != ".txt" {
+		t.report.add(Issue{Severity: SeverityWarning, Message: fmt.Sprintf("%s files are not checked against other files", ext)}, options.maxIssues())
+		return t, nil
+	}
+
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", file.FilePath, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		t.rows = []row{}
+		return t, nil
+	}
+	if err != nil {
+		t.report.add(parseIssue(err), options.maxIssues())
+		return t, nil
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	// Map file columns to schema columns, keeping the first of any duplicates
+	positions := make([]int, len(schema.Columns))
+	for i := range positions {
+		positions[i] = -1
+	}
+	for i, name := range header {
+		if index := schema.index(name); index >= 0 && positions[index] < 0 {
+			positions[index] = i
+			t.present[index] = true
+		}
+	}
+
+	t.rows = []row{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.report.add(parseIssue(err), options.maxIssues())
+			break
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := make([]string, len(schema.Columns))
+		for index, position := range positions {
+			if position >= 0 && position < len(record) {
+				values[index] = record[position]
+			}
+		}
+		t.rows = append(t.rows, row{line: line, values: values})
+		t.report.Rows++
+	}
+
+	return t, nil
+}
+
+// checkDuplicates reports rows of the same file type that share a key. Exact
+// copies are warnings; rows that share a key but differ have the layout severity.
+func checkDuplicates(tables []*table, options Options) {
+	type seen struct {
+		table *table
+		row   row
+	}
+	first := map[string]seen{}
+
+	for _, t := range tables {
+		if !t.has(t.schema.Key) {
+			continue
+		}
+		for _, r := range t.rows {
+			key := t.key(r, t.schema.Key)
+			if key == "" {
+				continue
+			}
+			prev, ok := first[key]
+			if !ok {
+				first[key] = seen{table: t, row: r}
+				continue
+			}
+
+			where := fmt.Sprintf("line %d", prev.row.line)
+			if prev.table != t {
+				where += " of " + filepath.Base(prev.table.file.FilePath)
+			}
+			issue := Issue{Line: r.line, Field: strings.Join(t.schema.Key, "+")}
+			if sameValues(prev.row.values, r.values) {
+				issue.Severity = SeverityWarning
+				issue.Message = fmt.Sprintf("duplicate of %s (%s)", where, t.describe(r, t.schema.Key))
+			} else {
+				issue.Severity = options.layoutSeverity()
+				issue.Message = fmt.Sprintf("conflicts with %s: %s appears twice with different values", where, t.describe(r, t.schema.Key))
+			}
+			t.report.add(issue, options.maxIssues())
+		}
+	}
+}
+
+// checkReferences reports rows whose reference is not a key in any of the referenced tables
+func checkReferences(ref reference, from, to []*table, options Options) {
+	if len(to) == 0 {
+		return
+	}
+
+	keys := map[string]bool{}
+	for _, t := range to {
+		if !t.has(t.schema.Key) {
+			// The referenced keys are unknown, so nothing can be reported as missing
+			return
+		}
+		for _, r := range t.rows {
+			keys[t.key(r, t.schema.Key)] = true
+		}
+	}
+
+	for _, t := range from {
+		if !t.has(ref.columns) {
+			continue
+		}
+		for _, r := range t.rows {
+			key := t.key(r, ref.columns)
+			if key == "" || keys[key] {
+				continue
+			}
+			t.report.add(Issue{
+				Severity: options.layoutSeverity(),
+				Line:     r.line,
+				Field:    strings.Join(ref.columns, "+"),
+				Message:  fmt.Sprintf("%s %s is not in the %s file", ref.name, t.describe(r, ref.columns), ref.to.String()),
+			}, options.maxIssues())
+		}
+	}
+}
+
+// checkCreditHours warns when a student course's credit hours differ from those of its course
+func checkCreditHours(studentCourses, courses []*table, options Options) {
+	courseKey := []string{"Subject", "CourseNumber"}
+	required := []string{"Subject", "CourseNumber", "CreditHours"}
+	hours := map[string]float64{}
+	for _, t := range courses {
+		if !t.has(required) {
+			continue
+		}
+		for _, r := range t.rows {
+			if value, err := strconv.ParseFloat(strings.TrimSpace(r.values[t.schema.index("CreditHours")]), 64); err == nil {
+				hours[t.key(r, courseKey)] = value
+			}
+		}
+	}
+	if len(hours) == 0 {
+		return
+	}
+
+	for _, t := range studentCourses {
+		if !t.has(required) {
+			continue
+		}
+		for _, r := range t.rows {
+			expected, ok := hours[t.key(r, courseKey)]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(r.values[t.schema.index("CreditHours")]), 64)
+			if err != nil || value == expected {
+				continue
+			}
+			t.report.add(Issue{
+				Severity: SeverityWarning,
+				Line:     r.line,
+				Field:    "CreditHours",
+				Message:  fmt.Sprintf("%s has %g credit hours here but %g in the courses file", t.describe(r, courseKey), value, expected),
+			}, options.maxIssues())
+		}
+	}
+}
+
+// sameValues reports whether two rows have the same values, ignoring surrounding space and case
+func sameValues(a, b []string) bool {
+	for i := range a {
+		if !strings.EqualFold(strings.TrimSpace(a[i]), strings.TrimSpace(b[i])) {
+			return false
+		}
+	}
+	return true
+}
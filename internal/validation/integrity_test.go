@@ -0,0 +1,129 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	courses := writeFile(t, "courses.csv", "Subject,CourseNumber,Title,CreditHours\n"+
+		"MATH,1130,College Algebra,3\n"+
+		"ENGL,1010,Composition I,3\n"+
+		"math,1130,College Algebra,3\n")
+	students := writeFile(t, "students.csv", "StudentID,FirstName,LastName,DateOfBirth,Consent\n"+
+		"1001,Ada,Lovelace,1965-12-10,Y\n"+
+		"1002,Grace,Hopper,1906-12-09,Y\n"+
+		"1002,Grace,Murray,1906-12-09,Y\n")
+	studentCourses := writeFile(t, "studentcourses.csv", "StudentID,Subject,CourseNumber,Term,Grade,CreditHours\n"+
+		"1001,MATH,1130,202410,A,3\n"+
+		"1003,ENGL,1010,202410,B,3\n"+
+		"1002,HIST,2010,202410,C,3\n"+
+		"1002,ENGL,1010,202410,A,4\n")
+	equivalencies := writeFile(t, "equivalencies.csv", "SourceInstitution,SourceSubject,SourceCourseNumber,Subject,CourseNumber\n"+
+		"ABC,MAT,101,MATH,1130\n"+
+		"ABC,BIO,101,BIOL,1110\n")
+
+	request := models.UploadRequest{Files: []models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: courses},
+		{Type: models.FileTypeStudents, FilePath: students},
+		{Type: models.FileTypeStudentCourses, FilePath: studentCourses},
+		{Type: models.FileTypeEquivalencies, FilePath: equivalencies},
+	}}
+
+	report, err := CheckIntegrity(request, Options{Strict: true})
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+
+	expected := map[models.FileType][]string{
+		models.FileTypeCourses: {
+			"warning: line 4: duplicate of line 2 (math 1130)",
+		},
+		models.FileTypeStudents: {
+			"error: line 4: conflicts with line 3: 1002 appears twice with different values",
+		},
+		models.FileTypeStudentCourses: {
+			"error: line 3: student 1003 is not in the students file",
+			"error: line 4: course HIST 2010 is not in the courses file",
+			"warning: line 5: ENGL 1010 has 4 credit hours here but 3 in the courses file",
+		},
+		models.FileTypeEquivalencies: {
+			"error: line 3: course BIOL 1110 is not in the courses file",
+		},
+	}
+	for _, file := range report.Files {
+		want := expected[file.Type]
+		if len(file.Issues) != len(want) {
+			t.Errorf("%s: expected %d issues, got %v", file.Type.String(), len(want), file.Issues)
+			continue
+		}
+		for i, issue := range file.Issues {
+			if issue.String() != want[i] {
+				t.Errorf("%s issue %d: expected %q, got %q", file.Type.String(), i, want[i], issue.String())
+			}
+		}
+	}
+	if report.Errors() != 4 {
+		t.Errorf("Expected 4 errors, got %d", report.Errors())
+	}
+
+	// Without Strict the same problems are only warnings
+	report, err = CheckIntegrity(request, Options{})
+	if err != nil || report.Errors() != 0 {
+		t.Errorf("Expected only warnings, got %v", err)
+	}
+}
+
+func TestCheckIntegrity_MissingReferencedFile(t *testing.T) {
+	// Without a students or courses file the references cannot be checked
+	studentCourses := writeFile(t, "studentcourses.csv", "StudentID,Subject,CourseNumber,Term,Grade,CreditHours\n"+
+		"1001,MATH,1130,202410,A,3\n")
+
+	report, err := CheckIntegrity(models.UploadRequest{Files: []models.UploadFile{
+		{Type: models.FileTypeStudentCourses, FilePath: studentCourses},
+	}}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Files) != 1 || len(report.Files[0].Issues) != 0 || report.Files[0].Rows != 1 {
+		t.Errorf("Expected a clean report with one row, got %+v", report.Files)
+	}
+}
+
+func TestCheckIntegrity_AcrossFiles(t *testing.T) {
+	// Two students files are combined, and a conflict between them names the other file
+	first := writeFile(t, "students-fall.csv", "StudentID,FirstName,LastName,DateOfBirth,Consent\n1001,Ada,Lovelace,1965-12-10,Y\n")
+	second := writeFile(t, "students-spring.csv", "StudentID,FirstName,LastName,DateOfBirth,Consent\n1001,Ada,Byron,1965-12-10,Y\n")
+
+	report, err := CheckIntegrity(models.UploadRequest{Files: []models.UploadFile{
+		{Type: models.FileTypeStudents, FilePath: first},
+		{Type: models.FileTypeStudents, FilePath: second},
+	}}, Options{Strict: true})
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	issues := report.Files[1].Issues
+	if len(issues) != 1 || issues[0].String() != "error: line 2: conflicts with line 2 of students-fall.csv: 1001 appears twice with different values" {
+		t.Errorf("Unexpected issues: %v", issues)
+	}
+}
+
+func TestReportMerge(t *testing.T) {
+	report := &Report{Files: []*FileReport{
+		{Type: models.FileTypeCourses, Path: "courses.csv", Issues: []Issue{{Severity: SeverityError, Message: "one"}}},
+	}}
+	report.Merge(&Report{Files: []*FileReport{
+		{Type: models.FileTypeCourses, Path: "courses.csv", Issues: []Issue{{Severity: SeverityWarning, Message: "two"}}, Truncated: 2},
+		{Type: models.FileTypeStudents, Path: "students.csv"},
+	}})
+
+	if len(report.Files) != 2 || len(report.Files[0].Issues) != 2 {
+		t.Fatalf("Expected the courses reports to be combined, got %+v", report.Files)
+	}
+	if report.Errors() != 3 {
+		t.Errorf("Expected 3 errors, got %d", report.Errors())
+	}
+}
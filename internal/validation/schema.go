@@ -0,0 +1,147 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// FieldType is the kind of value a column holds
+type FieldType int
+
+const (
+	// FieldString accepts any text
+	FieldString FieldType = iota
+	// FieldInteger accepts whole numbers
+	FieldInteger
+	// FieldDecimal accepts numbers with an optional fractional part
+	FieldDecimal
+	// FieldDate accepts dates as YYYY-MM-DD or MM/DD/YYYY
+	FieldDate
+)
+
+// String returns the string representation of a FieldType
+func (ft FieldType) String() string {
+	switch ft {
+	case FieldString:
+		return "string"
+	case FieldInteger:
+		return "integer"
+	case FieldDecimal:
+		return "decimal"
+	case FieldDate:
+		return "date"
+	default:
+		return "unknown"
+	}
+}
+
+// Column describes one expected column of a file
+type Column struct {
+	Name      string
+	Type      FieldType
+	Required  bool
+	MaxLength int
+	// Allowed lists the accepted values, compared case-insensitively; empty means any value
+	Allowed []string
+}
+
+// Schema describes the expected columns of a file type
+type Schema struct {
+	Type    models.FileType
+	Columns []Column
+	// Key names the columns that identify a row
+	Key []string
+}
+
+// Column returns the column with the given name
+func (s Schema) Column(name string) (Column, bool) {
+	if i := s.index(name); i >= 0 {
+		return s.Columns[i], true
+	}
+	return Column{}, false
+}
+
+// index returns the position of the column with the given name, or -1
+func (s Schema) index(name string) int {
+	for i, c := range s.Columns {
+		if normalizeHeader(c.Name) == normalizeHeader(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// grades are the accepted values of StudentCourses.Grade
+var grades = []string{
+	"A", "A-", "B+", "B", "B-", "C+", "C", "C-", "D+", "D", "D-", "F",
+	"P", "S", "U", "I", "W", "WF", "WP", "NC", "CR", "AU",
+}
+
+// schemas holds the expected layout of each file type. The columns, keys,
+// lengths and allowed values are not confirmed against a published TRTC file
+// specification, so the problems they find are warnings unless Options.Strict
+// is set.
+var schemas = map[models.FileType]Schema{
+	models.FileTypeCourses: {
+		Type: models.FileTypeCourses,
+		Columns: []Column{
+			{Name: "Subject", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "CourseNumber", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "Title", Type: FieldString, Required: true, MaxLength: 100},
+			{Name: "CreditHours", Type: FieldDecimal, Required: true},
+			{Name: "Level", Type: FieldString, MaxLength: 2, Allowed: []string{"LD", "UD", "GR", "DV"}},
+			{Name: "EffectiveTerm", Type: FieldString, MaxLength: 6},
+		},
+		Key: []string{"Subject", "CourseNumber"},
+	},
+	models.FileTypeEquivalencies: {
+		Type: models.FileTypeEquivalencies,
+		Columns: []Column{
+			{Name: "SourceInstitution", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "SourceSubject", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "SourceCourseNumber", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "Subject", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "CourseNumber", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "EffectiveTerm", Type: FieldString, MaxLength: 6},
+		},
+		Key: []string{"SourceInstitution", "SourceSubject", "SourceCourseNumber"},
+	},
+	models.FileTypeStudents: {
+		Type: models.FileTypeStudents,
+		Columns: []Column{
+			{Name: "StudentID", Type: FieldString, Required: true, MaxLength: 20},
+			{Name: "FirstName", Type: FieldString, Required: true, MaxLength: 50},
+			{Name: "MiddleName", Type: FieldString, MaxLength: 50},
+			{Name: "LastName", Type: FieldString, Required: true, MaxLength: 50},
+			{Name: "DateOfBirth", Type: FieldDate, Required: true},
+			{Name: "Email", Type: FieldString, MaxLength: 100},
+			{Name: "Consent", Type: FieldString, Required: true, MaxLength: 1, Allowed: []string{"Y", "N"}},
+		},
+		Key: []string{"StudentID"},
+	},
+	models.FileTypeStudentCourses: {
+		Type: models.FileTypeStudentCourses,
+		Columns: []Column{
+			{Name: "StudentID", Type: FieldString, Required: true, MaxLength: 20},
+			{Name: "Subject", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "CourseNumber", Type: FieldString, Required: true, MaxLength: 10},
+			{Name: "Term", Type: FieldString, Required: true, MaxLength: 6},
+			{Name: "Grade", Type: FieldString, Required: true, MaxLength: 2, Allowed: grades},
+			{Name: "CreditHours", Type: FieldDecimal, Required: true},
+		},
+		Key: []string{"StudentID", "Subject", "CourseNumber", "Term"},
+	},
+}
+
+// SchemaFor returns the schema for a file type
+func SchemaFor(ft models.FileType) (Schema, bool) {
+	schema, ok := schemas[ft]
+	return schema, ok
+}
+
+// normalizeHeader lowercases a column name and drops spaces, dashes and underscores
+// so "Course Number", "course_number" and "CourseNumber" match
+func normalizeHeader(name string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
@@ -0,0 +1,377 @@
+package validation
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// DefaultMaxIssues is the number of issues reported per file before the rest are summarized
+const DefaultMaxIssues = 100
+
+// Severity is how serious an issue is
+type Severity int
+
+const (
+	// SeverityWarning marks an issue that does not block the upload
+	SeverityWarning Severity = iota
+	// SeverityError marks an issue that blocks the upload
+	SeverityError
+)
+
+// String returns the string representation of a Severity
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// MarshalText encodes a Severity by name, so it reads naturally in JSON and YAML
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Issue is a single problem found in a file
+type Issue struct {
+	Severity Severity `json:"severity"`
+	// Line is the 1-based line in the file; 0 for file-level issues
+	Line int `json:"line"`
+	// Column is the 1-based column number; 0 when the issue is not tied to a column
+	Column int `json:"column"`
+	// Field is the name of the column, if any
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// String formats the issue with its position
+func (i Issue) String() string {
+	var position string
+	switch {
+	case i.Line > 0 && i.Column > 0:
+		position = fmt.Sprintf("line %d, column %d (%s): ", i.Line, i.Column, i.Field)
+	case i.Line > 0:
+		position = fmt.Sprintf("line %d: ", i.Line)
+	}
+	return fmt.Sprintf("%s: %s%s", i.Severity, position, i.Message)
+}
+
+// FileReport holds the validation result for a single file
+type FileReport struct {
+	Type   models.FileType `json:"type"`
+	Path   string          `json:"path"`
+	Rows   int             `json:"rows"`
+	Issues []Issue         `json:"issues"`
+	// Truncated is the number of further issues that were not recorded
+	Truncated int `json:"truncated"`
+}
+
+// Errors returns the number of errors, including truncated ones
+func (r *FileReport) Errors() int {
+	n := r.Truncated
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// add records an issue, counting errors beyond maxIssues as truncated
+func (r *FileReport) add(issue Issue, maxIssues int) {
+	if len(r.Issues) >= maxIssues {
+		if issue.Severity == SeverityError {
+			r.Truncated++
+		}
+		return
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+// Report holds the validation results for a set of files
+type Report struct {
+	Files []*FileReport `json:"files"`
+}
+
+// HasErrors reports whether any file has errors
+func (r *Report) HasErrors() bool {
+	return r.Errors() > 0
+}
+
+// Errors returns the total number of errors across all files
+func (r *Report) Errors() int {
+	n := 0
+	for _, f := range r.Files {
+		n += f.Errors()
+	}
+	return n
+}
+
+// Error is returned when validation finds errors
+type Error struct {
+	Report *Report
+}
+
+// Error summarizes the failed validation
+func (e *Error) Error() string {
+	files := 0
+	for _, f := range e.Report.Files {
+		if f.Errors() > 0 {
+			files++
+		}
+	}
+	return fmt.Sprintf("validation failed: %d error(s) in %d file(s)", e.Report.Errors(), files)
+}
+
+// Merge adds the files and issues of other to the report, combining reports for the same file
+func (r *Report) Merge(other *Report) {
+	for _, file := range other.Files {
+		existing := r.file(file.Type, file.Path)
+		if existing == nil {
+			r.Files = append(r.Files, file)
+			continue
+		}
+		existing.Issues = append(existing.Issues, file.Issues...)
+		existing.Truncated += file.Truncated
+	}
+}
+
+// file returns the report for a file, or nil if there is none
+func (r *Report) file(ft models.FileType, path string) *FileReport {
+	for _, f := range r.Files {
+		if f.Type == ft && f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// Options configures validation
+type Options struct {
+	// MaxIssues limits the issues recorded per file; zero means DefaultMaxIssues
+	MaxIssues int
+	// Strict reports problems found by the file layouts as errors. The layouts
+	// are not confirmed against a published TRTC specification, so by default
+	// those problems are warnings that do not block an upload. Files that are
+	// not valid CSV are errors either way.
+	Strict bool
+}
+
+// layoutSeverity returns the severity of a problem found by the file layouts
+func (o Options) layoutSeverity() Severity {
+	if o.Strict {
+		return SeverityError
+	}
+	return SeverityWarning
+}
+
+// maxIssues returns the per-file issue limit
+func (o Options) maxIssues() int {
+	if o.MaxIssues <= 0 {
+		return DefaultMaxIssues
+	}
+	return o.MaxIssues
+}
+
+// Validate validates every file and returns a report; it returns an *Error
+// alongside the report when any file has errors
+func Validate(files []models.UploadFile, options Options) (*Report, error) {
+	report := &Report{}
+	for _, file := range files {
+		fileReport, err := ValidateFile(file, options)
+		if err != nil {
+			return nil, err
+		}
+		report.Files = append(report.Files, fileReport)
+	}
+
+	if report.HasErrors() {
+		return report, &Error{Report: report}
+	}
+	return report, nil
+}
+
+// ValidateFile checks a single CSV file against the schema for its type.
+// It returns an error only if the file cannot be read; problems in the content are reported as issues.
+func ValidateFile(file models.UploadFile, options Options) (*FileReport, error) {
+	report := &FileReport{Type: file.Type, Path: file.FilePath}
+	add := func(issue Issue) {
+		report.add(issue, options.maxIssues())
+	}
+
+	schema, ok := SchemaFor(file.Type)
+	if !ok {
+		return nil, fmt.Errorf("no schema for file type %s", file.Type.String())
+	}
+
+	// Only delimited text files can be checked
+	if ext := strings.ToLower(filepath.Ext(file.FilePath)); ext != ".csv" && ext != ".txt" {
+		add(Issue{Severity: SeverityWarning, Message: fmt.Sprintf("%s files are not validated", ext)})
+		return report, nil
+	}
+
+	f, err := os.Open(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", file.FilePath, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	// Read and map the header
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		add(Issue{Severity: SeverityError, Message: "file is empty"})
+		return report, nil
+	}
+	if err != nil {
+		add(parseIssue(err))
+		return report, nil
+	}
+	columns := mapHeader(schema, header, options, add)
+	headerLen := len(header)
+
+	// Check each row
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// The reader cannot resynchronize after a quoting error, so stop here
+			add(parseIssue(err))
+			return report, nil
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue // blank line
+		}
+		report.Rows++
+
+		if len(record) != headerLen {
+			add(Issue{Severity: SeverityError, Line: line, Message: fmt.Sprintf("expected %d fields, found %d", headerLen, len(record))})
+		}
+
+		for index, column := range columns {
+			if column == nil {
+				continue
+			}
+			value := ""
+			if index < len(record) {
+				value = strings.TrimSpace(record[index])
+			}
+			if msg := checkValue(*column, value); msg != "" {
+				add(Issue{Severity: options.layoutSeverity(), Line: line, Column: index + 1, Field: column.Name, Message: msg})
+			}
+		}
+	}
+
+	if report.Rows == 0 {
+		add(Issue{Severity: SeverityWarning, Message: "file has no data rows"})
+	}
+
+	return report, nil
+}
+
+// mapHeader maps each header position to its schema column, reporting missing,
+// duplicate and unknown columns
+func mapHeader(schema Schema, header []string, options Options, add func(Issue)) []*Column {
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make([]*Column, len(header))
+	seen := map[string]bool{}
+	for i, name := range header {
+		column, ok := schema.Column(name)
+		if !ok {
+			add(Issue{Severity: SeverityWarning, Line: 1, Column: i + 1, Field: name, Message: "unknown column is ignored"})
+			continue
+		}
+		if seen[column.Name] {
+			add(Issue{Severity: SeverityError, Line: 1, Column: i + 1, Field: name, Message: "duplicate column"})
+			continue
+		}
+		seen[column.Name] = true
+		columns[i] = &column
+	}
+
+	for _, column := range schema.Columns {
+		if column.Required && !seen[column.Name] {
+			add(Issue{Severity: options.layoutSeverity(), Line: 1, Message: fmt.Sprintf("missing required column %s", column.Name)})
+		}
+	}
+
+	return columns
+}
+
+// dateLayouts are the accepted date formats
+var dateLayouts = []string{"2006-01-02", "01/02/2006", "1/2/2006"}
+
+// checkValue returns a description of what is wrong with value, or "" if it is valid
+func checkValue(column Column, value string) string {
+	if value == "" {
+		if column.Required {
+			return "required value is missing"
+		}
+		return ""
+	}
+
+	if column.MaxLength > 0 && utf8.RuneCountInString(value) > column.MaxLength {
+		return fmt.Sprintf("value %q is longer than %d characters", value, column.MaxLength)
+	}
+
+	switch column.Type {
+	case FieldInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Sprintf("value %q is not an integer", value)
+		}
+	case FieldDecimal:
+		if _, err := strconv.ParseFloat(value, 64); err != nil || strings.ContainsAny(value, "eEnN") {
+			return fmt.Sprintf("value %q is not a number", value)
+		}
+	case FieldDate:
+		valid := false
+		for _, layout := range dateLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("value %q is not a date (use YYYY-MM-DD or MM/DD/YYYY)", value)
+		}
+	}
+
+	if len(column.Allowed) > 0 {
+		for _, allowed := range column.Allowed {
+			if strings.EqualFold(allowed, value) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("value %q is not one of %s", value, strings.Join(column.Allowed, ", "))
+	}
+
+	return ""
+}
+
+// parseIssue converts a CSV parse error into an issue
+func parseIssue(err error) Issue {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Issue{Severity: SeverityError, Line: parseErr.Line, Message: parseErr.Err.Error()}
+	}
+	return Issue{Severity: SeverityError, Message: err.Error()}
+}
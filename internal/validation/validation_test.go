@@ -0,0 +1,182 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+)
+
+// writeFile writes content to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	return path
+}
+
+func TestValidateFile_Valid(t *testing.T) {
+	path := writeFile(t, "courses.csv", "\ufeffSubject,Course Number,Title,CreditHours,Level\n"+
+		"MATH,1130,College Algebra,3,LD\n"+
+		"\n"+
+		"ENGL,1010,\"Composition, I\",3.0,ld\n")
+
+	report, err := ValidateFile(models.UploadFile{Type: models.FileTypeCourses, FilePath: path}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Rows != 2 {
+		t.Errorf("Expected 2 rows, got %d", report.Rows)
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("Expected no issues, got %v", report.Issues)
+	}
+}
+
+func TestValidateFile_Issues(t *testing.T) {
+	path := writeFile(t, "students.csv", "StudentID,FirstName,LastName,DateOfBirth,Consent,Nickname\n"+
+		"1001,Ada,Lovelace,1815-12-10,Y,\n"+
+		"1002,,Hopper,12/09/1906,Maybe,\n"+
+		"1003,Alan,Turing,not-a-date,N\n")
+
+	report, err := ValidateFile(models.UploadFile{Type: models.FileTypeStudents, FilePath: path}, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{
+		"warning: line 1, column 6 (Nickname): unknown column is ignored",
+		"error: line 3, column 2 (FirstName): required value is missing",
+		`error: line 3, column 5 (Consent): value "Maybe" is longer than 1 characters`,
+		"error: line 4: expected 6 fields, found 5",
+		`error: line 4, column 4 (DateOfBirth): value "not-a-date" is not a date (use YYYY-MM-DD or MM/DD/YYYY)`,
+	}
+	if len(report.Issues) != len(expected) {
+		t.Fatalf("Expected %d issues, got %d: %v", len(expected), len(report.Issues), report.Issues)
+	}
+	for i, issue := range report.Issues {
+		if issue.String() != expected[i] {
+			t.Errorf("Issue %d: expected %q, got %q", i, expected[i], issue.String())
+		}
+	}
+	if report.Errors() != 4 {
+		t.Errorf("Expected 4 errors, got %d", report.Errors())
+	}
+
+	// Without Strict only the malformed row is an error
+	report, err = ValidateFile(models.UploadFile{Type: models.FileTypeStudents, FilePath: path}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Errors() != 1 || len(report.Issues) != len(expected) {
+		t.Errorf("Expected 1 error among %d issues, got %d: %v", len(expected), report.Errors(), report.Issues)
+	}
+}
+
+func TestValidateFile_MissingColumns(t *testing.T) {
+	path := writeFile(t, "studentcourses.csv", "StudentID,Subject,CourseNumber,Term,Grade,CreditHours,Grade\n")
+
+	report, err := ValidateFile(models.UploadFile{Type: models.FileTypeStudentCourses, FilePath: path}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Errors() != 1 || !strings.Contains(report.Issues[0].Message, "duplicate column") {
+		t.Errorf("Expected a duplicate column error, got %v", report.Issues)
+	}
+
+	path = writeFile(t, "equivalencies.csv", "SourceInstitution,Subject\nABC,MATH\n")
+	report, err = ValidateFile(models.UploadFile{Type: models.FileTypeEquivalencies, FilePath: path}, Options{Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// SourceSubject, SourceCourseNumber and CourseNumber are missing
+	if report.Errors() != 3 {
+		t.Errorf("Expected 3 errors, got %d: %v", report.Errors(), report.Issues)
+	}
+}
+
+func TestValidateFile_Truncated(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("Subject,CourseNumber,Title,CreditHours\n")
+	for i := 0; i < 10; i++ {
+		content.WriteString("MATH,1130,Algebra,three\n")
+	}
+	path := writeFile(t, "courses.csv", content.String())
+
+	report, err := ValidateFile(models.UploadFile{Type: models.FileTypeCourses, FilePath: path}, Options{MaxIssues: 3, Strict: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(report.Issues) != 3 || report.Truncated != 7 {
+		t.Errorf("Expected 3 issues and 7 truncated, got %d and %d", len(report.Issues), report.Truncated)
+	}
+	if report.Errors() != 10 {
+		t.Errorf("Expected 10 errors, got %d", report.Errors())
+	}
+}
+
+func TestValidateFile_Unsupported(t *testing.T) {
+	path := writeFile(t, "courses.xlsx", "not a csv")
+
+	report, err := ValidateFile(models.UploadFile{Type: models.FileTypeCourses, FilePath: path}, Options{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Errors() != 0 || len(report.Issues) != 1 {
+		t.Errorf("Expected a single warning, got %v", report.Issues)
+	}
+
+	if _, err := ValidateFile(models.UploadFile{Type: models.FileTypeCourses, FilePath: filepath.Join(t.TempDir(), "missing.csv")}, Options{}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := writeFile(t, "courses.csv", "Subject,CourseNumber,Title,CreditHours\nMATH,1130,Algebra,3\n")
+	bad := writeFile(t, "students.csv", "StudentID\n1001\n")
+
+	report, err := Validate([]models.UploadFile{{Type: models.FileTypeCourses, FilePath: good}}, Options{})
+	if err != nil || report.HasErrors() {
+		t.Fatalf("Expected a clean report, got %v", err)
+	}
+
+	report, err = Validate([]models.UploadFile{
+		{Type: models.FileTypeCourses, FilePath: good},
+		{Type: models.FileTypeStudents, FilePath: bad},
+	}, Options{Strict: true})
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if validationErr.Report != report || len(report.Files) != 2 {
+		t.Errorf("Expected the error to carry the report for both files")
+	}
+	if err.Error() != "validation failed: 4 error(s) in 1 file(s)" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	report := &Report{Files: []*FileReport{{
+		Type:   models.FileTypeCourses,
+		Path:   "courses.csv",
+		Rows:   1,
+		Issues: []Issue{{Severity: SeverityError, Line: 2, Column: 1, Field: "Subject", Message: "value is required"}},
+	}}}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	// The field names are part of the CLI's documented output
+	expected := `{"files":[{"type":"courses","path":"courses.csv","rows":1,"issues":[` +
+		`{"severity":"error","line":2,"column":1,"field":"Subject","message":"value is required"}],"truncated":0}]}`
+	if string(data) != expected {
+		t.Errorf("Unexpected JSON:\n%s\nexpected:\n%s", data, expected)
+	}
+}
@@ -0,0 +1,408 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+// DefaultSettle is how long a folder must be quiet before its files are uploaded
+const DefaultSettle = 10 * time.Second
+
+const (
+	// ProcessedDir is the default subdirectory for files that were uploaded
+	ProcessedDir = "processed"
+	// FailedDir is the default subdirectory for files that could not be uploaded
+	FailedDir = "failed"
+)
+
+// ErrSkipped can be returned by an UploadFunc when there was nothing to send;
+// the files are treated as processed
+var ErrSkipped = errors.New("upload skipped")
+
+// ErrRetry is matched by the errors of uploads that may succeed later, such as
+// when the server could not be reached; the files stay in the folder and are
+// uploaded again once it has been quiet for the settle time. An UploadFunc
+// marks such an error with Retry.
+var ErrRetry = errors.New("upload will be retried")
+
+// Retry marks an error from an UploadFunc as one to retry, so callers can
+// match both ErrRetry and the underlying error, keeping its message
+func Retry(err error) error {
+	return retryError{err}
+}
+
+// retryError is an error marked by Retry
+type retryError struct {
+	err error
+}
+
+// Error returns the message of the underlying error
+func (e retryError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns ErrRetry and the underlying error
+func (e retryError) Unwrap() []error {
+	return []error{ErrRetry, e.err}
+}
+
+// UploadFunc uploads a batch of files, returning an error if they were not accepted
+type UploadFunc func(ctx context.Context, files []models.UploadFile) error
+
+// Options configures a Watcher
+type Options struct {
+	// Dir is the folder to watch
+	Dir string
+	// Settle is how long the folder must be quiet, with no file changing size, before uploading
+	Settle time.Duration
+	// Patterns map file types to glob patterns matched against file names, ignoring case.
+	// File types without a pattern are recognized by name; see Classify.
+	Patterns map[models.FileType]string
+	// ProcessedDir and FailedDir receive the files after an upload; relative paths are under Dir
+	ProcessedDir string
+	FailedDir    string
+}
+
+// Watcher uploads files as they land in a folder
+type Watcher struct {
+	options Options
+	upload  UploadFunc
+	logger  *logger.Logger
+
+	// pending holds the files waiting for the folder to settle
+	pending map[string]*pendingFile
+	// ignored holds the unrecognized files already reported
+	ignored map[string]bool
+	// lastEvent is when a file in the folder last changed
+	lastEvent time.Time
+}
+
+// pendingFile is a file waiting to be uploaded
+type pendingFile struct {
+	fileType models.FileType
+	size     int64
+	modTime  time.Time
+}
+
+// New creates a watcher that passes settled files to upload
+func New(options Options, upload UploadFunc, logger *logger.Logger) (*Watcher, error) {
+	info, err := os.Stat(options.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot watch %s: %w", options.Dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("cannot watch %s: not a directory", options.Dir)
+	}
+	for ft, pattern := range options.Patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q for %s files", pattern, ft.String())
+		}
+	}
+
+	if options.Settle <= 0 {
+		options.Settle = DefaultSettle
+	}
+	if options.ProcessedDir == "" {
+		options.ProcessedDir = ProcessedDir
+	}
+	if options.FailedDir == "" {
+		options.FailedDir = FailedDir
+	}
+	if !filepath.IsAbs(options.ProcessedDir) {
+		options.ProcessedDir = filepath.Join(options.Dir, options.ProcessedDir)
+	}
+	if !filepath.IsAbs(options.FailedDir) {
+		options.FailedDir = filepath.Join(options.Dir, options.FailedDir)
+	}
+
+	return &Watcher{
+		options: options,
+		upload:  upload,
+		logger:  logger,
+		pending: map[string]*pendingFile{},
+		ignored: map[string]bool{},
+	}, nil
+}
+
+// Run watches the folder until ctx is cancelled. Files already in the folder
+// are picked up when it starts.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer fsw.Close()
+
+	if err := fsw.Add(w.options.Dir); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.options.Dir, err)
+	}
+	w.logger.Info("Watching %s for files to upload", w.options.Dir)
+
+	// Pick up files that landed while nothing was watching
+	entries, err := os.ReadDir(w.options.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", w.options.Dir, err)
+	}
+	for _, entry := range entries {
+		w.track(filepath.Join(w.options.Dir, entry.Name()))
+	}
+
+	ticker := time.NewTicker(w.options.Settle / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Stopped watching %s", w.options.Dir)
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(event)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warning("Watch error: %v", err)
+		case <-ticker.C:
+			w.processSettled(ctx)
+		}
+	}
+}
+
+// handle records a change to a file in the folder
+func (w *Watcher) handle(event fsnotify.Event) {
+	switch {
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		delete(w.pending, event.Name)
+		delete(w.ignored, event.Name)
+	case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+		w.track(event.Name)
+	}
+}
+
+// track adds a file to the pending files if it is one to upload
+func (w *Watcher) track(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || skipName(info.Name()) {
+		return
+	}
+
+	ft, ok := w.classify(info.Name())
+	if !ok {
+		if !w.ignored[path] {
+			w.logger.Warning("Ignoring %s: cannot tell its file type from its name", path)
+			w.ignored[path] = true
+		}
+		return
+	}
+
+	w.lastEvent = time.Now()
+	if p, ok := w.pending[path]; ok {
+		p.size, p.modTime = info.Size(), info.ModTime()
+		return
+	}
+	w.logger.Info("Found %s file %s", ft.String(), path)
+	w.pending[path] = &pendingFile{fileType: ft, size: info.Size(), modTime: info.ModTime()}
+}
+
+// classify returns the file type of a file name using the configured patterns, then Classify
+func (w *Watcher) classify(name string) (models.FileType, bool) {
+	for _, ft := range models.FileTypes {
+		pattern, ok := w.options.Patterns[ft]
+		if !ok {
+			continue
+		}
+		if matched, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name)); matched {
+			return ft, true
+		}
+	}
+	return Classify(name)
+}
+
+// processSettled uploads the pending files once the folder has been quiet for the settle time
+func (w *Watcher) processSettled(ctx context.Context) {
+	if len(w.pending) == 0 || time.Since(w.lastEvent) < w.options.Settle {
+		return
+	}
+
+	// A writer that does not trigger events may still be filling a file
+	for path, p := range w.pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			p.size, p.modTime = info.Size(), info.ModTime()
+			w.lastEvent = time.Now()
+		}
+	}
+	if len(w.pending) == 0 || time.Since(w.lastEvent) < w.options.Settle {
+		return
+	}
+
+	for _, batch := range w.batches() {
+		if ctx.Err() != nil || !w.process(ctx, batch) {
+			return
+		}
+	}
+}
+
+// batches groups the pending files into uploads with at most one file of each type, oldest first
+func (w *Watcher) batches() [][]models.UploadFile {
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(a, b int) bool {
+		pa, pb := w.pending[paths[a]], w.pending[paths[b]]
+		if !pa.modTime.Equal(pb.modTime) {
+			return pa.modTime.Before(pb.modTime)
+		}
+		return paths[a] < paths[b]
+	})
+
+	var batches [][]models.UploadFile
+	for _, path := range paths {
+		ft := w.pending[path].fileType
+		placed := false
+		for i, batch := range batches {
+			if !hasType(batch, ft) {
+				batches[i] = append(batch, models.UploadFile{Type: ft, FilePath: path})
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			batches = append(batches, []models.UploadFile{{Type: ft, FilePath: path}})
+		}
+	}
+
+	for _, batch := range batches {
+		sort.Slice(batch, func(a, b int) bool {
+			return batch[a].Type < batch[b].Type
+		})
+	}
+	return batches
+}
+
+// process uploads a batch and moves its files to the processed or failed
+// folder. It returns false when the files stay pending to be retried, so later
+// batches, which may hold newer files of the same types, wait for them.
+func (w *Watcher) process(ctx context.Context, batch []models.UploadFile) bool {
+	err := w.upload(ctx, batch)
+	if ctx.Err() != nil {
+		// Leave the files for the next run
+		w.logger.Info("Upload interrupted; the files stay in %s", w.options.Dir)
+		return false
+	}
+	if errors.Is(err, ErrRetry) {
+		// Wait another settle time before trying again rather than on every tick
+		w.logger.Warning("Upload of %s failed and will be retried; the files stay in %s: %v", describe(batch), w.options.Dir, err)
+		w.lastEvent = time.Now()
+		return false
+	}
+
+	for _, file := range batch {
+		delete(w.pending, file.FilePath)
+	}
+
+	if err != nil && !errors.Is(err, ErrSkipped) {
+		w.logger.Error("Upload of %s failed: %v", describe(batch), err)
+		for _, file := range batch {
+			dst, moveErr := move(file.FilePath, w.options.FailedDir)
+			if moveErr != nil {
+				w.logger.Error("%v", moveErr)
+				continue
+			}
+			if writeErr := os.WriteFile(dst+".error.txt", []byte(logger.Redact(err.Error())+"\n"), 0644); writeErr != nil {
+				w.logger.Warning("Failed to write the error for %s: %v", dst, writeErr)
+			}
+		}
+		return true
+	}
+
+	w.logger.Info("Uploaded %s", describe(batch))
+	for _, file := range batch {
+		if _, moveErr := move(file.FilePath, w.options.ProcessedDir); moveErr != nil {
+			w.logger.Error("%v", moveErr)
+		}
+	}
+	return true
+}
+
+// Classify returns the file type a file name refers to. Names are matched
+// ignoring case, spaces, dashes and underscores, so "Student_Courses_2024.csv"
+// is a student courses file and "students-fall.xlsx" a students file. Only
+// CSV, text and Excel files are recognized.
+func Classify(name string) (models.FileType, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt", ".xlsx", ".xlsm":
+	default:
+		return 0, false
+	}
+
+	normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(name))
+	// Student courses comes before students and courses because it starts with one and contains the other
+	for _, ft := range []models.FileType{models.FileTypeStudentCourses, models.FileTypeStudents, models.FileTypeEquivalencies, models.FileTypeCourses} {
+		if strings.HasPrefix(normalized, ft.String()) {
+			return ft, true
+		}
+	}
+	return 0, false
+}
+
+// skipName reports whether a file is hidden or still being written by another program
+func skipName(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") ||
+		strings.HasSuffix(lower, ".tmp") || strings.HasSuffix(lower, ".part") || strings.HasSuffix(lower, ".error.txt")
+}
+
+// hasType reports whether a batch already has a file of the given type
+func hasType(batch []models.UploadFile, ft models.FileType) bool {
+	for _, file := range batch {
+		if file.Type == ft {
+			return true
+		}
+	}
+	return false
+}
+
+// describe lists the file names of a batch for log messages
+func describe(batch []models.UploadFile) string {
+	names := make([]string, len(batch))
+	for i, file := range batch {
+		names[i] = filepath.Base(file.FilePath)
+	}
+	return strings.Join(names, ", ")
+}
+
+// move moves a file into dir, adding a timestamp to the name if a file of that name is already there
+func move(path, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	dst := filepath.Join(dir, filepath.Base(path))
+	if _, err := os.Stat(dst); err == nil {
+		ext := filepath.Ext(path)
+		dst = filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), ext)+"-"+time.Now().Format("20060102-150405")+ext)
+	}
+	if err := os.Rename(path, dst); err != nil {
+		return "", fmt.Errorf("failed to move %s to %s: %w", path, dir, err)
+	}
+	return dst, nil
+}
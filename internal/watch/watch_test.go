@@ -0,0 +1,220 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chatt-state/trtc-go/internal/models"
+	"github.com/chatt-state/trtc-go/pkg/logger"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected models.FileType
+		ok       bool
+	}{
+		{"courses.csv", models.FileTypeCourses, true},
+		{"Courses 2024.xlsx", models.FileTypeCourses, true},
+		{"equivalencies-fall.txt", models.FileTypeEquivalencies, true},
+		{"students_20240313.csv", models.FileTypeStudents, true},
+		{"Student_Courses_2024.csv", models.FileTypeStudentCourses, true},
+		{"studentcourses.xlsm", models.FileTypeStudentCourses, true},
+		{"grades.csv", 0, false},
+		{"courses.pdf", 0, false},
+	}
+
+	for _, tc := range testCases {
+		ft, ok := Classify(tc.name)
+		if ok != tc.ok || (ok && ft != tc.expected) {
+			t.Errorf("%s: expected %v %v, got %v %v", tc.name, tc.expected, tc.ok, ft, ok)
+		}
+	}
+}
+
+// newTestWatcher creates a watcher on a temporary folder with a short settle time
+func newTestWatcher(t *testing.T, options Options, upload UploadFunc) *Watcher {
+	t.Helper()
+	log, err := logger.New(filepath.Join(t.TempDir(), "test.log"), logger.LevelError)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	options.Settle = 100 * time.Millisecond
+	w, err := New(options, upload, log)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	return w
+}
+
+// waitFor polls until check passes or the deadline passes
+func waitFor(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	// A file already in the folder is picked up at startup
+	if err := os.WriteFile(filepath.Join(dir, "courses.csv"), []byte("Subject,CourseNumber\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var mu sync.Mutex
+	var batches [][]models.UploadFile
+	upload := func(ctx context.Context, files []models.UploadFile) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, files)
+		if files[0].Type == models.FileTypeEquivalencies {
+			return errors.New("upload failed with status code 400")
+		}
+		return nil
+	}
+	w := newTestWatcher(t, Options{Dir: dir}, upload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	// Wait for the watcher to start before adding files
+	time.Sleep(50 * time.Millisecond)
+	for name, content := range map[string]string{
+		"students.csv": "StudentID\n",
+		"notes.csv":    "hello\n",
+		".hidden.csv":  "hello\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	waitFor(t, "the first upload", func() bool {
+		return exists(filepath.Join(dir, ProcessedDir, "students.csv"))
+	})
+
+	// A failed upload moves the file to the failed folder with its error
+	if err := os.WriteFile(filepath.Join(dir, "equivalencies.csv"), []byte("SourceInstitution\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	waitFor(t, "the failed upload", func() bool {
+		return exists(filepath.Join(dir, FailedDir, "equivalencies.csv.error.txt"))
+	})
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 uploads, got %v", batches)
+	}
+	if len(batches[0]) != 2 || batches[0][0].Type != models.FileTypeCourses || batches[0][1].Type != models.FileTypeStudents {
+		t.Errorf("Expected courses and students to be uploaded together, got %v", batches[0])
+	}
+	if !exists(filepath.Join(dir, ProcessedDir, "courses.csv")) {
+		t.Error("Expected courses.csv to be moved to the processed folder")
+	}
+	if !exists(filepath.Join(dir, "notes.csv")) || !exists(filepath.Join(dir, ".hidden.csv")) {
+		t.Error("Expected unrecognized files to be left in place")
+	}
+	message, err := os.ReadFile(filepath.Join(dir, FailedDir, "equivalencies.csv.error.txt"))
+	if err != nil || !strings.Contains(string(message), "status code 400") {
+		t.Errorf("Expected the upload error to be saved, got %q, %v", message, err)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	dir := t.TempDir()
+	w := newTestWatcher(t, Options{Dir: dir, Patterns: map[models.FileType]string{models.FileTypeStudents: "STU*.CSV"}}, nil)
+
+	if ft, ok := w.classify("stu-export.csv"); !ok || ft != models.FileTypeStudents {
+		t.Errorf("Expected the pattern to classify stu-export.csv as students, got %v %v", ft, ok)
+	}
+
+	// Two files of the same type are sent in separate uploads, oldest first
+	now := time.Now()
+	w.pending = map[string]*pendingFile{
+		"students-new.csv": {fileType: models.FileTypeStudents, modTime: now},
+		"students-old.csv": {fileType: models.FileTypeStudents, modTime: now.Add(-time.Minute)},
+		"courses.csv":      {fileType: models.FileTypeCourses, modTime: now},
+	}
+	batches := w.batches()
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %v", batches)
+	}
+	if len(batches[0]) != 2 || batches[0][1].FilePath != "students-old.csv" {
+		t.Errorf("Expected the older students file in the first batch, got %v", batches[0])
+	}
+	if len(batches[1]) != 1 || batches[1][0].FilePath != "students-new.csv" {
+		t.Errorf("Expected the newer students file in the second batch, got %v", batches[1])
+	}
+}
+
+func TestRunRetry(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "courses.csv"), []byte("Subject,CourseNumber\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	var mu sync.Mutex
+	attempts := 0
+	upload := func(ctx context.Context, files []models.UploadFile) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return Retry(errors.New("server unreachable"))
+		}
+		return nil
+	}
+	w := newTestWatcher(t, Options{Dir: dir}, upload)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	// The file stays in place after the first attempt and is uploaded by the second
+	waitFor(t, "the retried upload", func() bool {
+		return exists(filepath.Join(dir, ProcessedDir, "courses.csv"))
+	})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned an error: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", attempts)
+	}
+	if exists(filepath.Join(dir, FailedDir)) {
+		t.Error("Expected nothing to be moved to the failed folder")
+	}
+}
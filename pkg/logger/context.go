@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// KeyRunID is the attribute holding the ID of the run a record belongs to
+const KeyRunID = "run_id"
+
+// runIDKey is the context key of the run ID
+type runIDKey struct{}
+
+// WithRunID returns a context carrying the ID of a run, such as an upload.
+// Records logged with the context, or by a logger from WithContext, include
+// it as the run_id attribute.
+func WithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunID returns the run ID carried by ctx, or an empty string
+func RunID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the run ID carried by the context to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the run ID to a record and passes it to the wrapped handler
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RunID(ctx); id != "" {
+		r = r.Clone()
+		r.AddAttrs(slog.String(KeyRunID, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding attrs to every record
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a handler nesting later attributes under name
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
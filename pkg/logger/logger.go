@@ -1,11 +1,14 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,43 +18,130 @@ const (
 	LevelInfo
 	LevelWarning
 	LevelError
+	// LevelOff turns a sink off
+	LevelOff
 )
 
-// Logger represents a logger instance
+// Output formats
+const (
+	// FormatText writes records as key=value pairs
+	FormatText = "text"
+	// FormatJSON writes each record as a JSON object on its own line
+	FormatJSON = "json"
+)
+
+// Attribute keys used across the application, so records about the same
+// thing can be queried the same way
+const (
+	KeyFileType = "file_type"
+	KeyPath     = "path"
+	KeyEndpoint = "endpoint"
+	KeyStatus   = "status"
+	KeyDuration = "duration"
+	KeyAttempt  = "attempt"
+	KeyError    = "error"
+)
+
+// levelNames maps the names accepted by ParseLevel to levels
+var levelNames = map[string]int{
+	"debug":   LevelDebug,
+	"info":    LevelInfo,
+	"warn":    LevelWarning,
+	"warning": LevelWarning,
+	"error":   LevelError,
+	"off":     LevelOff,
+}
+
+// Options configure a logger. Records always go to the log file, and to the
+// console and syslog when they are set.
+type Options struct {
+	// Level is the lowest level written to the log file
+	Level int
+	// Format is FormatText or FormatJSON for every sink; empty means FormatText
+	Format string
+	// Rotation controls when the log file is archived
+	Rotation Rotation
+	// Console writes records to standard error
+	Console *Console
+	// Syslog sends records to a local syslog socket
+	Syslog *Syslog
+}
+
+// Console configures records written for the person running the program
+type Console struct {
+	// Level is the lowest level written; LevelOff writes nothing
+	Level int
+	// Writer receives the records; nil means standard error
+	Writer io.Writer
+}
+
+// Logger writes leveled records with key/value attributes through log/slog.
+// The printf-style Debug, Info, Warning and Error methods write a record
+// with only a message; Log and With add attributes.
 type Logger struct {
-	debugLogger   *log.Logger
-	infoLogger    *log.Logger
-	warningLogger *log.Logger
-	errorLogger   *log.Logger
-	level         int
-	file          *os.File
+	slog   *slog.Logger
+	level  *slog.LevelVar
+	file   *rotatingFile
+	syslog *syslogOutput
+	// ctx is the context of records written by the printf-style methods
+	ctx context.Context
 }
 
-// New creates a new logger instance
+// New creates a new logger instance writing text records at level to standard
+// error and to a log file that is never rotated
 func New(logFilePath string, level int) (*Logger, error) {
+	return NewWithOptions(logFilePath, Options{Level: level, Console: &Console{Level: level}})
+}
+
+// NewWithOptions creates a new logger instance writing to the log file and
+// the sinks set in options
+func NewWithOptions(logFilePath string, options Options) (*Logger, error) {
+	format, err := ParseFormat(options.Format)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create log directory if it doesn't exist
 	logDir := filepath.Dir(logFilePath)
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	// Open log file
-	file, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	// Open log file, rotating it if it is due
+	file, err := openRotatingFile(logFilePath, options.Rotation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
 
-	// Create multi-writer for console and file
-	multiWriter := io.MultiWriter(os.Stdout, file)
+	// Create a handler for each sink, each with its own level
+	level := new(slog.LevelVar)
+	level.Set(slogLevel(options.Level))
+	handlers := []slog.Handler{newHandler(file, format, level)}
+	if console := options.Console; console != nil && console.Level < LevelOff {
+		w := console.Writer
+		if w == nil {
+			w = os.Stderr
+		}
+		handlers = append(handlers, newHandler(w, format, slogLevel(console.Level)))
+	}
+	var syslogOut *syslogOutput
+	if sl := options.Syslog; sl != nil && sl.Level < LevelOff {
+		w, err := dialSyslog(sl.Address, sl.Tag)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		handler := newSyslogHandler(w, format, slogLevel(sl.Level))
+		syslogOut = handler.out
+		handlers = append(handlers, handler)
+	}
 
 	// Create logger instance
 	logger := &Logger{
-		debugLogger:   log.New(multiWriter, "DEBUG: ", log.Ldate|log.Ltime),
-		infoLogger:    log.New(multiWriter, "INFO: ", log.Ldate|log.Ltime),
-		warningLogger: log.New(multiWriter, "WARNING: ", log.Ldate|log.Ltime),
-		errorLogger:   log.New(multiWriter, "ERROR: ", log.Ldate|log.Ltime),
-		level:         level,
-		file:          file,
+		slog:   slog.New(&redactHandler{contextHandler{fanoutHandler(handlers)}}),
+		level:  level,
+		file:   file,
+		syslog: syslogOut,
 	}
 
 	// Log startup message
@@ -60,43 +150,157 @@ func New(logFilePath string, level int) (*Logger, error) {
 	return logger, nil
 }
 
-// Close closes the logger
+// newHandler creates the slog handler writing records to w in format
+func newHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	options := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, options)
+	}
+	return slog.NewTextHandler(w, options)
+}
+
+// ParseLevel returns the level named by s: debug, info, warn (or warning),
+// error or off, in any case. The numbers 0 to 3 are accepted as well, as older
+// versions used them.
+func ParseLevel(s string) (int, error) {
+	if level, ok := levelNames[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return level, nil
+	}
+	if level, err := strconv.Atoi(s); err == nil && level >= LevelDebug && level <= LevelError {
+		return level, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q: use debug, info, warn or error", s)
+}
+
+// LevelName returns the name of level, as accepted by ParseLevel
+func LevelName(level int) string {
+	switch {
+	case level <= LevelDebug:
+		return "debug"
+	case level == LevelInfo:
+		return "info"
+	case level == LevelWarning:
+		return "warn"
+	case level == LevelError:
+		return "error"
+	default:
+		return "off"
+	}
+}
+
+// ParseFormat checks that s names an output format, returning FormatText
+// for an empty string
+func ParseFormat(s string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(s)); format {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("invalid log format %q: use %s or %s", s, FormatText, FormatJSON)
+	}
+}
+
+// slogLevel converts a logger level to the slog level
+func slogLevel(level int) slog.Level {
+	switch {
+	case level <= LevelDebug:
+		return slog.LevelDebug
+	case level == LevelInfo:
+		return slog.LevelInfo
+	case level == LevelWarning:
+		return slog.LevelWarn
+	case level == LevelError:
+		return slog.LevelError
+	default:
+		return levelOff
+	}
+}
+
+// levelOff is above every level records are written at
+const levelOff = slog.Level(1 << 10)
+
+// Close closes the log file and the syslog connection. Loggers returned by
+// With share them and must not be used afterwards.
 func (l *Logger) Close() error {
+	var err error
+	if l.syslog != nil {
+		err = l.syslog.Close()
+	}
 	if l.file != nil {
-		return l.file.Close()
+		if fileErr := l.file.Close(); fileErr != nil {
+			err = fileErr
+		}
 	}
-	return nil
+	return err
+}
+
+// With returns a logger that adds the key/value pairs in args, given as for
+// slog.Logger.With, to every record
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{
+		slog:   l.slog.With(args...),
+		level:  l.level,
+		file:   l.file,
+		syslog: l.syslog,
+		ctx:    l.ctx,
+	}
+}
+
+// WithContext returns a logger whose printf-style methods log with ctx, so
+// their records include the run ID it carries
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return &Logger{
+		slog:   l.slog,
+		level:  l.level,
+		file:   l.file,
+		syslog: l.syslog,
+		ctx:    ctx,
+	}
+}
+
+// Log writes a record with a message and the key/value pairs in args, given
+// as for slog.Logger.Log, such as
+//
+//	log.Log(ctx, logger.LevelInfo, "Response received", logger.KeyStatus, 200)
+func (l *Logger) Log(ctx context.Context, level int, msg string, args ...any) {
+	l.slog.Log(ctx, slogLevel(level), msg, args...)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, v ...interface{}) {
-	if l.level <= LevelDebug {
-		l.debugLogger.Printf(format, v...)
-	}
+	l.print(LevelDebug, format, v...)
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, v ...interface{}) {
-	if l.level <= LevelInfo {
-		l.infoLogger.Printf(format, v...)
-	}
+	l.print(LevelInfo, format, v...)
 }
 
 // Warning logs a warning message
 func (l *Logger) Warning(format string, v ...interface{}) {
-	if l.level <= LevelWarning {
-		l.warningLogger.Printf(format, v...)
-	}
+	l.print(LevelWarning, format, v...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, v ...interface{}) {
-	if l.level <= LevelError {
-		l.errorLogger.Printf(format, v...)
+	l.print(LevelError, format, v...)
+}
+
+// print formats a message and writes it as a record without attributes,
+// skipping the formatting when the level is disabled
+func (l *Logger) print(level int, format string, v ...interface{}) {
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.slog.Enabled(ctx, slogLevel(level)) {
+		return
 	}
+	l.slog.Log(ctx, slogLevel(level), fmt.Sprintf(format, v...))
 }
 
-// SetLevel sets the logger level
+// SetLevel sets the lowest level written to the log file
 func (l *Logger) SetLevel(level int) {
-	l.level = level
+	l.level.Set(slogLevel(level))
 }
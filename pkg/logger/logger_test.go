@@ -1,10 +1,15 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew(t *testing.T) {
@@ -114,3 +119,256 @@ func TestSetLevel(t *testing.T) {
 		t.Errorf("Error message should be in the log file")
 	}
 }
+
+func TestRedact(t *testing.T) {
+	RegisterSecret("registered-secret-value")
+	RegisterSecret("plus+secret/value")
+	RegisterSecret("abc")
+
+	tests := []struct {
+		name   string
+		input  string
+		secret string
+	}{
+		{"registered value", "upload failed for registered-secret-value", "registered-secret-value"},
+		{"registered value escaped", "GET /upload?k=plus%2Bsecret%2Fvalue", "plus%2Bsecret%2Fvalue"},
+		{"multipart field", "Content-Disposition: form-data; name=\"apikey\"\r\n\r\nmultipart-key\r\n--boundary", "multipart-key"},
+		{"flag with equals", "running trtc-go upload --apikey=flag-key-1", "flag-key-1"},
+		{"flag with space", "running trtc-go upload --apikey flag-key-2 --courses=x.csv", "flag-key-2"},
+		{"query string", "POST https://example.com/api/Upload?apikey=query-key&x=1", "query-key"},
+		{"json field", `{"api_key": "json-key", "code": 401}`, "json-key"},
+		{"environment variable", "TRTC_API_KEY=env-key trtc-go upload", "env-key"},
+		{"authorization header", "Authorization: Bearer header-token", "header-token"},
+		{"passphrase", "passphrase: my-passphrase", "my-passphrase"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Redact(tt.input)
+			if tt.secret != "" && strings.Contains(got, tt.secret) {
+				t.Errorf("Expected %q to be redacted, got %q", tt.secret, got)
+			}
+			if tt.secret != "" && !strings.Contains(got, Redacted) {
+				t.Errorf("Expected %s in %q", Redacted, got)
+			}
+		})
+	}
+
+	// Short values are not registered, and ordinary text is left alone
+	for _, text := range []string{"abc def", "an API key is required: use --apikey, --apikey-file or TRTC_API_KEY", "Uploading files to https://example.com/api/Upload"} {
+		if got := Redact(text); got != text {
+			t.Errorf("Expected %q to be unchanged, got %q", text, got)
+		}
+	}
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	const apiKey = "logger-test-api-key"
+	RegisterSecret(apiKey)
+
+	logFilePath := filepath.Join(t.TempDir(), "test.log")
+	logger, err := New(logFilePath, LevelDebug)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	// Every level, formatted arguments, errors and request dumps
+	logger.Debug("Request body: name=\"apikey\"\r\n\r\n%s\r\n", apiKey)
+	logger.Info("Using key %s", apiKey)
+	logger.Warning("Retrying with %q", apiKey)
+	logger.Error("Upload failed: %v", fmt.Errorf("server rejected key %s", apiKey))
+	logger.Close()
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if strings.Contains(string(content), apiKey) {
+		t.Errorf("Expected the API key never to appear in the log:\n%s", content)
+	}
+	if n := strings.Count(string(content), Redacted); n < 4 {
+		t.Errorf("Expected every message to be redacted, got %d redactions:\n%s", n, content)
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	RegisterSecret("writer-secret")
+
+	var buf bytes.Buffer
+	w := RedactWriter(&buf)
+	n, err := fmt.Fprintln(w, "Error: invalid key writer-secret")
+	if err != nil || n != len("Error: invalid key writer-secret\n") {
+		t.Fatalf("Expected the full write to be reported, got %d (%v)", n, err)
+	}
+	if got := buf.String(); got != "Error: invalid key "+Redacted+"\n" {
+		t.Errorf("Unexpected output %q", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input string
+		level int
+	}{
+		{"debug", LevelDebug},
+		{"INFO", LevelInfo},
+		{"warn", LevelWarning},
+		{"warning", LevelWarning},
+		{" error ", LevelError},
+		{"0", LevelDebug},
+		{"3", LevelError},
+		{"off", LevelOff},
+	}
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.input)
+		if err != nil || level != tt.level {
+			t.Errorf("ParseLevel(%q) = %d, %v; expected %d", tt.input, level, err, tt.level)
+		}
+	}
+	for _, input := range []string{"", "verbose", "4", "-1"} {
+		if _, err := ParseLevel(input); err == nil {
+			t.Errorf("Expected an error for level %q", input)
+		}
+	}
+	if name := LevelName(LevelWarning); name != "warn" {
+		t.Errorf("Expected warn, got %s", name)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	RegisterSecret("json-attribute-secret")
+
+	logFilePath := filepath.Join(t.TempDir(), "test.log")
+	logger, err := NewWithOptions(logFilePath, Options{Level: LevelInfo, Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	// Attributes from With and Log, a printf-style message, and a filtered debug record
+	fileLogger := logger.With(KeyFileType, "courses", KeyPath, "/data/courses.csv")
+	fileLogger.Log(context.Background(), LevelWarning, "Response received",
+		KeyStatus, 503, KeyAttempt, 2, KeyDuration, 1500*time.Millisecond,
+		KeyError, fmt.Errorf("server said json-attribute-secret"))
+	fileLogger.Info("Converted %d rows", 12)
+	logger.Log(context.Background(), LevelDebug, "Hidden")
+	logger.Close()
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected a JSON record, got %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+
+	// The startup record, then the two written above
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d:\n%s", len(records), content)
+	}
+	got := records[1]
+	for key, want := range map[string]any{
+		"level":     "WARN",
+		"msg":       "Response received",
+		KeyFileType: "courses",
+		KeyPath:     "/data/courses.csv",
+		KeyStatus:   float64(503),
+		KeyAttempt:  float64(2),
+		KeyDuration: float64(1500 * time.Millisecond),
+		KeyError:    "server said " + Redacted,
+	} {
+		if got[key] != want {
+			t.Errorf("Expected %s to be %v, got %v", key, want, got[key])
+		}
+	}
+	if records[2]["msg"] != "Converted 12 rows" || records[2][KeyPath] != "/data/courses.csv" {
+		t.Errorf("Expected the printf-style record with the logger's attributes, got %v", records[2])
+	}
+}
+
+func TestNewWithOptionsInvalidFormat(t *testing.T) {
+	if _, err := NewWithOptions(filepath.Join(t.TempDir(), "test.log"), Options{Format: "xml"}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestConsoleSink(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "test.log")
+	var console bytes.Buffer
+	logger, err := NewWithOptions(logFilePath, Options{
+		Level:   LevelDebug,
+		Console: &Console{Level: LevelWarning, Writer: &console},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	logger.Debug("debug record")
+	logger.With(KeyPath, "/data/x.csv").Warning("warning record")
+	logger.Close()
+
+	// The file gets every record and the console only warnings and errors
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(content), "debug record") || !strings.Contains(string(content), "warning record") {
+		t.Errorf("Expected both records in the log file:\n%s", content)
+	}
+	if strings.Contains(console.String(), "debug record") || strings.Contains(console.String(), "Logger initialized") {
+		t.Errorf("Expected no info or debug records on the console:\n%s", console.String())
+	}
+	if !strings.Contains(console.String(), "warning record") || !strings.Contains(console.String(), "path=/data/x.csv") {
+		t.Errorf("Expected the warning with its attributes on the console:\n%s", console.String())
+	}
+
+	// A console that is off gets nothing
+	console.Reset()
+	logger, err = NewWithOptions(logFilePath, Options{Level: LevelInfo, Console: &Console{Level: LevelOff, Writer: &console}})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	logger.Error("error record")
+	logger.Close()
+	if console.Len() != 0 {
+		t.Errorf("Expected a quiet console, got:\n%s", console.String())
+	}
+}
+
+func TestRunID(t *testing.T) {
+	logFilePath := filepath.Join(t.TempDir(), "test.log")
+	logger, err := NewWithOptions(logFilePath, Options{Level: LevelInfo, Format: FormatJSON})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	// Records logged with the context, or by a logger bound to it, carry the run ID
+	ctx := WithRunID(context.Background(), "20250313-130420-ab12")
+	if got := RunID(ctx); got != "20250313-130420-ab12" {
+		t.Errorf("Expected the run ID from the context, got %q", got)
+	}
+	logger.Log(ctx, LevelInfo, "Structured")
+	logger.WithContext(ctx).With(KeyPath, "/data/x.csv").Info("Printf-style")
+	logger.Info("Outside the run")
+	logger.Close()
+
+	content, err := os.ReadFile(logFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 records, got %d:\n%s", len(lines), content)
+	}
+	for i, want := range []any{"20250313-130420-ab12", "20250313-130420-ab12", nil} {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(lines[i+1]), &record); err != nil {
+			t.Fatalf("Expected a JSON record, got %q: %v", lines[i+1], err)
+		}
+		if record[KeyRunID] != want {
+			t.Errorf("Expected %s of %q to be %v, got %v", KeyRunID, record["msg"], want, record[KeyRunID])
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in log output and error messages
+const Redacted = "[REDACTED]"
+
+// MinSecretLength is the length of the shortest value RegisterSecret accepts;
+// shorter values would redact ordinary text
+const MinSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	// secrets are the registered values, longest first so a secret containing
+	// another is replaced whole
+	secrets []string
+)
+
+// secretPatterns find the values of fields that hold secrets even when they
+// were never registered. The first group, kept, is everything before the value.
+var secretPatterns = []*regexp.Regexp{
+	// Multipart form fields, such as the apikey field of an upload request dump
+	regexp.MustCompile(`(?i)(name="?(?:api[_-]?key|passphrase|password|token)"?\r?\n(?:[^\r\n]+\r?\n)*\r?\n)([^\r\n]+)`),
+	// Authorization headers
+	regexp.MustCompile(`(?i)(authorization"?\s*[:=]\s*"?(?:bearer\s+|basic\s+|token\s+)?)([^\s"',;]+)`),
+	// key=value, key: value and "key": "value", including flags, query strings and environment variables
+	regexp.MustCompile(`(?i)((?:api[_-]?key|passphrase|password|secret|token)["']?\s*[:=]\s*["']?)([^\s"'&,;]+)`),
+	// Flags followed by their value, such as --apikey value
+	regexp.MustCompile(`(?i)(--?(?:apikey|passphrase)\s+)([^\s-]\S*)`),
+}
+
+// RegisterSecret makes every logger, Redact and RedactWriter replace value,
+// such as an API key once it is known. Values shorter than MinSecretLength
+// are ignored.
+func RegisterSecret(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < MinSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range []string{value, url.QueryEscape(value)} {
+		known := false
+		for _, secret := range secrets {
+			if secret == v {
+				known = true
+				break
+			}
+		}
+		if !known {
+			secrets = append(secrets, v)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Redact replaces the registered secrets and the values of fields that look
+// like secrets in s
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	secretsMu.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	return s
+}
+
+// redactWriter redacts each write before passing it on
+type redactWriter struct {
+	w io.Writer
+}
+
+// RedactWriter returns a writer that redacts secrets from each write to w.
+// A secret split across two writes is only caught by the patterns, so each
+// write should hold whole lines.
+func RedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+// Write writes p to the underlying writer with secrets redacted
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactHandler redacts the message and attributes of each record before
+// passing it on
+type redactHandler struct {
+	slog.Handler
+}
+
+// Handle redacts a record and passes it to the wrapped handler
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.Handler.Handle(ctx, redacted)
+}
+
+// WithAttrs returns a handler adding the redacted attributes to every record
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactHandler{h.Handler.WithAttrs(redacted)}
+}
+
+// WithGroup returns a handler nesting later attributes under name
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{h.Handler.WithGroup(name)}
+}
+
+// redactAttr redacts the value of an attribute that is, or prints as, text
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(Redact(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			a.Value = slog.StringValue(Redact(v.Error()))
+		case fmt.Stringer:
+			a.Value = slog.StringValue(Redact(v.String()))
+		}
+	}
+	return a
+}
@@ -0,0 +1,303 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// archiveTimeFormat is the rotation time in the names of archived log files
+const archiveTimeFormat = "20060102T150405.000"
+
+// Rotation controls when the log file is archived and how many archives are
+// kept. The zero value never rotates.
+type Rotation struct {
+	// MaxSize is the size in bytes the file may reach before it is rotated;
+	// 0 disables rotation by size
+	MaxSize int64
+	// MaxAge is how long the file is written to before it is rotated, counted
+	// from its first record; 0 disables rotation by age
+	MaxAge time.Duration
+	// MaxBackups is the number of archives kept, removing the oldest first;
+	// 0 keeps them all
+	MaxBackups int
+	// Compress gzips each archive
+	Compress bool
+}
+
+// firstRecordTime finds the time of a record written by this or an older
+// version at the start of a log file
+var firstRecordTime = []struct {
+	pattern *regexp.Regexp
+	layout  string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})`), time.RFC3339Nano},
+	{regexp.MustCompile(`\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}`), "2006/01/02 15:04:05"},
+}
+
+// rotatingFile is a log file that moves itself aside to a timestamped archive
+// next to it once it is too large or too old, such as log.txt becoming
+// log-20250313T090420.000.txt.gz. Several processes, such as the scheduler
+// and a manual upload, may write to the same file: each follows the file to
+// its new one when another rotates it.
+type rotatingFile struct {
+	path     string
+	rotation Rotation
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	started  time.Time
+	// retry is when to try again after a rotation failed
+	retry time.Time
+	// now returns the current time; tests replace it
+	now func() time.Time
+}
+
+// openRotatingFile opens the log file at path for appending, rotating it
+// first if it is already due
+func openRotatingFile(path string, rotation Rotation) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, rotation: rotation, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	if f.due(0) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return nil, err
+			}
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	return f, nil
+}
+
+// Write appends p to the file, rotating it first if p would make it too
+// large or the file is too old
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if err := f.follow(); err != nil {
+		return 0, err
+	}
+	if f.due(len(p)) {
+		// A file that cannot be rotated, such as one another process has
+		// open on Windows, is written to anyway
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the log file, noting its size and when its first record was written
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.started = f.now()
+	if f.size > 0 {
+		f.started = startTime(f.path, info.ModTime())
+	}
+	return nil
+}
+
+// follow reopens the log file when another process has rotated or removed it,
+// so records are not written to an archive that may be compressed and pruned.
+// Otherwise it updates the size with what other processes appended.
+func (f *rotatingFile) follow() error {
+	current, err := f.file.Stat()
+	if err != nil {
+		return nil
+	}
+	if latest, err := os.Stat(f.path); err == nil && os.SameFile(current, latest) {
+		f.size = current.Size()
+		return nil
+	}
+
+	f.file.Close()
+	f.file = nil
+	return f.open()
+}
+
+// startTime returns the time of the first record in the log file at path,
+// or fallback if it has none that can be read
+func startTime(path string, fallback time.Time) time.Time {
+	file, err := os.Open(path)
+	if err != nil {
+		return fallback
+	}
+	defer file.Close()
+
+	line, _ := bufio.NewReader(io.LimitReader(file, 512)).ReadString('\n')
+	for _, format := range firstRecordTime {
+		if match := format.pattern.FindString(line); match != "" {
+			if t, err := time.ParseInLocation(format.layout, match, time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	return fallback
+}
+
+// due reports whether the file must be rotated before n more bytes are written.
+// An empty file is never rotated.
+func (f *rotatingFile) due(n int) bool {
+	if f.size == 0 || f.now().Before(f.retry) {
+		return false
+	}
+	if f.rotation.MaxSize > 0 && f.size+int64(n) > f.rotation.MaxSize {
+		return true
+	}
+	return f.rotation.MaxAge > 0 && f.now().Sub(f.started) >= f.rotation.MaxAge
+}
+
+// rotate moves the file to an archive, compressing it if configured, opens a
+// new file and removes the archives beyond MaxBackups
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	archive := f.archivePath(f.now())
+	if err := os.Rename(f.path, archive); err != nil {
+		// Keep logging to the current file rather than losing records
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		f.retry = f.now().Add(time.Minute)
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	if f.rotation.Compress {
+		if err := compressFile(archive); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to compress %s: %v\n", archive, err)
+		}
+	}
+	if err := f.prune(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to remove old log archives: %v\n", err)
+	}
+	return nil
+}
+
+// archivePath returns the name of the archive for a rotation at t
+func (f *rotatingFile) archivePath(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	return base + "-" + t.Format(archiveTimeFormat) + ext
+}
+
+// Archives returns the archives of the log file at path, newest first
+func Archives(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	prefix := filepath.Base(strings.TrimSuffix(path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log archives: %w", err)
+	}
+	var archives []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(archiveTimeFormat, stamp); err != nil {
+			continue
+		}
+		archives = append(archives, filepath.Join(filepath.Dir(path), name))
+	}
+
+	// The timestamps sort in time order
+	sort.Sort(sort.Reverse(sort.StringSlice(archives)))
+	return archives, nil
+}
+
+// prune removes the oldest archives beyond MaxBackups
+func (f *rotatingFile) prune() error {
+	if f.rotation.MaxBackups <= 0 {
+		return nil
+	}
+	archives, err := Archives(f.path)
+	if err != nil {
+		return err
+	}
+	for i := f.rotation.MaxBackups; i < len(archives); i++ {
+		if err := os.Remove(archives[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressFile replaces the file at path with a gzipped copy named path.gz
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	clock := time.Date(2025, 3, 13, 9, 0, 0, 0, time.Local)
+	f, err := openRotatingFile(path, Rotation{MaxSize: 100, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	f.now = func() time.Time { return clock }
+	defer f.Close()
+
+	// Each 60-byte write after the first rotates the file
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 4; i++ {
+		clock = clock.Add(time.Second)
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+	}
+
+	archives, err := Archives(path)
+	if err != nil {
+		t.Fatalf("Failed to list archives: %v", err)
+	}
+	if len(archives) != 2 {
+		t.Fatalf("Expected the 2 newest of 3 archives to be kept, got %v", archives)
+	}
+	if want := filepath.Join(filepath.Dir(path), "log-20250313T090004.000.txt.gz"); archives[0] != want {
+		t.Errorf("Expected the newest archive to be %s, got %s", want, archives[0])
+	}
+
+	// Archives are gzipped copies of the file as it was
+	file, err := os.Open(archives[0])
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Expected a gzipped archive: %v", err)
+	}
+	if content, err := io.ReadAll(gz); err != nil || string(content) != line {
+		t.Errorf("Unexpected archive content %q (%v)", content, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != line {
+		t.Errorf("Expected the log file to hold the last write, got %q", content)
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+
+	// A file whose first record is older than MaxAge is rotated when opened
+	old := "time=2025-03-01T09:00:00.000Z level=INFO msg=\"Logger initialized\"\n"
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	f, err := openRotatingFile(path, Rotation{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	archives, err := Archives(path)
+	if err != nil || len(archives) != 1 || strings.HasSuffix(archives[0], ".gz") {
+		t.Fatalf("Expected one uncompressed archive, got %v (%v)", archives, err)
+	}
+	if content, _ := os.ReadFile(archives[0]); string(content) != old {
+		t.Errorf("Unexpected archive content %q", content)
+	}
+
+	// The new file is rotated once it is a day old
+	start := time.Now()
+	f.now = func() time.Time { return start }
+	if _, err := f.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	f.now = func() time.Time { return start.Add(23 * time.Hour) }
+	f.Write([]byte("second\n"))
+	if archives, _ := Archives(path); len(archives) != 1 {
+		t.Errorf("Expected no rotation within a day, got %v", archives)
+	}
+	f.now = func() time.Time { return start.Add(25 * time.Hour) }
+	f.Write([]byte("third\n"))
+	if archives, _ := Archives(path); len(archives) != 2 {
+		t.Errorf("Expected a rotation after a day, got %v", archives)
+	}
+}
+
+func TestRotatingFileSharedWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+
+	// Two writers of the same file, as if in two processes
+	first, err := openRotatingFile(path, Rotation{MaxSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer first.Close()
+	second, err := openRotatingFile(path, Rotation{MaxSize: 100})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer second.Close()
+
+	line := strings.Repeat("x", 59) + "\n"
+	if _, err := first.Write([]byte(line)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	// The second writer counts the first's record and rotates the file
+	if _, err := second.Write([]byte(line)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	// The first writer follows the rotation instead of writing to the archive
+	last := strings.Repeat("y", 9) + "\n"
+	if _, err := first.Write([]byte(last)); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	archives, err := Archives(path)
+	if err != nil || len(archives) != 1 {
+		t.Fatalf("Expected one archive, got %v (%v)", archives, err)
+	}
+	if content, _ := os.ReadFile(archives[0]); string(content) != line {
+		t.Errorf("Expected the archive to hold the first write only, got %q", content)
+	}
+	if content, _ := os.ReadFile(path); string(content) != line+last {
+		t.Errorf("Expected the log file to hold the later writes, got %q", content)
+	}
+}
+
+func TestStartTime(t *testing.T) {
+	dir := t.TempDir()
+	fallback := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		first string
+		want  time.Time
+	}{
+		{"text", "time=2025-03-13T09:04:20.123-04:00 level=INFO msg=x\n", time.Date(2025, 3, 13, 13, 4, 20, 123000000, time.UTC)},
+		{"json", `{"time":"2025-03-13T13:04:20Z","level":"INFO","msg":"x"}` + "\n", time.Date(2025, 3, 13, 13, 4, 20, 0, time.UTC)},
+		{"older version", "INFO: 2025/03/13 09:04:20 Logger initialized\n", time.Date(2025, 3, 13, 9, 4, 20, 0, time.Local)},
+		{"no time", "something else\n", fallback},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".txt")
+			if err := os.WriteFile(path, []byte(tt.first+"more\n"), 0644); err != nil {
+				t.Fatalf("Failed to write log file: %v", err)
+			}
+			if got := startTime(path, fallback); !got.Equal(tt.want) {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
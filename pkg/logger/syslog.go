@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Syslog configures records sent to a local syslog socket
+type Syslog struct {
+	// Level is the lowest level sent; LevelOff sends nothing
+	Level int
+	// Address is the path of the socket; empty tries the usual locations,
+	// such as /dev/log
+	Address string
+	// Tag names the program in each message; empty means the program's name
+	Tag string
+}
+
+// syslogWriter sends messages with a priority, as *syslog.Writer does
+type syslogWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Close() error
+}
+
+// syslogHandler formats each record with the wrapped handler and sends it
+// with the priority of its level. Syslog stamps messages with the time
+// itself, so the record's time is left out.
+type syslogHandler struct {
+	slog.Handler
+	out *syslogOutput
+}
+
+// syslogOutput passes what a handler writes to the syslog writer with the
+// priority of the record being written
+type syslogOutput struct {
+	mu     sync.Mutex
+	writer syslogWriter
+	level  slog.Level
+}
+
+// newSyslogHandler creates a handler sending records at level or above to w in format
+func newSyslogHandler(w syslogWriter, format string, level slog.Leveler) *syslogHandler {
+	out := &syslogOutput{writer: w}
+	options := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	var handler slog.Handler = slog.NewTextHandler(out, options)
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(out, options)
+	}
+	return &syslogHandler{Handler: handler, out: out}
+}
+
+// Handle sends a record with the priority of its level
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+	h.out.level = r.Level
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler adding attrs to every record
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithAttrs(attrs), out: h.out}
+}
+
+// WithGroup returns a handler nesting later attributes under name
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithGroup(name), out: h.out}
+}
+
+// Write sends one formatted record. It is only called from Handle, which
+// holds the lock and has set the level.
+func (o *syslogOutput) Write(p []byte) (int, error) {
+	m := strings.TrimSuffix(string(p), "\n")
+	var err error
+	switch {
+	case o.level >= slog.LevelError:
+		err = o.writer.Err(m)
+	case o.level >= slog.LevelWarn:
+		err = o.writer.Warning(m)
+	case o.level >= slog.LevelInfo:
+		err = o.writer.Info(m)
+	default:
+		err = o.writer.Debug(m)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to syslog
+func (o *syslogOutput) Close() error {
+	return o.writer.Close()
+}
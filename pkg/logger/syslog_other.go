@@ -0,0 +1,12 @@
+//go:build windows || plan9
+
+package logger
+
+import (
+	"errors"
+)
+
+// dialSyslog reports that there is no local syslog socket on this platform
+func dialSyslog(address, tag string) (syslogWriter, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
@@ -0,0 +1,69 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyslogSink(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, so keep this one short
+	dir, err := os.MkdirTemp("", "syslog")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Skipf("Unix datagram sockets are unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	RegisterSecret("syslog-secret")
+	logger, err := NewWithOptions(filepath.Join(t.TempDir(), "test.log"), Options{
+		Level:  LevelDebug,
+		Syslog: &Syslog{Level: LevelWarning, Address: socket, Tag: "trtc-test"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	defer logger.Close()
+
+	logger.Info("not sent")
+	logger.Error("Upload failed with key syslog-secret")
+
+	// Only the error arrives, at the error priority of the user facility, redacted and without a time attribute
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read syslog message: %v", err)
+	}
+	message := string(buf[:n])
+	if !strings.HasPrefix(message, "<11>") {
+		t.Errorf("Expected priority <11> (user.err), got %q", message)
+	}
+	for _, want := range []string{"trtc-test[", "level=ERROR", "Upload failed with key " + Redacted} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected %q in %q", want, message)
+		}
+	}
+	if strings.Contains(message, "time=") || strings.Contains(message, "not sent") {
+		t.Errorf("Unexpected content in %q", message)
+	}
+}
+
+func TestSyslogUnavailable(t *testing.T) {
+	_, err := NewWithOptions(filepath.Join(t.TempDir(), "test.log"), Options{
+		Syslog: &Syslog{Level: LevelInfo, Address: filepath.Join(t.TempDir(), "missing.sock")},
+	})
+	if err == nil {
+		t.Error("Expected an error for a missing syslog socket")
+	}
+}
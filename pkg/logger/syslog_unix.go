@@ -0,0 +1,30 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// dialSyslog connects to the syslog socket at address, or the local syslog
+// daemon when address is empty
+func dialSyslog(address, tag string) (syslogWriter, error) {
+	if address == "" {
+		w, err := syslog.Dial("", "", syslog.LOG_INFO|syslog.LOG_USER, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		return w, nil
+	}
+
+	// Daemons listen for datagrams or, less often, streams
+	w, err := syslog.Dial("unixgram", address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		var streamErr error
+		if w, streamErr = syslog.Dial("unix", address, syslog.LOG_INFO|syslog.LOG_USER, tag); streamErr != nil {
+			return nil, fmt.Errorf("failed to connect to syslog at %s: %w", address, err)
+		}
+	}
+	return w, nil
+}
@@ -1,7 +1,11 @@
 package ui
 
 import (
+	"context"
+
 	"github.com/chatt-state/trtc-go/internal/config"
+	"github.com/chatt-state/trtc-go/internal/delta"
+	"github.com/chatt-state/trtc-go/internal/history"
 	"github.com/chatt-state/trtc-go/internal/models"
 	"github.com/chatt-state/trtc-go/internal/uploader"
 	"github.com/chatt-state/trtc-go/pkg/logger"
@@ -13,13 +17,52 @@ type Uploader struct {
 }
 
 // NewUploader creates a new uploader for the UI
-func NewUploader(config *config.Config, logger *logger.Logger) *Uploader {
-	return &Uploader{
-		uploader: uploader.New(config, logger),
+func NewUploader(config *config.Config, logger *logger.Logger) (*Uploader, error) {
+	u, err := uploader.New(config, logger)
+	if err != nil {
+		return nil, err
 	}
+	return &Uploader{
+		uploader: u,
+	}, nil
+}
+
+// SetProgressFunc sets a callback that receives upload progress
+func (u *Uploader) SetProgressFunc(onProgress models.ProgressFunc) {
+	u.uploader.SetProgressFunc(onProgress)
+}
+
+// SetSheet selects the worksheet converted from Excel workbooks
+func (u *Uploader) SetSheet(sheet string) {
+	u.uploader.SetSheet(sheet)
+}
+
+// SetJournal sets the history journal that records each upload run
+func (u *Uploader) SetJournal(journal *history.Journal) {
+	u.uploader.SetJournal(journal)
+}
+
+// SetForce controls whether files unchanged since the last successful upload are sent
+func (u *Uploader) SetForce(force bool) {
+	u.uploader.SetForce(force)
+}
+
+// SetSnapshots sets the store of last uploaded files that delta uploads are compared against
+func (u *Uploader) SetSnapshots(snapshots *delta.Store) {
+	u.uploader.SetSnapshots(snapshots)
+}
+
+// SetDelta controls whether only the rows added or changed since the last successful upload are sent
+func (u *Uploader) SetDelta(enabled bool) {
+	u.uploader.SetDelta(enabled)
 }
 
 // UploadFiles uploads files to the TRTC API
 func (u *Uploader) UploadFiles(apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) (*models.UploadResponse, error) {
 	return u.uploader.UploadFilesFromPaths(apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
 }
+
+// UploadFilesWithContext uploads files to the TRTC API, aborting when ctx is cancelled
+func (u *Uploader) UploadFilesWithContext(ctx context.Context, apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath string) (*models.UploadResponse, error) {
+	return u.uploader.UploadFilesFromPathsWithContext(ctx, apiKey, coursesPath, equivalenciesPath, studentsPath, studentCoursesPath)
+}